@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// workload builds the commands a test sends.
+type workload struct {
+	keySpace int
+	payload  string
+}
+
+// key is prefix:NNNNNNNNNNNN, random within the key space, or always
+// the same name when there is none.
+func (w *workload) key(prefix string, rng *rand.Rand) string {
+	n := 0
+	if w.keySpace > 0 {
+		n = rng.IntN(w.keySpace)
+	}
+	s := strconv.Itoa(n)
+	return prefix + ":" + strings.Repeat("0", max(12-len(s), 0)) + s
+}
+
+// tests are the commands that -t and -mix can name.
+var tests = map[string]func(w *workload, rng *rand.Rand) []string{
+	"ping":  func(w *workload, rng *rand.Rand) []string { return []string{"PING"} },
+	"set":   func(w *workload, rng *rand.Rand) []string { return []string{"SET", w.key("key", rng), w.payload} },
+	"get":   func(w *workload, rng *rand.Rand) []string { return []string{"GET", w.key("key", rng)} },
+	"incr":  func(w *workload, rng *rand.Rand) []string { return []string{"INCR", w.key("counter", rng)} },
+	"lpush": func(w *workload, rng *rand.Rand) []string { return []string{"LPUSH", w.key("mylist", rng), w.payload} },
+	"rpush": func(w *workload, rng *rand.Rand) []string { return []string{"RPUSH", w.key("mylist", rng), w.payload} },
+	"lpop":  func(w *workload, rng *rand.Rand) []string { return []string{"LPOP", w.key("mylist", rng)} },
+	"rpop":  func(w *workload, rng *rand.Rand) []string { return []string{"RPOP", w.key("mylist", rng)} },
+	"del":   func(w *workload, rng *rand.Rand) []string { return []string{"DEL", w.key("key", rng)} },
+}
+
+func testNames() []string {
+	names := make([]string, 0, len(tests))
+	for name := range tests {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// mixed picks a test for each request at random, in proportion to the
+// weights.
+func mixed(mix []mixEntry) func(w *workload, rng *rand.Rand) []string {
+	total := 0
+	for _, m := range mix {
+		total += m.weight
+	}
+	return func(w *workload, rng *rand.Rand) []string {
+		n := rng.IntN(total)
+		for _, m := range mix {
+			if n < m.weight {
+				return tests[m.test](w, rng)
+			}
+			n -= m.weight
+		}
+		return nil
+	}
+}
+
+// result is the outcome of one test.
+type result struct {
+	name      string
+	requests  int
+	errors    int
+	elapsed   time.Duration
+	latencies []time.Duration // sorted
+}
+
+// run sends cfg.Requests commands built by gen over cfg.Clients
+// connections, cfg.Pipeline at a time. Every connection is dialed before
+// the clock starts. A request's latency runs from the write of its batch
+// to the arrival of its reply.
+func run(ctx context.Context, pool *conn.Pool, cfg *Config, name string, gen func(*workload, *rand.Rand) []string) (result, error) {
+	conns := make([]net.Conn, cfg.Clients)
+	for i := range conns {
+		cn, err := pool.Dial(ctx)
+		if err != nil {
+			for _, c := range conns[:i] {
+				c.Close()
+			}
+			return result{}, err
+		}
+		conns[i] = cn
+	}
+
+	w := &workload{keySpace: cfg.KeySpace, payload: strings.Repeat("x", cfg.DataSize)}
+	var claimed atomic.Int64
+	var mu sync.Mutex
+	res := result{name: name}
+	var firstErr error
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for _, cn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer cn.Close()
+			stop := context.AfterFunc(ctx, func() { cn.Close() })
+			defer stop()
+
+			rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+			rw, rr := resp.NewWriter(cn), resp.NewReader(cn)
+			var lat []time.Duration
+			var errs int
+			var err error
+			for err == nil {
+				end := claimed.Add(int64(cfg.Pipeline))
+				batch := cfg.Pipeline - int(max(end-int64(cfg.Requests), 0))
+				if batch <= 0 {
+					break
+				}
+				for range batch {
+					args := gen(w, rng)
+					rw.WriteArrayHeader(len(args))
+					for _, a := range args {
+						rw.WriteBulkString(a)
+					}
+				}
+				sent := time.Now()
+				if err = rw.Flush(); err != nil {
+					break
+				}
+				for range batch {
+					var v resp.Value
+					if v, err = rr.ReadValue(); err != nil {
+						break
+					}
+					if v.Typ == "error" {
+						errs++
+					}
+					lat = append(lat, time.Since(sent))
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			res.latencies = append(res.latencies, lat...)
+			res.errors += errs
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	res.requests = len(res.latencies)
+	slices.Sort(res.latencies)
+	if ctx.Err() != nil {
+		return res, ctx.Err()
+	}
+	return res, firstErr
+}
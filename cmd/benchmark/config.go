@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Config holds the benchmark settings.
+type Config struct {
+	Host     string
+	Port     int
+	Password string
+	Clients  int
+	Requests int
+	Pipeline int
+	DataSize int
+	// KeySpace spreads keys over this many random names; 0 uses one key
+	// per test.
+	KeySpace int
+	Tests    []string
+	// Mix, when set, replaces Tests with one run that picks each command
+	// at random by weight.
+	Mix   []mixEntry
+	Quiet bool
+	CSV   bool
+}
+
+// mixEntry is one "test:weight" item of -mix.
+type mixEntry struct {
+	test   string
+	weight int
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Host:     "127.0.0.1",
+		Port:     8090,
+		Clients:  50,
+		Requests: 100000,
+		Pipeline: 1,
+		DataSize: 3,
+		Tests:    []string{"ping", "set", "get", "incr", "lpush", "rpush", "lpop", "rpop"},
+	}
+}
+
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("benchmark", flag.ContinueOnError)
+	fs.StringVar(&cfg.Host, "h", cfg.Host, "server hostname")
+	fs.IntVar(&cfg.Port, "p", cfg.Port, "server port")
+	fs.StringVar(&cfg.Password, "a", "", "password to use when connecting")
+	fs.IntVar(&cfg.Clients, "c", cfg.Clients, "number of parallel connections")
+	fs.IntVar(&cfg.Requests, "n", cfg.Requests, "total number of requests per test")
+	fs.IntVar(&cfg.Pipeline, "P", cfg.Pipeline, "pipeline this many requests per round trip")
+	fs.IntVar(&cfg.DataSize, "d", cfg.DataSize, "payload size of SET and list values in bytes")
+	fs.IntVar(&cfg.KeySpace, "r", cfg.KeySpace, "use random keys out of this many; 0 uses a single key")
+	tests := fs.String("t", strings.Join(cfg.Tests, ","), "comma separated list of tests to run: "+strings.Join(testNames(), ","))
+	mix := fs.String("mix", "", `run one mixed test instead, e.g. "get:8,set:2"`)
+	fs.BoolVar(&cfg.Quiet, "q", false, "print only the throughput and median latency of each test")
+	fs.BoolVar(&cfg.CSV, "csv", false, "print results as CSV")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg.Tests = nil
+	for _, t := range strings.Split(*tests, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			cfg.Tests = append(cfg.Tests, t)
+		}
+	}
+	if *mix != "" {
+		m, err := parseMix(*mix)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Mix = m
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func parseMix(s string) ([]mixEntry, error) {
+	var mix []mixEntry
+	for _, item := range strings.Split(s, ",") {
+		name, w, ok := strings.Cut(strings.TrimSpace(item), ":")
+		weight := 1
+		if ok {
+			n, err := strconv.Atoi(w)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid weight %q in -mix", w)
+			}
+			weight = n
+		}
+		mix = append(mix, mixEntry{test: strings.ToLower(name), weight: weight})
+	}
+	return mix, nil
+}
+
+func (c *Config) validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d", c.Port)
+	}
+	if c.Clients < 1 || c.Requests < 1 || c.Pipeline < 1 || c.DataSize < 0 || c.KeySpace < 0 {
+		return fmt.Errorf("-c, -n and -P must be positive and -d and -r not negative")
+	}
+	for _, t := range c.Tests {
+		if _, ok := tests[t]; !ok {
+			return fmt.Errorf("unknown test %q", t)
+		}
+	}
+	for _, m := range c.Mix {
+		if _, ok := tests[m.test]; !ok {
+			return fmt.Errorf("unknown test %q in -mix", m.test)
+		}
+	}
+	return nil
+}
+
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
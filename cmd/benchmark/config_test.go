@@ -0,0 +1,55 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := loadConfig([]string{"-h", "example", "-p", "7000", "-c", "4", "-n", "10", "-P", "16", "-d", "0", "-r", "100", "-t", " SET, get,,ping ", "-q"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr() != "example:7000" || cfg.Clients != 4 || cfg.Requests != 10 || cfg.Pipeline != 16 ||
+		cfg.DataSize != 0 || cfg.KeySpace != 100 || !cfg.Quiet || cfg.CSV {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+	if want := []string{"set", "get", "ping"}; !reflect.DeepEqual(cfg.Tests, want) {
+		t.Fatalf("Tests = %q, want %q", cfg.Tests, want)
+	}
+
+	cfg, err = loadConfig(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def := defaultConfig(); !reflect.DeepEqual(cfg, def) {
+		t.Fatalf("no flags = %+v, want the defaults %+v", cfg, def)
+	}
+
+	cfg, err = loadConfig([]string{"-mix", "GET:8, set:2,incr"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []mixEntry{{"get", 8}, {"set", 2}, {"incr", 1}}; !reflect.DeepEqual(cfg.Mix, want) {
+		t.Fatalf("Mix = %+v, want %+v", cfg.Mix, want)
+	}
+
+	for _, args := range [][]string{
+		{"-p", "0"},
+		{"-p", "65536"},
+		{"-c", "0"},
+		{"-n", "-1"},
+		{"-P", "0"},
+		{"-d", "-1"},
+		{"-r", "-1"},
+		{"-t", "ping,nope"},
+		{"-mix", "get:0"},
+		{"-mix", "get:x"},
+		{"-mix", "nope:1"},
+		{"-bogus"},
+	} {
+		if _, err := loadConfig(args); err == nil {
+			t.Errorf("loadConfig(%q) accepted bad input", args)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Command benchmark is a load generator in the spirit of redis-benchmark:
+// it runs each test with many parallel, optionally pipelined connections
+// and reports throughput and latency percentiles.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+)
+
+func main() {
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatalf("failed to load config: %s", err.Error())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+
+	pool := conn.NewConnPoolWithOptions(cfg.Addr(), conn.Options{
+		MaxActive: cfg.Clients,
+		Password:  cfg.Password,
+	})
+	defer pool.Close()
+
+	if cfg.CSV {
+		writeCSVHeader(os.Stdout)
+	}
+	if len(cfg.Mix) > 0 {
+		res, err := run(ctx, pool, cfg, "mix", mixed(cfg.Mix))
+		if err != nil {
+			log.Fatalf("mix: %s", err.Error())
+		}
+		writeResult(os.Stdout, cfg, res)
+		return
+	}
+	// A test that loses its connections is reported and the rest still run.
+	failed := false
+	for _, name := range cfg.Tests {
+		res, err := run(ctx, pool, cfg, name, tests[name])
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Fatalf("%s: %s", name, err.Error())
+			}
+			log.Printf("%s: %s after %d requests", name, err.Error(), res.requests)
+			failed = true
+			continue
+		}
+		writeResult(os.Stdout, cfg, res)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
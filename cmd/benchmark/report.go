@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// percentiles reported for every test.
+var percentiles = []float64{50, 90, 95, 99, 99.9, 100}
+
+// percentile returns the latency below which p percent of requests fell.
+func (r result) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(r.latencies)))) - 1
+	return r.latencies[max(i, 0)]
+}
+
+func (r result) rps() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.requests) / r.elapsed.Seconds()
+}
+
+func (r result) mean() time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, l := range r.latencies {
+		sum += l
+	}
+	return sum / time.Duration(len(r.latencies))
+}
+
+func ms(d time.Duration) string {
+	return fmt.Sprintf("%.3f", float64(d)/float64(time.Millisecond))
+}
+
+func writeCSVHeader(out io.Writer) {
+	fmt.Fprintln(out, `"test","rps","avg_latency_ms","min_latency_ms","p50_latency_ms","p95_latency_ms","p99_latency_ms","max_latency_ms","errors"`)
+}
+
+func writeResult(out io.Writer, cfg *Config, r result) {
+	name := strings.ToUpper(r.name)
+	switch {
+	case cfg.CSV:
+		fmt.Fprintf(out, "%q,\"%.2f\",%q,%q,%q,%q,%q,%q,\"%d\"\n", name, r.rps(),
+			ms(r.mean()), ms(r.percentile(0)), ms(r.percentile(50)), ms(r.percentile(95)),
+			ms(r.percentile(99)), ms(r.percentile(100)), r.errors)
+	case cfg.Quiet:
+		fmt.Fprintf(out, "%s: %.2f requests per second, p50=%s msec", name, r.rps(), ms(r.percentile(50)))
+		if r.errors > 0 {
+			fmt.Fprintf(out, " (%d errors)", r.errors)
+		}
+		fmt.Fprintln(out)
+	default:
+		fmt.Fprintf(out, "====== %s ======\n", name)
+		fmt.Fprintf(out, "  %d requests completed in %.2f seconds\n", r.requests, r.elapsed.Seconds())
+		fmt.Fprintf(out, "  %d parallel clients\n", cfg.Clients)
+		fmt.Fprintf(out, "  %d bytes payload\n", cfg.DataSize)
+		fmt.Fprintf(out, "  pipeline %d\n", cfg.Pipeline)
+		if r.errors > 0 {
+			fmt.Fprintf(out, "  %d error replies\n", r.errors)
+		}
+		fmt.Fprintf(out, "\nLatency by percentile (msec):\n")
+		for _, p := range percentiles {
+			fmt.Fprintf(out, "  %6.2f%% <= %s\n", p, ms(r.percentile(p)))
+		}
+		fmt.Fprintf(out, "\nSummary:\n")
+		fmt.Fprintf(out, "  throughput summary: %.2f requests per second\n", r.rps())
+		fmt.Fprintf(out, "  latency summary (msec): avg %s, min %s, p50 %s, p95 %s, p99 %s, max %s\n\n",
+			ms(r.mean()), ms(r.percentile(0)), ms(r.percentile(50)), ms(r.percentile(95)),
+			ms(r.percentile(99)), ms(r.percentile(100)))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResultStats(t *testing.T) {
+	r := result{requests: 10, elapsed: 2 * time.Second}
+	for i := 1; i <= 10; i++ {
+		r.latencies = append(r.latencies, time.Duration(i)*time.Millisecond)
+	}
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{10, time.Millisecond},
+		{11, 2 * time.Millisecond},
+		{50, 5 * time.Millisecond},
+		{90, 9 * time.Millisecond},
+		{95, 10 * time.Millisecond},
+		{99.9, 10 * time.Millisecond},
+		{100, 10 * time.Millisecond},
+	} {
+		if got := r.percentile(tc.p); got != tc.want {
+			t.Errorf("percentile(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+	if got := r.rps(); got != 5 {
+		t.Errorf("rps = %v, want 5", got)
+	}
+	if got := r.mean(); got != 5500*time.Microsecond {
+		t.Errorf("mean = %v, want 5.5ms", got)
+	}
+
+	var empty result
+	if empty.percentile(50) != 0 || empty.mean() != 0 || empty.rps() != 0 {
+		t.Error("an empty result should report zeros")
+	}
+}
+
+func TestWriteResult(t *testing.T) {
+	r := result{name: "get", requests: 4, errors: 1, elapsed: time.Second,
+		latencies: []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond}}
+
+	var out bytes.Buffer
+	writeResult(&out, &Config{CSV: true}, r)
+	if want := `"GET","4.00","2.500","1.000","2.000","4.000","4.000","4.000","1"` + "\n"; out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	writeResult(&out, &Config{Quiet: true}, r)
+	if want := "GET: 4.00 requests per second, p50=2.000 msec (1 errors)\n"; out.String() != want {
+		t.Errorf("quiet = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	writeResult(&out, &Config{Clients: 2, Pipeline: 1}, r)
+	for _, want := range []string{"====== GET ======", "4 requests completed in 1.00 seconds", "1 error replies",
+		" 50.00% <= 2.000", "throughput summary: 4.00 requests per second"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report is missing %q:\n%s", want, out.String())
+		}
+	}
+}
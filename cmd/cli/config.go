@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+)
+
+// Config holds the connection settings. A -u URI is applied first and any
+// other flag that was set explicitly overrides the matching part of it.
+type Config struct {
+	Host     string
+	Port     int
+	DB       int
+	Username string
+	Password string
+	TLS      bool
+	// Output is how replies are printed: pretty, raw or json.
+	Output string
+	// Pipe streams commands from stdin for mass insertion instead of
+	// starting the prompt.
+	Pipe bool
+	// Scan, BigKeys and MemKeys walk the keys matching Pattern, COUNT at
+	// a time, listing them or reporting the largest per type.
+	Scan    bool
+	BigKeys bool
+	MemKeys bool
+	Pattern string
+	Count   int
+	// Eval is a Lua script file to run with EVAL, and EvalArgs the keys and
+	// arguments given after the flags, split by a lone ",".
+	Eval     string
+	EvalArgs []string
+	// Cluster follows -MOVED and -ASK redirects to other nodes.
+	Cluster bool
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Host:    "127.0.0.1",
+		Port:    8090,
+		Output:  outputPretty,
+		Pattern: "*",
+		Count:   10,
+	}
+}
+
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("cli", flag.ContinueOnError)
+	host := fs.String("h", cfg.Host, "server hostname")
+	port := fs.Int("p", cfg.Port, "server port")
+	db := fs.Int("n", cfg.DB, "database number, selected after connecting")
+	password := fs.String("a", "", "password to use when connecting")
+	user := fs.String("user", "", "username to use when connecting")
+	uri := fs.String("u", "", "server URI: redis://[user:password@]host[:port][/db], or rediss:// for TLS")
+	raw := fs.Bool("raw", false, "print replies without quotes or type hints, for scripts")
+	asJSON := fs.Bool("json", false, "print replies as JSON")
+	fs.BoolVar(&cfg.Pipe, "pipe", false, "pipeline commands read from stdin (RESP or one per line) and report a summary")
+	fs.BoolVar(&cfg.Scan, "scan", false, "list all keys matching -pattern using SCAN")
+	fs.BoolVar(&cfg.BigKeys, "bigkeys", false, "sample the keyspace for the biggest key of each type")
+	fs.BoolVar(&cfg.MemKeys, "memkeys", false, "sample the keyspace for the key of each type using the most memory")
+	fs.StringVar(&cfg.Pattern, "pattern", cfg.Pattern, "key pattern for -scan, -bigkeys and -memkeys")
+	fs.IntVar(&cfg.Count, "count", cfg.Count, "SCAN COUNT hint for -scan, -bigkeys and -memkeys")
+	fs.BoolVar(&cfg.Cluster, "c", false, "cluster mode: follow -MOVED and -ASK redirects")
+	fs.StringVar(&cfg.Eval, "eval", "", "run the Lua script in this file; keys and args follow the flags as: key1 key2 , arg1 arg2")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	cfg.EvalArgs = fs.Args()
+	if *raw && *asJSON {
+		return nil, fmt.Errorf("--raw and --json are mutually exclusive")
+	}
+	if *raw {
+		cfg.Output = outputRaw
+	}
+	if *asJSON {
+		cfg.Output = outputJSON
+	}
+
+	if *uri != "" {
+		if err := cfg.parseURI(*uri); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "h":
+			cfg.Host = *host
+		case "p":
+			cfg.Port = *port
+		case "n":
+			cfg.DB = *db
+		case "a":
+			cfg.Password = *password
+		case "user":
+			cfg.Username = *user
+		}
+	})
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) parseURI(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid URI: %w", err)
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		c.TLS = true
+	default:
+		return fmt.Errorf("invalid URI scheme %q, want redis:// or rediss://", u.Scheme)
+	}
+
+	if h := u.Hostname(); h != "" {
+		c.Host = h
+	}
+	if p := u.Port(); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return fmt.Errorf("invalid port %q in URI", p)
+		}
+		c.Port = n
+	}
+	if u.User != nil {
+		// redis://:password@host has no username; redis://password@host is
+		// the older password-only form.
+		if pw, ok := u.User.Password(); ok {
+			c.Username, c.Password = u.User.Username(), pw
+		} else {
+			c.Password = u.User.Username()
+		}
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		n, err := strconv.Atoi(path)
+		if err != nil {
+			return fmt.Errorf("invalid database %q in URI", path)
+		}
+		c.DB = n
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("invalid port %d", c.Port)
+	}
+	if c.DB < 0 {
+		return fmt.Errorf("invalid database %d", c.DB)
+	}
+	if c.Count < 1 {
+		return fmt.Errorf("invalid count %d", c.Count)
+	}
+	modes := 0
+	for _, on := range []bool{c.Pipe, c.Scan, c.BigKeys, c.MemKeys, c.Eval != ""} {
+		if on {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return fmt.Errorf("only one of --pipe, --scan, --bigkeys, --memkeys and --eval may be given")
+	}
+	return nil
+}
+
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+func (c *Config) poolOptions() conn.Options {
+	opts := conn.Options{
+		MaxActive: 6,
+		MinIdle:   6,
+		Username:  c.Username,
+		Password:  c.Password,
+		DB:        c.DB,
+	}
+	if c.TLS {
+		opts.TLSConfig = &tls.Config{}
+	}
+	return opts
+}
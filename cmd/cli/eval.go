@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// evalCommand builds the EVAL for a script file. words are the keys and
+// arguments from the command line, keys first and separated from the
+// arguments by a lone ",".
+func evalCommand(path string, words []string) ([]string, error) {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, args := words, []string(nil)
+	for i, w := range words {
+		if w != "," {
+			continue
+		}
+		keys, args = words[:i], words[i+1:]
+		for _, a := range args {
+			if a == "," {
+				return nil, fmt.Errorf(`--eval takes a single "," between keys and arguments`)
+			}
+		}
+		break
+	}
+	cmd := make([]string, 0, 3+len(keys)+len(args))
+	cmd = append(cmd, "EVAL", string(script), strconv.Itoa(len(keys)))
+	cmd = append(cmd, keys...)
+	return append(cmd, args...), nil
+}
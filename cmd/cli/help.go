@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// commandDoc is what HELP shows for one command.
+type commandDoc struct {
+	Name    string
+	Params  string
+	Summary string
+	Since   string
+	Group   string
+}
+
+// builtinDocs covers the common commands for servers without COMMAND DOCS.
+var builtinDocs = []commandDoc{
+	{"APPEND", "key value", "Appends a string to the value of a key.", "2.0.0", "string"},
+	{"AUTH", "[username] password", "Authenticates the connection.", "1.0.0", "connection"},
+	{"BLPOP", "key [key ...] timeout", "Removes and returns the first element in a list, blocking until one is available.", "2.0.0", "list"},
+	{"BRPOP", "key [key ...] timeout", "Removes and returns the last element in a list, blocking until one is available.", "2.0.0", "list"},
+	{"CLIENT", "subcommand [argument ...]", "A container for client connection commands.", "2.4.0", "connection"},
+	{"CONFIG", "subcommand [argument ...]", "A container for server configuration commands.", "2.0.0", "server"},
+	{"DBSIZE", "", "Returns the number of keys in the database.", "1.0.0", "server"},
+	{"DECR", "key", "Decrements the integer value of a key by one.", "1.0.0", "string"},
+	{"DECRBY", "key decrement", "Decrements a number from the integer value of a key.", "1.0.0", "string"},
+	{"DEL", "key [key ...]", "Deletes one or more keys.", "1.0.0", "generic"},
+	{"DISCARD", "", "Discards a transaction.", "2.0.0", "transactions"},
+	{"ECHO", "message", "Returns the given string.", "1.0.0", "connection"},
+	{"EVAL", "script numkeys [key [key ...]] [arg [arg ...]]", "Executes a server-side Lua script.", "2.6.0", "scripting"},
+	{"EXEC", "", "Executes all commands in a transaction.", "1.2.0", "transactions"},
+	{"EXISTS", "key [key ...]", "Determines whether one or more keys exist.", "1.0.0", "generic"},
+	{"EXPIRE", "key seconds [NX|XX|GT|LT]", "Sets the expiration time of a key in seconds.", "1.0.0", "generic"},
+	{"FLUSHALL", "[ASYNC|SYNC]", "Removes all keys from all databases.", "1.0.0", "server"},
+	{"FLUSHDB", "[ASYNC|SYNC]", "Removes all keys from the current database.", "1.0.0", "server"},
+	{"GET", "key", "Returns the string value of a key.", "1.0.0", "string"},
+	{"GETDEL", "key", "Returns the string value of a key after deleting the key.", "6.2.0", "string"},
+	{"GETRANGE", "key start end", "Returns a substring of the string stored at a key.", "2.4.0", "string"},
+	{"HDEL", "key field [field ...]", "Deletes one or more fields and their values from a hash.", "2.0.0", "hash"},
+	{"HELLO", "[protover [AUTH username password] [SETNAME clientname]]", "Handshakes with the server.", "6.0.0", "connection"},
+	{"HEXISTS", "key field", "Determines whether a field exists in a hash.", "2.0.0", "hash"},
+	{"HEXPIRE", "key seconds [NX|XX|GT|LT] FIELDS numfields field [field ...]", "Sets the expiration time of hash fields in seconds.", "7.4.0", "hash"},
+	{"HEXPIREAT", "key unix-time-seconds [NX|XX|GT|LT] FIELDS numfields field [field ...]", "Sets the expiration time of hash fields to a Unix timestamp in seconds.", "7.4.0", "hash"},
+	{"HEXPIRETIME", "key FIELDS numfields field [field ...]", "Returns the expiration time of hash fields as a Unix timestamp in seconds.", "7.4.0", "hash"},
+	{"HGET", "key field", "Returns the value of a field in a hash.", "2.0.0", "hash"},
+	{"HGETALL", "key", "Returns all fields and values in a hash.", "2.0.0", "hash"},
+	{"HKEYS", "key", "Returns all fields in a hash.", "2.0.0", "hash"},
+	{"HLEN", "key", "Returns the number of fields in a hash.", "2.0.0", "hash"},
+	{"HMGET", "key field [field ...]", "Returns the values of all fields in a hash.", "2.0.0", "hash"},
+	{"HPERSIST", "key FIELDS numfields field [field ...]", "Removes the expiration time of hash fields.", "7.4.0", "hash"},
+	{"HPEXPIRE", "key milliseconds [NX|XX|GT|LT] FIELDS numfields field [field ...]", "Sets the expiration time of hash fields in milliseconds.", "7.4.0", "hash"},
+	{"HPEXPIREAT", "key unix-time-milliseconds [NX|XX|GT|LT] FIELDS numfields field [field ...]", "Sets the expiration time of hash fields to a Unix timestamp in milliseconds.", "7.4.0", "hash"},
+	{"HPEXPIRETIME", "key FIELDS numfields field [field ...]", "Returns the expiration time of hash fields as a Unix timestamp in milliseconds.", "7.4.0", "hash"},
+	{"HPTTL", "key FIELDS numfields field [field ...]", "Returns the time to live of hash fields in milliseconds.", "7.4.0", "hash"},
+	{"HSET", "key field value [field value ...]", "Creates or modifies the value of a field in a hash.", "2.0.0", "hash"},
+	{"HSETNX", "key field value", "Sets the value of a field in a hash only when the field doesn't exist.", "2.0.0", "hash"},
+	{"HSTRLEN", "key field", "Returns the length of the value of a field.", "3.2.0", "hash"},
+	{"HTTL", "key FIELDS numfields field [field ...]", "Returns the time to live of hash fields in seconds.", "7.4.0", "hash"},
+	{"HVALS", "key", "Returns all values in a hash.", "2.0.0", "hash"},
+	{"INCR", "key", "Increments the integer value of a key by one.", "1.0.0", "string"},
+	{"INCRBY", "key increment", "Increments the integer value of a key by a number.", "1.0.0", "string"},
+	{"INFO", "[section [section ...]]", "Returns information and statistics about the server.", "1.0.0", "server"},
+	{"KEYS", "pattern", "Returns all key names that match a pattern.", "1.0.0", "generic"},
+	{"LLEN", "key", "Returns the length of a list.", "1.0.0", "list"},
+	{"LPOP", "key [count]", "Returns the first elements in a list after removing them.", "1.0.0", "list"},
+	{"LPUSH", "key element [element ...]", "Prepends one or more elements to a list.", "1.0.0", "list"},
+	{"LRANGE", "key start stop", "Returns a range of elements from a list.", "1.0.0", "list"},
+	{"MGET", "key [key ...]", "Atomically returns the string values of one or more keys.", "1.0.0", "string"},
+	{"MONITOR", "", "Listens for all requests received by the server in real time.", "1.0.0", "server"},
+	{"MSET", "key value [key value ...]", "Atomically creates or modifies the string values of one or more keys.", "1.0.1", "string"},
+	{"MULTI", "", "Starts a transaction.", "1.2.0", "transactions"},
+	{"PERSIST", "key", "Removes the expiration time of a key.", "2.2.0", "generic"},
+	{"PEXPIRE", "key milliseconds [NX|XX|GT|LT]", "Sets the expiration time of a key in milliseconds.", "2.6.0", "generic"},
+	{"PING", "[message]", "Returns the server's liveliness response.", "1.0.0", "connection"},
+	{"PSUBSCRIBE", "pattern [pattern ...]", "Listens for messages published to channels that match one or more patterns.", "2.0.0", "pubsub"},
+	{"PTTL", "key", "Returns the expiration time in milliseconds of a key.", "2.6.0", "generic"},
+	{"PUBLISH", "channel message", "Posts a message to a channel.", "2.0.0", "pubsub"},
+	{"PUNSUBSCRIBE", "[pattern [pattern ...]]", "Stops listening to messages published to channels that match one or more patterns.", "2.0.0", "pubsub"},
+	{"QUIT", "", "Closes the connection.", "1.0.0", "connection"},
+	{"RENAME", "key newkey", "Renames a key and overwrites the destination.", "1.0.0", "generic"},
+	{"RPOP", "key [count]", "Returns and removes the last elements of a list.", "1.0.0", "list"},
+	{"RPUSH", "key element [element ...]", "Appends one or more elements to a list.", "1.0.0", "list"},
+	{"SCAN", "cursor [MATCH pattern] [COUNT count] [TYPE type]", "Iterates over the key names in the database.", "2.8.0", "generic"},
+	{"SELECT", "index", "Changes the selected database.", "1.0.0", "connection"},
+	{"SET", "key value [NX|XX] [GET] [EX seconds|PX milliseconds|EXAT unix-time-seconds|PXAT unix-time-milliseconds|KEEPTTL]", "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.", "1.0.0", "string"},
+	{"STRLEN", "key", "Returns the length of a string value.", "2.2.0", "string"},
+	{"SUBSCRIBE", "channel [channel ...]", "Listens for messages published to channels.", "2.0.0", "pubsub"},
+	{"TTL", "key", "Returns the expiration time in seconds of a key.", "1.0.0", "generic"},
+	{"TYPE", "key", "Determines the type of value stored at a key.", "1.0.0", "generic"},
+	{"UNSUBSCRIBE", "[channel [channel ...]]", "Stops listening to messages posted to channels.", "2.0.0", "pubsub"},
+}
+
+// helpIndex answers HELP, preferring the server's COMMAND DOCS, fetched
+// on first use, over builtinDocs.
+type helpIndex struct {
+	fetch  func() (resp.Value, error)
+	loaded bool
+	docs   map[string]commandDoc
+}
+
+func newHelpIndex(fetch func() (resp.Value, error)) *helpIndex {
+	return &helpIndex{fetch: fetch}
+}
+
+func (h *helpIndex) load() {
+	if h.loaded {
+		return
+	}
+	h.loaded = true
+	h.docs = make(map[string]commandDoc, len(builtinDocs))
+	for _, d := range builtinDocs {
+		h.docs[d.Name] = d
+	}
+	v, err := h.fetch()
+	if err != nil || v.Typ == "error" {
+		return
+	}
+	for _, p := range pairsOf(v) {
+		if d, ok := parseCommandDoc(rawText(p.Key), p.Value); ok {
+			h.docs[d.Name] = d
+		}
+	}
+}
+
+// write prints help for args, the words after HELP: nothing for an
+// overview, @group for every command in a group, or a command name.
+func (h *helpIndex) write(out io.Writer, args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(out, "To get help about commands type:\n"+
+			"      \"help @<group>\" to get a list of commands in <group>\n"+
+			"      \"help <command>\" for help on <command>\n"+
+			"To quit the CLI type \"quit\" or \"exit\".\n")
+		return
+	}
+	h.load()
+	topic := strings.Join(args, " ")
+	if group, ok := strings.CutPrefix(topic, "@"); ok {
+		var names []string
+		for name, d := range h.docs {
+			if strings.EqualFold(d.Group, group) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			fmt.Fprintf(out, "No commands in group %q\n", group)
+			return
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			writeDoc(out, h.docs[name])
+		}
+		return
+	}
+	d, ok := h.docs[strings.ToUpper(topic)]
+	if !ok {
+		fmt.Fprintf(out, "No help for %q\n", topic)
+		return
+	}
+	writeDoc(out, d)
+}
+
+func writeDoc(out io.Writer, d commandDoc) {
+	fmt.Fprintf(out, "\n  %s %s\n", d.Name, d.Params)
+	fmt.Fprintf(out, "  summary: %s\n", d.Summary)
+	fmt.Fprintf(out, "  since: %s\n", d.Since)
+	fmt.Fprintf(out, "  group: %s\n", d.Group)
+}
+
+// pairsOf reads a RESP3 map, or the flat key-value array RESP2 sends in
+// its place.
+func pairsOf(v resp.Value) []resp.Pair {
+	if v.Typ == "map" {
+		return v.Map
+	}
+	pairs := make([]resp.Pair, 0, len(v.Array)/2)
+	for i := 0; i+1 < len(v.Array); i += 2 {
+		pairs = append(pairs, resp.Pair{Key: v.Array[i], Value: v.Array[i+1]})
+	}
+	return pairs
+}
+
+// parseCommandDoc reads one entry of COMMAND DOCS, rebuilding the syntax
+// line from the argument tree.
+func parseCommandDoc(name string, v resp.Value) (commandDoc, bool) {
+	d := commandDoc{Name: strings.ToUpper(name)}
+	if d.Name == "" || strings.Contains(d.Name, "|") {
+		return d, false
+	}
+	var params []string
+	for _, p := range pairsOf(v) {
+		switch rawText(p.Key) {
+		case "summary":
+			d.Summary = rawText(p.Value)
+		case "since":
+			d.Since = rawText(p.Value)
+		case "group":
+			d.Group = rawText(p.Value)
+		case "arguments":
+			for _, a := range p.Value.Array {
+				params = append(params, argSyntax(a))
+			}
+		}
+	}
+	d.Params = strings.Join(params, " ")
+	return d, true
+}
+
+// argSyntax renders one COMMAND DOCS argument the way the manual does:
+// "[EX seconds|PX milliseconds]", "key [key ...]" and so on.
+func argSyntax(v resp.Value) string {
+	var name, typ, token string
+	var optional, multiple bool
+	var children []string
+	for _, p := range pairsOf(v) {
+		switch rawText(p.Key) {
+		case "name":
+			name = rawText(p.Value)
+		case "type":
+			typ = rawText(p.Value)
+		case "token":
+			token = rawText(p.Value)
+		case "flags":
+			for _, f := range p.Value.Array {
+				switch rawText(f) {
+				case "optional":
+					optional = true
+				case "multiple":
+					multiple = true
+				}
+			}
+		case "arguments":
+			for _, a := range p.Value.Array {
+				children = append(children, argSyntax(a))
+			}
+		}
+	}
+
+	s := name
+	switch typ {
+	case "pure-token":
+		s = token
+	case "oneof":
+		s = strings.Join(children, "|")
+	case "block":
+		s = strings.Join(children, " ")
+	}
+	if token != "" && typ != "pure-token" {
+		s = token + " " + s
+	}
+	if multiple {
+		s = s + " [" + s + " ...]"
+	}
+	if optional {
+		s = "[" + s + "]"
+	}
+	return s
+}
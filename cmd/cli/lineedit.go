@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const maxHistory = 1000
+
+// errInterrupted is returned by ReadLine when Ctrl-C is pressed on an
+// empty line.
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads commands with readline-style editing when stdin is a
+// terminal, and plain lines otherwise. History is kept in memory and
+// appended to histPath as commands are entered.
+type lineEditor struct {
+	in       *bufio.Reader
+	out      io.Writer
+	fd       int
+	tty      bool
+	history  []string
+	histPath string
+}
+
+func newLineEditor(histPath string) *lineEditor {
+	e := &lineEditor{
+		in:       bufio.NewReader(os.Stdin),
+		out:      os.Stdout,
+		fd:       int(os.Stdin.Fd()),
+		histPath: histPath,
+	}
+	e.tty = isTerminal(e.fd)
+	e.loadHistory()
+	return e
+}
+
+// historyPath is ~/.redis_clone_history, or "" when there is no home.
+func historyPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".redis_clone_history")
+}
+
+func (e *lineEditor) loadHistory() {
+	if e.histPath == "" {
+		return
+	}
+	data, err := os.ReadFile(e.histPath)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+		os.WriteFile(e.histPath, []byte(strings.Join(e.history, "\n")+"\n"), 0o600)
+	}
+}
+
+// AddHistory records a command, skipping repeats of the previous one and
+// commands that carry a password.
+func (e *lineEditor) AddHistory(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	if first, _, _ := strings.Cut(strings.ToUpper(line), " "); first == "AUTH" || first == "HELLO" {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[1:]
+	}
+	if e.histPath == "" {
+		return
+	}
+	f, err := os.OpenFile(e.histPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// ReadLine prints prompt and returns the next line without its newline,
+// or io.EOF once input ends.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	if !e.tty {
+		fmt.Fprint(e.out, prompt)
+		line, err := e.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	restore, err := makeRaw(e.fd)
+	if err != nil {
+		e.tty = false
+		return e.ReadLine(prompt)
+	}
+	defer restore()
+	s := &editState{e: e, prompt: prompt, hist: len(e.history)}
+	s.refresh()
+	return s.run()
+}
+
+// editState is one line being edited.
+type editState struct {
+	e      *lineEditor
+	prompt string
+	buf    []rune
+	pos    int
+	hist   int    // index into history while browsing; len(history) is the new line
+	saved  []rune // the new line, kept while browsing history
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyDelete    = 127
+
+	// Decoded escape sequences, outside the rune range.
+	keyUp = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDel
+	keyUnknown
+)
+
+func (s *editState) run() (string, error) {
+	for {
+		k, err := s.readKey()
+		if err != nil {
+			return "", err
+		}
+		switch k {
+		case keyEnter, '\n':
+			s.write("\r\n")
+			return string(s.buf), nil
+		case keyCtrlC:
+			s.write("^C\r\n")
+			if len(s.buf) == 0 {
+				return "", errInterrupted
+			}
+			s.buf, s.pos = nil, 0
+			s.refresh()
+		case keyCtrlD:
+			if len(s.buf) == 0 {
+				s.write("\r\n")
+				return "", io.EOF
+			}
+			s.deleteAt(s.pos)
+		case keyBackspace, keyDelete:
+			if s.pos > 0 {
+				s.pos--
+				s.deleteAt(s.pos)
+			}
+		case keyDel:
+			s.deleteAt(s.pos)
+		case keyLeft, keyCtrlB:
+			if s.pos > 0 {
+				s.pos--
+				s.refresh()
+			}
+		case keyRight, keyCtrlF:
+			if s.pos < len(s.buf) {
+				s.pos++
+				s.refresh()
+			}
+		case keyHome, keyCtrlA:
+			s.pos = 0
+			s.refresh()
+		case keyEnd, keyCtrlE:
+			s.pos = len(s.buf)
+			s.refresh()
+		case keyUp, keyCtrlP:
+			s.browse(-1)
+		case keyDown, keyCtrlN:
+			s.browse(1)
+		case keyCtrlK:
+			s.buf = s.buf[:s.pos]
+			s.refresh()
+		case keyCtrlU:
+			s.buf = append([]rune{}, s.buf[s.pos:]...)
+			s.pos = 0
+			s.refresh()
+		case keyCtrlW:
+			start := s.pos
+			for start > 0 && s.buf[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && s.buf[start-1] != ' ' {
+				start--
+			}
+			s.buf = append(s.buf[:start], s.buf[s.pos:]...)
+			s.pos = start
+			s.refresh()
+		case keyCtrlL:
+			s.write("\x1b[H\x1b[2J")
+			s.refresh()
+		case keyCtrlR:
+			if line, ok, err := s.search(); err != nil {
+				return "", err
+			} else if ok {
+				s.write("\r\n")
+				return line, nil
+			}
+		default:
+			if k >= 32 {
+				s.buf = append(s.buf[:s.pos], append([]rune{k}, s.buf[s.pos:]...)...)
+				s.pos++
+				s.refresh()
+			}
+		}
+	}
+}
+
+// readKey reads one key press, decoding the escape sequences of arrows,
+// Home, End and Delete.
+func (s *editState) readKey() (rune, error) {
+	r, _, err := s.e.in.ReadRune()
+	if err != nil || r != keyEsc {
+		return r, err
+	}
+	next, _, err := s.e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+	code, _, err := s.e.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	switch code {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if code < '0' || code > '9' {
+		return keyUnknown, nil
+	}
+	// ESC [ n ~
+	if tilde, _, err := s.e.in.ReadRune(); err != nil {
+		return 0, err
+	} else if tilde != '~' {
+		return keyUnknown, nil
+	}
+	switch code {
+	case '1', '7':
+		return keyHome, nil
+	case '4', '8':
+		return keyEnd, nil
+	case '3':
+		return keyDel, nil
+	}
+	return keyUnknown, nil
+}
+
+func (s *editState) deleteAt(i int) {
+	if i < 0 || i >= len(s.buf) {
+		return
+	}
+	s.buf = append(s.buf[:i], s.buf[i+1:]...)
+	s.refresh()
+}
+
+// browse moves through history by dir, keeping the line being typed so
+// moving past the newest entry brings it back.
+func (s *editState) browse(dir int) {
+	history := s.e.history
+	next := s.hist + dir
+	if next < 0 || next > len(history) {
+		return
+	}
+	if s.hist == len(history) {
+		s.saved = append([]rune{}, s.buf...)
+	}
+	s.hist = next
+	if next == len(history) {
+		s.buf = append([]rune{}, s.saved...)
+	} else {
+		s.buf = []rune(history[next])
+	}
+	s.pos = len(s.buf)
+	s.refresh()
+}
+
+// search runs a Ctrl-R reverse incremental search. Enter runs the match,
+// Ctrl-G or Esc cancel, and any other control key keeps the match for
+// editing.
+func (s *editState) search() (string, bool, error) {
+	var query []rune
+	from := len(s.e.history) // search entries before this index
+	match := -1
+	find := func(before int) {
+		for i := before - 1; i >= 0; i-- {
+			if strings.Contains(s.e.history[i], string(query)) {
+				match = i
+				return
+			}
+		}
+	}
+	show := func() {
+		text := ""
+		if match >= 0 {
+			text = s.e.history[match]
+		}
+		s.write(fmt.Sprintf("\r\x1b[K(reverse-i-search)`%s': %s", string(query), text))
+	}
+	show()
+	for {
+		k, err := s.readKey()
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case k == keyCtrlR:
+			if match > 0 {
+				find(match)
+			}
+		case k == keyBackspace || k == keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = -1
+				find(from)
+			}
+		case k == keyEnter || k == '\n':
+			if match >= 0 {
+				return s.e.history[match], true, nil
+			}
+			s.refresh()
+			return "", false, nil
+		case k == keyCtrlG || k == keyEsc || k == keyCtrlC:
+			s.refresh()
+			return "", false, nil
+		case k >= 32:
+			query = append(query, k)
+			start := from
+			if match >= 0 {
+				start = match + 1
+			}
+			match = -1
+			find(start)
+		default:
+			if match >= 0 {
+				s.buf = []rune(s.e.history[match])
+				s.pos = len(s.buf)
+				s.hist = match
+			}
+			s.refresh()
+			return "", false, nil
+		}
+		show()
+	}
+}
+
+// refresh redraws the prompt and buffer and puts the cursor back.
+func (s *editState) refresh() {
+	s.write(fmt.Sprintf("\r\x1b[K%s%s\r", s.prompt, string(s.buf)))
+	if col := len([]rune(s.prompt)) + s.pos; col > 0 {
+		s.write(fmt.Sprintf("\x1b[%dC", col))
+	}
+}
+
+func (s *editState) write(str string) {
+	io.WriteString(s.e.out, str)
+}
@@ -3,42 +3,110 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
-	"github.com/jafari-mohammad-reza/redis-clone/pkg"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/client"
 	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
 	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
 )
 
 func main() {
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt, syscall.SIGINT)
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatalf("failed to load config: %s", err.Error())
+	}
+
+	// Ctrl-C is left to the prompt, which reads it as a key, and to
+	// streaming modes like SUBSCRIBE, which catch it to stop streaming.
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
 
 	// create a connection pool that send each request to one of connection in pool and each connection must be replaced with new one if disconnected
-	connPool := conn.NewConnPool(":8090", 6) // 6 connection
+	connPool := conn.NewConnPoolWithOptions(cfg.Addr(), cfg.poolOptions())
 
 	defer connPool.Close()
 
 	// send ping request to check if connection was successful
-	if err := pingServer(connPool); err != nil {
+	if err := pingServer(ctx, connPool); err != nil {
 		log.Fatalf("failed to ping server: %s", err.Error())
 		return
 	}
+	if cfg.Pipe {
+		st, err := runPipe(ctx, connPool, os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			log.Fatalf("pipe failed after %d replies: %s", st.replies, err.Error())
+		}
+		if st.errors > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.Eval != "" {
+		cmd, err := evalCommand(cfg.Eval, cfg.EvalArgs)
+		if err != nil {
+			log.Fatalf("failed to read script: %s", err.Error())
+		}
+		conn, err := connPool.Get(ctx)
+		if err != nil {
+			log.Fatalf("failed to get conn from conn pool: %s", err.Error())
+		}
+		resp, err := SendCmd(conn, cmd[0], cmd[1:]...)
+		if err != nil || resp == nil {
+			connPool.Discard(conn)
+			log.Fatalf("failed to run script: %v", err)
+		}
+		connPool.Put(conn)
+		fmt.Print(formatReply(*resp, cfg.Output))
+		if resp.Typ == "error" {
+			os.Exit(1)
+		}
+		return
+	}
+	if cfg.Scan || cfg.BigKeys || cfg.MemKeys {
+		c := client.NewFromPool(connPool)
+		if cfg.Scan {
+			err = runScan(ctx, c, cfg.Pattern, cfg.Count, os.Stdout)
+		} else {
+			err = runBigKeys(ctx, c, cfg.Pattern, cfg.Count, cfg.MemKeys, os.Stdout)
+		}
+		if err != nil {
+			log.Fatalf("keyspace scan failed: %s", err.Error())
+		}
+		return
+	}
 	// start reading user commands
-	scanner := bufio.NewScanner(os.Stdin)
+	editor := newLineEditor(historyPath())
+	sess := newSession(connPool, cfg.DB, os.Stdout)
+	defer sess.Close()
+	if cfg.Cluster {
+		sess.followRedirects(cfg.Addr(), cfg.poolOptions())
+	}
+	help := newHelpIndex(func() (resp.Value, error) {
+		return sess.Do(ctx, []string{"COMMAND", "DOCS"})
+	})
+	var readErr error
 	for {
-		conn := connPool.Get()
-		fmt.Print(">>>")
-		if !scanner.Scan() {
+		line, err := editor.ReadLine(">>>")
+		if err != nil {
+			if err != io.EOF && err != errInterrupted {
+				readErr = err
+			}
 			break
 		}
-		line := scanner.Text()
 		line = strings.TrimSpace(line)
+		editor.AddHistory(line)
 
 		if line == "" {
 			continue
@@ -46,40 +114,52 @@ func main() {
 		if line == "quit" || line == "exit" {
 			os.Exit(0)
 		}
-		spited := strings.Split(line, " ")
-		cmd, args := spited[0], spited[1:]
-		switch strings.ToUpper(cmd) {
-		case string(pkg.PING_CMD), string(pkg.SET_CMD), string(pkg.GET_CMD), string(pkg.DEL_CMD), string(pkg.RPUSH_CMD), string(pkg.RLEN_CMD), string(pkg.RRANGE_CMD), string(pkg.LPOP_CMD), string(pkg.RPOP_CMD):
-			resp, err := SendCmd(conn, strings.ToUpper(cmd), args...)
-			if err != nil {
+		// Quotes group words and "..." understands \n, \xhh and friends,
+		// the same rules the server applies to inline commands.
+		spited, err := resp.SplitArgs(line)
+		if err != nil {
+			fmt.Println("Invalid argument(s)")
+			continue
+		}
+		if len(spited) == 0 {
+			continue
+		}
+		if strings.EqualFold(spited[0], "HELP") {
+			help.write(os.Stdout, spited[1:])
+			continue
+		}
+		if strings.EqualFold(spited[0], "MONITOR") {
+			if err := runMonitor(ctx, connPool, cfg.Output, os.Stdout); err != nil {
 				fmt.Println(err.Error())
-				return
 			}
-			if resp == nil {
-				fmt.Println("nil response from server. wait few seconds for reconnect")
-				connPool.HealthCheckerOnce()
-				continue
+			continue
+		}
+		if _, ok := subscribeCmds[strings.ToUpper(spited[0])]; ok {
+			if err := runSubscribe(ctx, connPool, spited, cfg.Output, os.Stdout); err != nil {
+				fmt.Println(err.Error())
 			}
-			fmt.Println(*resp)
-
-		default:
-			fmt.Println("Invalid Command")
+			continue
 		}
+		reply, err := sess.Do(ctx, spited)
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		fmt.Print(formatReply(reply, outputMode(cfg.Output, spited)))
 	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Println("Error reading input:", err)
+	if readErr != nil {
+		fmt.Println("Error reading input:", readErr)
 	}
-
-	defer cancel()
-	<-ctx.Done()
+	cancel()
 }
+
+// SendCmd sends a command as an array of bulk strings and reads the reply.
+// Error replies come back as a Value for the caller to print.
 func SendCmd(conn net.Conn, command string, args ...string) (*resp.Value, error) {
-	cmd := make([]any, 0, len(args)+1)
+	cmd := make([]string, 0, len(args)+1)
 	cmd = append(cmd, command)
-	for _, arg := range args {
-		cmd = append(cmd, arg)
-	}
+	cmd = append(cmd, args...)
 	data, err := resp.Marshal(cmd)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marsha given cmd: %s", err.Error())
@@ -97,20 +177,21 @@ func SendCmd(conn net.Conn, command string, args ...string) (*resp.Value, error)
 	}
 	return &val, nil
 }
-func pingServer(connPool *conn.Pool) error {
-	conn := connPool.Get()
-	if conn == nil {
-
-		return fmt.Errorf("failed to get conn from conn pool")
+func pingServer(ctx context.Context, connPool *conn.Pool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	conn, err := connPool.Get(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get conn from conn pool: %w", err)
 	}
+	defer connPool.Put(conn)
 	pingCmd := []any{"PING"}
 	data, _ := resp.Marshal(pingCmd)
 	if _, err := conn.Write(data); err != nil { // send paylaod using RESP builder
 		return fmt.Errorf("failed to get PONG response: %s", err.Error())
 	}
-	reader := bufio.NewReader(conn)
-	val, _ := resp.UnmarshalOne(reader)
-	if val.Str != "PONG" {
+	var pong string
+	if err := resp.NewDecoder(conn).Decode(&pong); err != nil || pong != "PONG" {
 		return fmt.Errorf("failed to get PONG response")
 	}
 	return nil
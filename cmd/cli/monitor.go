@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// monitorEntry is one line of the MONITOR feed:
+//
+//	1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+type monitorEntry struct {
+	Time   time.Time `json:"time"`
+	DB     string    `json:"db"`
+	Client string    `json:"client"`
+	Args   []string  `json:"args"`
+}
+
+// parseMonitorLine splits a MONITOR line into its parts. The arguments are
+// quoted the way SplitArgs reads them.
+func parseMonitorLine(line string) (monitorEntry, bool) {
+	var e monitorEntry
+	stamp, rest, ok := strings.Cut(line, " [")
+	if !ok {
+		return e, false
+	}
+	origin, cmd, ok := strings.Cut(rest, "] ")
+	if !ok {
+		return e, false
+	}
+	secs, frac, _ := strings.Cut(stamp, ".")
+	sec, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return e, false
+	}
+	usec, _ := strconv.ParseInt((frac + "000000")[:6], 10, 64)
+	e.Time = time.Unix(sec, usec*1000)
+	e.DB, e.Client, _ = strings.Cut(origin, " ")
+	if e.Args, err = resp.SplitArgs(cmd); err != nil {
+		return e, false
+	}
+	return e, true
+}
+
+// runMonitor prints the server's command feed until Ctrl-C. Pretty output
+// shows the local time of each command, raw output passes the server's
+// lines through untouched and JSON output has one object per command.
+func runMonitor(ctx context.Context, pool *conn.Pool, mode string, out io.Writer) error {
+	return runStream(ctx, pool, []string{"MONITOR"}, nil, func(v resp.Value, _ bool) bool {
+		line := rawText(v)
+		if v.Typ == "error" || line == "OK" {
+			fmt.Fprint(out, formatReply(v, mode))
+			return v.Typ == "error"
+		}
+		e, ok := parseMonitorLine(line)
+		switch {
+		case mode == outputRaw || !ok:
+			fmt.Fprintln(out, line)
+		case mode == outputJSON:
+			data, _ := json.Marshal(e)
+			fmt.Fprintf(out, "%s\n", data)
+		default:
+			args := make([]string, len(e.Args))
+			for i, a := range e.Args {
+				args[i] = quote(a)
+			}
+			fmt.Fprintf(out, "%s [%s %s] %s\n", e.Time.Format("15:04:05.000000"), e.DB, e.Client, strings.Join(args, " "))
+		}
+		return false
+	})
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// Output modes selected with --raw and --json.
+const (
+	outputPretty = "pretty"
+	outputRaw    = "raw"
+	outputJSON   = "json"
+)
+
+// rawReplyCmds return human readable reports that redis-cli prints as
+// they are rather than as a quoted string.
+var rawReplyCmds = map[string]bool{
+	"INFO": true, "LOLWUT": true, "LATENCY DOCTOR": true, "MEMORY DOCTOR": true,
+	"CLIENT LIST": true, "CLIENT INFO": true, "CLUSTER NODES": true,
+}
+
+// outputMode picks the mode for a command's reply: reports are printed raw
+// unless JSON was asked for.
+func outputMode(mode string, args []string) string {
+	if mode != outputPretty || len(args) == 0 {
+		return mode
+	}
+	name := strings.ToUpper(args[0])
+	if rawReplyCmds[name] || (len(args) > 1 && rawReplyCmds[name+" "+strings.ToUpper(args[1])]) {
+		return outputRaw
+	}
+	return mode
+}
+
+// formatReply renders a reply for the terminal, ending in a newline.
+func formatReply(v resp.Value, mode string) string {
+	var b strings.Builder
+	switch mode {
+	case outputRaw:
+		writeRaw(&b, v)
+	case outputJSON:
+		data, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			data, _ = json.Marshal(map[string]string{"error": err.Error()})
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	default:
+		writePretty(&b, v, "")
+	}
+	return b.String()
+}
+
+// writePretty prints v the way redis-cli does on a terminal: typed scalars,
+// quoted strings and numbered aggregates whose nested elements line up
+// under their parent's label. prefix is the indentation of continuation
+// lines.
+func writePretty(b *strings.Builder, v resp.Value, prefix string) {
+	if v.IsNull() {
+		b.WriteString("(nil)\n")
+		return
+	}
+	switch v.Typ {
+	case "array", "set", "push":
+		if len(v.Array) == 0 {
+			b.WriteString("(empty array)\n")
+			return
+		}
+		mark := ')'
+		if v.Typ == "set" {
+			mark = '~'
+		}
+		width := len(strconv.Itoa(len(v.Array)))
+		for i, el := range v.Array {
+			label := fmt.Sprintf("%*d%c ", width, i+1, mark)
+			if i > 0 {
+				b.WriteString(prefix)
+			}
+			b.WriteString(label)
+			writePretty(b, el, prefix+strings.Repeat(" ", len(label)))
+		}
+	case "map":
+		if len(v.Map) == 0 {
+			b.WriteString("(empty hash)\n")
+			return
+		}
+		width := len(strconv.Itoa(len(v.Map)))
+		for i, p := range v.Map {
+			label := fmt.Sprintf("%*d# ", width, i+1)
+			if i > 0 {
+				b.WriteString(prefix)
+			}
+			b.WriteString(label)
+			key := prettyScalar(p.Key)
+			b.WriteString(key)
+			b.WriteString(" => ")
+			writePretty(b, p.Value, prefix+strings.Repeat(" ", len(label)+len(key)+4))
+		}
+	default:
+		b.WriteString(prettyScalar(v))
+		b.WriteByte('\n')
+	}
+}
+
+func prettyScalar(v resp.Value) string {
+	switch v.Typ {
+	case "string":
+		return v.Str
+	case "error":
+		return "(error) " + v.Str
+	case "integer":
+		return "(integer) " + strconv.FormatInt(v.Num, 10)
+	case "bulk":
+		return quote(v.Bulk)
+	case "double":
+		return "(double) " + strconv.FormatFloat(v.Double, 'g', -1, 64)
+	case "boolean":
+		if v.Bool {
+			return "(true)"
+		}
+		return "(false)"
+	case "bignum":
+		return "(big number) " + v.Str
+	case "verbatim":
+		return v.Bulk
+	case "null":
+		return "(nil)"
+	}
+	return fmt.Sprintf("(%s)", v.Typ)
+}
+
+// quote escapes s like redis-cli: printable ASCII as is and everything
+// else as a C escape or \xHH, byte by byte.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\a':
+			b.WriteString(`\a`)
+		case '\b':
+			b.WriteString(`\b`)
+		default:
+			if c < 0x20 || c > 0x7e {
+				fmt.Fprintf(&b, `\x%02x`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeRaw prints v for scripts: unquoted strings, bare numbers, an empty
+// line for nil and one line per aggregate element.
+func writeRaw(b *strings.Builder, v resp.Value) {
+	if v.IsNull() {
+		b.WriteByte('\n')
+		return
+	}
+	switch v.Typ {
+	case "array", "set", "push":
+		for _, el := range v.Array {
+			writeRaw(b, el)
+		}
+	case "map":
+		for _, p := range v.Map {
+			writeRaw(b, p.Key)
+			writeRaw(b, p.Value)
+		}
+	case "string", "error", "bignum":
+		b.WriteString(v.Str)
+		b.WriteByte('\n')
+	case "integer":
+		b.WriteString(strconv.FormatInt(v.Num, 10))
+		b.WriteByte('\n')
+	case "double":
+		b.WriteString(strconv.FormatFloat(v.Double, 'g', -1, 64))
+		b.WriteByte('\n')
+	case "boolean":
+		if v.Bool {
+			b.WriteString("1\n")
+		} else {
+			b.WriteString("0\n")
+		}
+	default:
+		b.WriteString(v.Bulk)
+		b.WriteByte('\n')
+	}
+}
+
+// jsonValue converts a reply to the value encoding/json should emit. Maps
+// become objects keyed by the key's text and errors become {"error": msg}.
+func jsonValue(v resp.Value) any {
+	if v.IsNull() {
+		return nil
+	}
+	switch v.Typ {
+	case "array", "set", "push":
+		out := make([]any, len(v.Array))
+		for i, el := range v.Array {
+			out[i] = jsonValue(el)
+		}
+		return out
+	case "map":
+		out := make(map[string]any, len(v.Map))
+		for _, p := range v.Map {
+			out[rawText(p.Key)] = jsonValue(p.Value)
+		}
+		return out
+	case "string":
+		return v.Str
+	case "error":
+		return map[string]string{"error": v.Str}
+	case "integer":
+		return v.Num
+	case "double":
+		if math.IsInf(v.Double, 0) || math.IsNaN(v.Double) {
+			return strconv.FormatFloat(v.Double, 'g', -1, 64)
+		}
+		return v.Double
+	case "boolean":
+		return v.Bool
+	case "bignum":
+		return json.Number(v.Str)
+	}
+	return v.Bulk
+}
+
+func rawText(v resp.Value) string {
+	var b strings.Builder
+	writeRaw(&b, v)
+	return strings.TrimSuffix(b.String(), "\n")
+}
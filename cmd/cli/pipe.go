@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// pipeStats is the outcome of a --pipe run.
+type pipeStats struct {
+	sent    int
+	replies int
+	errors  int
+}
+
+// runPipe streams the commands read from in to the server on a dedicated
+// connection without waiting for replies, counting replies and errors as
+// they come back. Input may be RESP arrays, as produced by a generator
+// script, or plain text with one command per line; the two can be mixed.
+// Error replies are printed to errOut as they arrive.
+func runPipe(ctx context.Context, pool *conn.Pool, in io.Reader, out, errOut io.Writer) (pipeStats, error) {
+	cn, err := pool.Dial(ctx)
+	if err != nil {
+		return pipeStats{}, err
+	}
+	defer cn.Close()
+	stop := context.AfterFunc(ctx, func() { cn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	// written carries one token per command handed to the writer; the
+	// reader reads exactly that many replies and finishes once it is
+	// closed, so no marker command is needed to spot the last reply.
+	written := make(chan struct{}, 4096)
+	writeErr := make(chan error, 1)
+	go func() {
+		defer close(written)
+		writeErr <- pipeCommands(in, cn, written)
+	}()
+
+	var st pipeStats
+	r := resp.NewReader(cn)
+	for range written {
+		st.sent++
+		v, err := r.ReadValue()
+		if err != nil {
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return st, fmt.Errorf("reading reply %d: %w", st.sent, err)
+		}
+		st.replies++
+		if v.Typ == "error" {
+			st.errors++
+			fmt.Fprintln(errOut, v.Str)
+		}
+	}
+	if err := <-writeErr; err != nil {
+		return st, err
+	}
+	fmt.Fprintln(out, "All data transferred. Last reply received from server.")
+	fmt.Fprintf(out, "errors: %d, replies: %d\n", st.errors, st.replies)
+	return st, nil
+}
+
+// pipeCommands copies commands from in to cn, re-encoding each one so a
+// malformed input stops the run before it reaches the server. The buffer
+// is flushed whenever input stalls, before blocking on a full written
+// queue and at the end, so the reader never waits on a reply to a command
+// that is still sitting in the buffer.
+func pipeCommands(in io.Reader, cn io.Writer, written chan<- struct{}) error {
+	br := bufio.NewReader(in)
+	r := resp.NewReader(br)
+	w := resp.NewWriter(cn)
+	var args [][]byte
+	for n := 1; ; n++ {
+		b, err := r.Peek()
+		if err == io.EOF {
+			return w.Flush()
+		}
+		if err != nil {
+			return err
+		}
+		if b == '*' {
+			if args, err = r.ReadCommand(args); err != nil {
+				return fmt.Errorf("input command %d: %w", n, err)
+			}
+			if len(args) == 0 {
+				continue
+			}
+			w.WriteArrayHeader(len(args))
+			for _, a := range args {
+				w.WriteBulkBytes(a)
+			}
+		} else {
+			// Read through br so a last line without a newline still counts.
+			line, err := br.ReadString('\n')
+			if err != nil && err != io.EOF {
+				return err
+			}
+			words, err := resp.SplitArgs(strings.TrimSpace(line))
+			if err != nil {
+				return fmt.Errorf("input command %d: %w", n, err)
+			}
+			if len(words) == 0 {
+				continue
+			}
+			w.WriteArrayHeader(len(words))
+			for _, word := range words {
+				w.WriteBulkString(word)
+			}
+		}
+
+		select {
+		case written <- struct{}{}:
+		default:
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			written <- struct{}{}
+		}
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
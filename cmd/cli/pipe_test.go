@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// fakeNode serves raw replies from handle, one per command, and records
+// every command it receives.
+func fakeNode(t *testing.T, handle func(args []string) string) (string, func() [][]string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var (
+		mu   sync.Mutex
+		seen [][]string
+	)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				var args [][]byte
+				for {
+					if args, err = r.ReadCommand(args); err != nil {
+						return
+					}
+					cmd := resp.CopyArgs(args)
+					mu.Lock()
+					seen = append(seen, cmd)
+					mu.Unlock()
+					if _, err := c.Write([]byte(handle(cmd))); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([][]string(nil), seen...)
+	}
+}
+
+func TestRunPipe(t *testing.T) {
+	addr, seen := fakeNode(t, func(args []string) string {
+		if strings.EqualFold(args[0], "BAD") {
+			return "-ERR unknown command 'BAD'\r\n"
+		}
+		return "+OK\r\n"
+	})
+	pool := conn.NewConnPoolWithOptions(addr, conn.Options{MaxActive: 1})
+	defer pool.Close()
+
+	// RESP arrays and plain lines mixed, with a blank line and a last line
+	// that has no newline.
+	in := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n" +
+		"SET b \"two words\"\n" +
+		"\n" +
+		"BAD\n" +
+		"*2\r\n$3\r\nGET\r\n$1\r\na\r\n" +
+		"SET c 3"
+	var out, errOut bytes.Buffer
+	st, err := runPipe(context.Background(), pool, strings.NewReader(in), &out, &errOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st != (pipeStats{sent: 5, replies: 5, errors: 1}) {
+		t.Fatalf("stats = %+v, want 5 sent, 5 replies, 1 error", st)
+	}
+	if !strings.Contains(out.String(), "errors: 1, replies: 5") {
+		t.Fatalf("summary missing from output: %q", out.String())
+	}
+	if !strings.Contains(errOut.String(), "unknown command 'BAD'") {
+		t.Fatalf("error reply not printed: %q", errOut.String())
+	}
+	if got := seen(); len(got) != 5 || got[1][2] != "two words" {
+		t.Fatalf("server saw %q", got)
+	}
+
+	if _, err := runPipe(context.Background(), pool, strings.NewReader("SET \"open\n"), &out, &errOut); err == nil {
+		t.Fatal("expected malformed input to stop the run")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/client"
+)
+
+// scanKeys walks the keyspace with SCAN, calling fn with each batch of
+// keys matching pattern. Keys added or removed during the walk may or may
+// not be seen, as with any SCAN.
+func scanKeys(ctx context.Context, c *client.Client, pattern string, count int, fn func([]string) error) error {
+	cursor := "0"
+	for {
+		cmd := c.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count)
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+		v := cmd.Val()
+		if len(v.Array) != 2 {
+			return fmt.Errorf("unexpected SCAN reply %s", rawText(v))
+		}
+		cursor = rawText(v.Array[0])
+		keys := make([]string, 0, len(v.Array[1].Array))
+		for _, k := range v.Array[1].Array {
+			keys = append(keys, rawText(k))
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+// runScan prints every key matching pattern, one per line.
+func runScan(ctx context.Context, c *client.Client, pattern string, count int, out io.Writer) error {
+	return scanKeys(ctx, c, pattern, count, func(keys []string) error {
+		for _, k := range keys {
+			fmt.Fprintln(out, k)
+		}
+		return nil
+	})
+}
+
+// sizeCmds is how --bigkeys measures a key of each type, and the unit it
+// reports the size in.
+var sizeCmds = map[string]struct{ cmd, unit string }{
+	"string": {"STRLEN", "bytes"},
+	"list":   {"LLEN", "items"},
+	"set":    {"SCARD", "members"},
+	"zset":   {"ZCARD", "members"},
+	"hash":   {"HLEN", "fields"},
+	"stream": {"XLEN", "entries"},
+}
+
+// typeStats accumulates what --bigkeys and --memkeys report per type.
+type typeStats struct {
+	keys    int
+	total   int64
+	biggest string
+	size    int64
+}
+
+// runBigKeys samples every key matching pattern and reports the biggest
+// key of each type with totals per type. With mem set sizes come from
+// MEMORY USAGE in bytes; otherwise they are the type's natural length:
+// bytes for strings and element counts for aggregates.
+func runBigKeys(ctx context.Context, c *client.Client, pattern string, count int, mem bool, out io.Writer) error {
+	// Without DBSIZE the walk still works, it just cannot show progress.
+	var dbsize int64
+	c.Do(ctx, "DBSIZE").Scan(&dbsize)
+	what := "biggest keys as well as average sizes per key type"
+	if mem {
+		what = "keys using the most memory per key type"
+	}
+	fmt.Fprintf(out, "\n# Scanning the entire keyspace to find %s.\n\n", what)
+
+	stats := map[string]*typeStats{}
+	var sampled int
+	var keyBytes int64
+	err := scanKeys(ctx, c, pattern, count, func(keys []string) error {
+		p := c.Pipeline()
+		types := make([]*client.Cmd, len(keys))
+		for i, k := range keys {
+			types[i] = p.Do("TYPE", k)
+		}
+		if _, err := p.Exec(ctx); err != nil {
+			return err
+		}
+
+		sizes := make([]*client.Cmd, len(keys))
+		for i, k := range keys {
+			typ := rawText(types[i].Val())
+			switch {
+			case mem:
+				sizes[i] = p.Do("MEMORY", "USAGE", k)
+			case sizeCmds[typ].cmd != "":
+				sizes[i] = p.Do(sizeCmds[typ].cmd, k)
+			}
+		}
+		// Keys that vanished or have an unknown type have no size; their
+		// error replies are skipped below.
+		p.Exec(ctx)
+
+		for i, k := range keys {
+			typ := rawText(types[i].Val())
+			if typ == "none" || sizes[i] == nil || sizes[i].Err() != nil || sizes[i].Val().IsNull() {
+				continue
+			}
+			size := sizes[i].Val().Num
+			sampled++
+			keyBytes += int64(len(k))
+			st := stats[typ]
+			if st == nil {
+				st = &typeStats{size: -1}
+				stats[typ] = st
+			}
+			st.keys++
+			st.total += size
+			if size > st.size {
+				st.biggest, st.size = k, size
+				fmt.Fprintf(out, "[%s] Biggest %-6s found so far %s with %d %s\n",
+					progress(sampled, dbsize), typ, quote(k), size, unitOf(typ, mem))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n-------- summary -------\n\n")
+	fmt.Fprintf(out, "Sampled %d keys in the keyspace!\n", sampled)
+	avg := 0.0
+	if sampled > 0 {
+		avg = float64(keyBytes) / float64(sampled)
+	}
+	fmt.Fprintf(out, "Total key length in bytes is %d (avg len %.2f)\n\n", keyBytes, avg)
+
+	types := make([]string, 0, len(stats))
+	for typ := range stats {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		st := stats[typ]
+		fmt.Fprintf(out, "Biggest %6s found %s has %d %s\n", typ, quote(st.biggest), st.size, unitOf(typ, mem))
+	}
+	if len(types) > 0 {
+		fmt.Fprintln(out)
+	}
+	for _, typ := range types {
+		st := stats[typ]
+		fmt.Fprintf(out, "%d %ss with %d %s (%05.2f%% of keys, avg size %.2f)\n",
+			st.keys, typ, st.total, unitOf(typ, mem),
+			100*float64(st.keys)/float64(sampled), float64(st.total)/float64(st.keys))
+	}
+	return nil
+}
+
+func unitOf(typ string, mem bool) string {
+	if mem {
+		return "bytes"
+	}
+	return sizeCmds[typ].unit
+}
+
+// progress is how far through the keyspace the walk is, as "12.34%", or
+// "??.??%" when DBSIZE was not available.
+func progress(sampled int, dbsize int64) string {
+	if dbsize <= 0 {
+		return "??.??%"
+	}
+	pct := min(100*float64(sampled)/float64(dbsize), 100)
+	return fmt.Sprintf("%05.2f%%", pct)
+}
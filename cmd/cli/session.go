@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// reconnectAttempts bounds how long a command waits for the server to come
+// back before the prompt gives up on it; the next command tries again.
+const reconnectAttempts = 6
+
+// maxRedirects bounds how many MOVED and ASK replies one command follows.
+const maxRedirects = 16
+
+// session is the prompt's connection. Commands run on one connection so
+// that SELECT and AUTH typed at the prompt stick, and both are replayed
+// when the connection is lost and redialed. In cluster mode it follows
+// -MOVED by moving to the named node and -ASK by asking it once.
+type session struct {
+	pool *conn.Pool
+	log  io.Writer
+
+	cluster   bool
+	opts      conn.Options          // for pools to other nodes
+	nodes     map[string]*conn.Pool // by address, excluding the first pool
+	start     *conn.Pool
+	startAddr string
+
+	cn net.Conn
+	r  *resp.Reader
+	w  *resp.Writer
+
+	// auth is the last AUTH that succeeded at the prompt and db the
+	// database last selected; the pool's handshake covers the flags.
+	auth      []string
+	db        int
+	initialDB int
+}
+
+func newSession(pool *conn.Pool, db int, log io.Writer) *session {
+	return &session{pool: pool, start: pool, log: log, db: db, initialDB: db}
+}
+
+// followRedirects turns on cluster mode; pools to other nodes use opts.
+func (s *session) followRedirects(addr string, opts conn.Options) {
+	s.cluster = true
+	opts.MinIdle = 0
+	s.opts = opts
+	s.nodes = map[string]*conn.Pool{}
+	s.startAddr = addr
+}
+
+// Do runs one command. If the connection turns out to be gone the
+// session reconnects, restores its state and sends the command again, once.
+// Error replies are returned as values for the caller to print.
+func (s *session) Do(ctx context.Context, args []string) (resp.Value, error) {
+	v, err := s.do(ctx, args)
+	for i := 0; s.cluster && err == nil && i < maxRedirects; i++ {
+		var re *resp.RedirectError
+		if !errors.As(v.Err(), &re) {
+			break
+		}
+		fmt.Fprintf(s.log, "-> Redirected to slot [%d] located at %s\n", re.Slot, re.Addr)
+		if re.Kind == "ASK" {
+			v, err = s.ask(ctx, re.Addr, args)
+			continue
+		}
+		s.drop()
+		s.pool = s.node(re.Addr)
+		v, err = s.do(ctx, args)
+	}
+	return v, err
+}
+
+func (s *session) do(ctx context.Context, args []string) (resp.Value, error) {
+	if s.cn == nil {
+		if err := s.reconnect(ctx); err != nil {
+			return resp.Value{}, err
+		}
+	}
+	v, err := s.roundTrip(args)
+	if err != nil {
+		s.drop()
+		fmt.Fprintf(s.log, "Connection lost (%s), reconnecting...\n", err)
+		if err := s.reconnect(ctx); err != nil {
+			return resp.Value{}, err
+		}
+		if v, err = s.roundTrip(args); err != nil {
+			s.drop()
+			return resp.Value{}, err
+		}
+	}
+	if v.Typ != "error" {
+		s.track(args)
+	}
+	return v, nil
+}
+
+// track records the state a successful command leaves on the connection.
+func (s *session) track(args []string) {
+	switch strings.ToUpper(args[0]) {
+	case "SELECT":
+		if len(args) == 2 {
+			if n, err := strconv.Atoi(args[1]); err == nil {
+				s.db = n
+			}
+		}
+	case "AUTH":
+		s.auth = append([]string(nil), args...)
+	}
+}
+
+// node returns the pool for a cluster node, opening it on first use.
+func (s *session) node(addr string) *conn.Pool {
+	if addr == s.startAddr {
+		return s.start
+	}
+	p, ok := s.nodes[addr]
+	if !ok {
+		p = conn.NewConnPoolWithOptions(addr, s.opts)
+		s.nodes[addr] = p
+	}
+	return p
+}
+
+// ask runs a command once on the node an -ASK reply named, preceded by
+// ASKING, leaving the session on its current node.
+func (s *session) ask(ctx context.Context, addr string, args []string) (resp.Value, error) {
+	cn, err := s.node(addr).Dial(ctx)
+	if err != nil {
+		return resp.Value{}, err
+	}
+	defer cn.Close()
+	w, r := resp.NewWriter(cn), resp.NewReader(cn)
+	for _, cmd := range [][]string{{"ASKING"}, args} {
+		w.WriteArrayHeader(len(cmd))
+		for _, a := range cmd {
+			w.WriteBulkString(a)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return resp.Value{}, err
+	}
+	if v, err := r.ReadValue(); err != nil {
+		return v, err
+	} else if v.Typ == "error" {
+		return v, nil
+	}
+	return r.ReadValue()
+}
+
+func (s *session) roundTrip(args []string) (resp.Value, error) {
+	s.w.WriteArrayHeader(len(args))
+	for _, a := range args {
+		s.w.WriteBulkString(a)
+	}
+	if err := s.w.Flush(); err != nil {
+		return resp.Value{}, err
+	}
+	return s.r.ReadValue()
+}
+
+// reconnect dials with growing pauses, waiting out an open circuit, and
+// restores the session's AUTH and SELECT on the new connection.
+func (s *session) reconnect(ctx context.Context) error {
+	delay := 100 * time.Millisecond
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		if err = s.connect(ctx); err == nil {
+			return nil
+		}
+		if attempt == reconnectAttempts {
+			break
+		}
+		wait := delay
+		var ce *conn.CircuitError
+		if errors.As(err, &ce) {
+			wait = max(wait, time.Until(ce.Until))
+		}
+		delay = min(2*delay, 2*time.Second)
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return fmt.Errorf("could not reconnect: %w", err)
+}
+
+func (s *session) connect(ctx context.Context) error {
+	cn, err := s.pool.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	s.cn, s.r, s.w = cn, resp.NewReader(cn), resp.NewWriter(cn)
+
+	var restore [][]string
+	if s.auth != nil {
+		restore = append(restore, s.auth)
+	}
+	if s.db != s.initialDB {
+		restore = append(restore, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	for _, args := range restore {
+		v, err := s.roundTrip(args)
+		if err == nil {
+			err = v.Err()
+		}
+		if err != nil {
+			s.drop()
+			return fmt.Errorf("restoring %s: %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+// drop closes the connection; the next command dials again.
+func (s *session) drop() {
+	if s.cn != nil {
+		s.cn.Close()
+		s.cn = nil
+	}
+}
+
+// Close drops the connection and the pools opened for other cluster
+// nodes. The pool the session started with belongs to the caller.
+func (s *session) Close() {
+	s.drop()
+	for _, p := range s.nodes {
+		p.Close()
+	}
+}
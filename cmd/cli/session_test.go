@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+)
+
+func TestSessionRedirects(t *testing.T) {
+	target, targetSeen := fakeNode(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "ASKING":
+			return "+OK\r\n"
+		case "GET":
+			return "$6\r\nmoved!\r\n"
+		}
+		return "+OK\r\n"
+	})
+	start, startSeen := fakeNode(t, func(args []string) string {
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			return fmt.Sprintf("-MOVED 3999 %s\r\n", target)
+		case "SET":
+			return fmt.Sprintf("-ASK 12182 %s\r\n", target)
+		}
+		return "+PONG\r\n"
+	})
+
+	opts := conn.Options{MaxActive: 1}
+	pool := conn.NewConnPoolWithOptions(start, opts)
+	defer pool.Close()
+	var log bytes.Buffer
+	s := newSession(pool, 0, &log)
+	s.followRedirects(start, opts)
+	defer s.Close()
+	ctx := context.Background()
+
+	// -ASK runs the command once on the named node, after ASKING, and the
+	// session stays where it was.
+	v, err := s.Do(ctx, []string{"SET", "k", "v"})
+	if err != nil || v.Str != "OK" {
+		t.Fatalf("SET after -ASK = %+v, %v", v, err)
+	}
+	if !strings.Contains(log.String(), "-> Redirected to slot [12182] located at "+target) {
+		t.Fatalf("ASK redirect not logged: %q", log.String())
+	}
+	if got := targetSeen(); !reflect.DeepEqual(got, [][]string{{"ASKING"}, {"SET", "k", "v"}}) {
+		t.Fatalf("target saw %q, want ASKING then SET", got)
+	}
+	if v, err := s.Do(ctx, []string{"PING"}); err != nil || v.Str != "PONG" {
+		t.Fatalf("PING after -ASK went elsewhere: %+v, %v", v, err)
+	}
+
+	// -MOVED moves the session to the named node for good.
+	v, err = s.Do(ctx, []string{"GET", "k"})
+	if err != nil || v.Bulk != "moved!" {
+		t.Fatalf("GET after -MOVED = %+v, %v", v, err)
+	}
+	if !strings.Contains(log.String(), "-> Redirected to slot [3999] located at "+target) {
+		t.Fatalf("MOVED redirect not logged: %q", log.String())
+	}
+	before := len(startSeen())
+	if v, err := s.Do(ctx, []string{"PING"}); err != nil || v.Str != "OK" {
+		t.Fatalf("PING after -MOVED = %+v, %v", v, err)
+	}
+	if len(startSeen()) != before {
+		t.Fatal("the session should have stayed on the node -MOVED named")
+	}
+
+	// Without cluster mode a redirect is just an error reply.
+	plain := newSession(pool, 0, &log)
+	defer plain.Close()
+	if v, err := plain.Do(ctx, []string{"GET", "k"}); err != nil || v.Typ != "error" || !strings.HasPrefix(v.Str, "MOVED 3999") {
+		t.Fatalf("GET without cluster mode = %+v, %v", v, err)
+	}
+}
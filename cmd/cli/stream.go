@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// runStream sends a command that turns its connection into a feed, such
+// as SUBSCRIBE or MONITOR, on a connection of its own and hands every
+// frame to handle until handle reports the feed is over. Ctrl-C sends stop,
+// after which handle sees stopping set and the stream also ends if the
+// server goes quiet for a second; with no stop command Ctrl-C ends the
+// stream at once. Either way the connection is closed on return.
+func runStream(ctx context.Context, pool *conn.Pool, args, stop []string, handle func(v resp.Value, stopping bool) bool) error {
+	cn, err := pool.Dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+
+	w := resp.NewWriter(cn)
+	send := func(words []string) error {
+		w.WriteArrayHeader(len(words))
+		for _, word := range words {
+			w.WriteBulkString(word)
+		}
+		return w.Flush()
+	}
+	if err := send(args); err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	frames := make(chan resp.Value)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		r := resp.NewReader(cn)
+		for {
+			v, err := r.ReadValue()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case frames <- v:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	stopping := false
+	for {
+		select {
+		case v := <-frames:
+			if handle(v, stopping) {
+				return nil
+			}
+		case <-interrupt:
+			if stopping || len(stop) == 0 {
+				return nil
+			}
+			stopping = true
+			// A server that never confirms must not hold the prompt.
+			cn.SetReadDeadline(time.Now().Add(time.Second))
+			if err := send(stop); err != nil {
+				return err
+			}
+		case err := <-readErr:
+			if stopping {
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
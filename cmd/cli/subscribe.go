@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// subscribeCmds switch the prompt into streaming mode, each with the
+// command that undoes it.
+var subscribeCmds = map[string]string{
+	"SUBSCRIBE":  "UNSUBSCRIBE",
+	"PSUBSCRIBE": "PUNSUBSCRIBE",
+	"SSUBSCRIBE": "SUNSUBSCRIBE",
+}
+
+// subscriptionKinds are the frames whose last element is the number of
+// subscriptions left on the connection.
+var subscriptionKinds = map[string]bool{
+	"subscribe": true, "psubscribe": true, "ssubscribe": true,
+	"unsubscribe": true, "punsubscribe": true, "sunsubscribe": true,
+}
+
+// runSubscribe prints every frame pushed to a SUBSCRIBE-family command
+// until Ctrl-C, which unsubscribes from everything and returns to the
+// prompt once the server confirms.
+func runSubscribe(ctx context.Context, pool *conn.Pool, args []string, mode string, out io.Writer) error {
+	fmt.Fprintln(out, "Reading messages... (press Ctrl-C to quit)")
+	stop := []string{subscribeCmds[strings.ToUpper(args[0])]}
+	return runStream(ctx, pool, args, stop, func(v resp.Value, stopping bool) bool {
+		fmt.Fprint(out, formatReply(v, mode))
+		return v.Typ == "error" || (stopping && subscriptionsLeft(v) == 0)
+	})
+}
+
+// subscriptionsLeft is the count carried by a (un)subscribe confirmation,
+// or -1 for any other frame.
+func subscriptionsLeft(v resp.Value) int64 {
+	if len(v.Array) != 3 || !subscriptionKinds[strings.ToLower(rawText(v.Array[0]))] {
+		return -1
+	}
+	return v.Array[2].Num
+}
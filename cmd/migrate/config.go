@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+)
+
+// Config holds the migration settings.
+type Config struct {
+	From     endpoint
+	To       endpoint
+	Pattern  string
+	Count    int
+	Workers  int
+	Restore  bool
+	Progress bool
+}
+
+// endpoint is one side of the migration, given as a redis:// URI.
+type endpoint struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	TLS      bool
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Pattern:  "*",
+		Count:    100,
+		Workers:  8,
+		Progress: true,
+	}
+}
+
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	from := fs.String("from", "redis://127.0.0.1:6379", "source server URI: redis://[user:password@]host[:port][/db]")
+	to := fs.String("to", "redis://127.0.0.1:8090", "target server URI")
+	fs.StringVar(&cfg.Pattern, "pattern", cfg.Pattern, "only migrate keys matching this pattern")
+	fs.IntVar(&cfg.Count, "count", cfg.Count, "SCAN COUNT hint")
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "keys migrated in parallel")
+	fs.BoolVar(&cfg.Restore, "restore", false, "copy keys with DUMP and RESTORE instead of replaying type-specific commands; the target must accept the source's DUMP format")
+	fs.BoolVar(&cfg.Progress, "progress", cfg.Progress, "report progress every second")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if cfg.From, err = parseEndpoint(*from); err != nil {
+		return nil, fmt.Errorf("-from: %w", err)
+	}
+	if cfg.To, err = parseEndpoint(*to); err != nil {
+		return nil, fmt.Errorf("-to: %w", err)
+	}
+	if cfg.Count < 1 || cfg.Workers < 1 {
+		return nil, fmt.Errorf("-count and -workers must be positive")
+	}
+	return cfg, nil
+}
+
+func parseEndpoint(s string) (endpoint, error) {
+	var e endpoint
+	u, err := url.Parse(s)
+	if err != nil {
+		return e, fmt.Errorf("invalid URI: %w", err)
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		e.TLS = true
+	default:
+		return e, fmt.Errorf("invalid URI scheme %q, want redis:// or rediss://", u.Scheme)
+	}
+	host, port := u.Hostname(), u.Port()
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if port == "" {
+		port = "6379"
+	}
+	e.Addr = net.JoinHostPort(host, port)
+	if u.User != nil {
+		if pw, ok := u.User.Password(); ok {
+			e.Username, e.Password = u.User.Username(), pw
+		} else {
+			e.Password = u.User.Username()
+		}
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if e.DB, err = strconv.Atoi(path); err != nil {
+			return e, fmt.Errorf("invalid database %q in URI", path)
+		}
+	}
+	return e, nil
+}
+
+func (e endpoint) poolOptions(size int) conn.Options {
+	opts := conn.Options{
+		MaxActive: size,
+		Username:  e.Username,
+		Password:  e.Password,
+		DB:        e.DB,
+	}
+	if e.TLS {
+		opts.TLSConfig = &tls.Config{}
+	}
+	return opts
+}
@@ -0,0 +1,120 @@
+// Command migrate copies the keys of a Redis server, or any server that
+// speaks the protocol, into this one, keeping their TTLs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/client"
+)
+
+func main() {
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		log.Fatalf("failed to load config: %s", err.Error())
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer cancel()
+
+	src := client.New(cfg.From.Addr, client.Options{Pool: cfg.From.poolOptions(cfg.Workers + 1)})
+	defer src.Close()
+	dst := client.New(cfg.To.Addr, client.Options{Pool: cfg.To.poolOptions(cfg.Workers)})
+	defer dst.Close()
+	if err := src.Ping(ctx); err != nil {
+		log.Fatalf("source %s: %s", cfg.From.Addr, err.Error())
+	}
+	if err := dst.Ping(ctx); err != nil {
+		log.Fatalf("target %s: %s", cfg.To.Addr, err.Error())
+	}
+
+	m := &migrator{src: src, dst: dst, restore: cfg.Restore}
+	keys := make(chan string, cfg.Count)
+	var wg sync.WaitGroup
+	for range cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range keys {
+				if err := m.migrate(ctx, key); err != nil {
+					m.failed.Add(1)
+					log.Printf("key %q: %s", key, err.Error())
+				}
+			}
+		}()
+	}
+
+	start := time.Now()
+	stopProgress := make(chan struct{})
+	if cfg.Progress {
+		go func() {
+			t := time.NewTicker(time.Second)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					report(m, start)
+				case <-stopProgress:
+					return
+				}
+			}
+		}()
+	}
+
+	scanErr := scan(ctx, src, cfg.Pattern, cfg.Count, keys)
+	close(keys)
+	wg.Wait()
+	close(stopProgress)
+	report(m, start)
+	if scanErr != nil {
+		log.Fatalf("scan stopped: %s", scanErr.Error())
+	}
+	if m.failed.Load() > 0 {
+		os.Exit(1)
+	}
+}
+
+// scan walks the source keyspace with SCAN and queues every key.
+func scan(ctx context.Context, c *client.Client, pattern string, count int, keys chan<- string) error {
+	cursor := "0"
+	for {
+		cmd := c.Do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", count)
+		if err := cmd.Err(); err != nil {
+			return err
+		}
+		v := cmd.Val()
+		if len(v.Array) != 2 {
+			return fmt.Errorf("unexpected SCAN reply")
+		}
+		cursor = text(v.Array[0])
+		for _, k := range v.Array[1].Array {
+			select {
+			case keys <- text(k):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+	}
+}
+
+func report(m *migrator, start time.Time) {
+	done := m.migrated.Load()
+	elapsed := time.Since(start)
+	fmt.Printf("migrated %d keys, skipped %d, failed %d in %s (%.0f keys/s)\n",
+		done, m.skipped.Load(), m.failed.Load(), elapsed.Round(time.Millisecond), float64(done)/elapsed.Seconds())
+}
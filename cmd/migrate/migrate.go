@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/client"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// chunk bounds how many elements go into one write command, so huge
+// aggregates do not turn into a single huge request.
+const chunk = 512
+
+// migrator copies keys from src to dst. Each key is read at one point in
+// time and written in one pipeline, but writes to the source while the
+// migration runs are not tracked.
+type migrator struct {
+	src, dst *client.Client
+	restore  bool
+
+	migrated atomic.Int64
+	skipped  atomic.Int64 // gone before they could be read
+	failed   atomic.Int64
+}
+
+func (m *migrator) migrate(ctx context.Context, key string) error {
+	if m.restore {
+		return m.copyDump(ctx, key)
+	}
+	return m.replay(ctx, key)
+}
+
+// copyDump moves a key with DUMP and RESTORE ... REPLACE, keeping its TTL.
+func (m *migrator) copyDump(ctx context.Context, key string) error {
+	p := m.src.Pipeline()
+	dump := p.Do("DUMP", key)
+	pttl := p.Do("PTTL", key)
+	if _, err := p.Exec(ctx); err != nil {
+		return err
+	}
+	if dump.Val().IsNull() {
+		m.skipped.Add(1)
+		return nil
+	}
+	ttl := max(pttl.Val().Num, 0)
+	if err := m.dst.Do(ctx, "RESTORE", key, ttl, []byte(dump.Val().Bulk), "REPLACE").Err(); err != nil {
+		return err
+	}
+	m.migrated.Add(1)
+	return nil
+}
+
+// replay reads a key with the read command of its type and writes it back
+// with the matching write commands, then restores its TTL.
+func (m *migrator) replay(ctx context.Context, key string) error {
+	p := m.src.Pipeline()
+	typ := p.Do("TYPE", key)
+	pttl := p.Do("PTTL", key)
+	if _, err := p.Exec(ctx); err != nil {
+		return err
+	}
+
+	var read []any
+	switch kind := text(typ.Val()); kind {
+	case "none":
+		m.skipped.Add(1)
+		return nil
+	case "string":
+		read = []any{"GET", key}
+	case "list":
+		read = []any{"LRANGE", key, 0, -1}
+	case "hash":
+		read = []any{"HGETALL", key}
+	case "set":
+		read = []any{"SMEMBERS", key}
+	case "zset":
+		read = []any{"ZRANGE", key, 0, -1, "WITHSCORES"}
+	default:
+		return fmt.Errorf("type %s cannot be replayed, use -restore", kind)
+	}
+	val := m.src.Do(ctx, read...)
+	if err := val.Err(); err != nil {
+		return err
+	}
+	if val.Val().IsNull() {
+		m.skipped.Add(1)
+		return nil
+	}
+
+	w := m.dst.Pipeline()
+	ttl := pttl.Val().Num
+	if read[0] == "GET" {
+		if ttl > 0 {
+			w.Do("SET", key, val.Val().Bulk, "PX", ttl)
+		} else {
+			w.Do("SET", key, val.Val().Bulk)
+		}
+	} else {
+		items := flatten(val.Val())
+		if len(items) == 0 {
+			m.skipped.Add(1)
+			return nil
+		}
+		w.Do("DEL", key)
+		write, step := "RPUSH", 1
+		switch read[0] {
+		case "HGETALL":
+			write, step = "HSET", 2
+		case "SMEMBERS":
+			write = "SADD"
+		case "ZRANGE":
+			write, step = "ZADD", 2
+			// WITHSCORES gives member, score; ZADD wants score, member.
+			for i := 0; i+1 < len(items); i += 2 {
+				items[i], items[i+1] = items[i+1], items[i]
+			}
+		}
+		for start := 0; start < len(items); start += chunk * step {
+			end := min(start+chunk*step, len(items))
+			args := append([]any{write, key}, items[start:end]...)
+			w.Do(args...)
+		}
+		if ttl > 0 {
+			w.Do("PEXPIRE", key, ttl)
+		}
+	}
+	if _, err := w.Exec(ctx); err != nil {
+		return err
+	}
+	m.migrated.Add(1)
+	return nil
+}
+
+// flatten lists the scalars of a reply in order, so RESP3 maps and score
+// pairs come out the same as their flat RESP2 form.
+func flatten(v resp.Value) []any {
+	var out []any
+	switch v.Typ {
+	case "array", "set", "push":
+		for _, el := range v.Array {
+			out = append(out, flatten(el)...)
+		}
+	case "map":
+		for _, p := range v.Map {
+			out = append(out, flatten(p.Key)...)
+			out = append(out, flatten(p.Value)...)
+		}
+	default:
+		out = append(out, text(v))
+	}
+	return out
+}
+
+func text(v resp.Value) string {
+	switch v.Typ {
+	case "string", "error", "bignum":
+		return v.Str
+	case "integer":
+		return strconv.FormatInt(v.Num, 10)
+	case "double":
+		return strconv.FormatFloat(v.Double, 'g', -1, 64)
+	}
+	return v.Bulk
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/client"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// fakeKey is one key of a fakeStore. Hashes and sorted sets keep their
+// pairs flat, in the order the read commands return them.
+type fakeKey struct {
+	typ   string
+	items []string
+	pttl  int64
+}
+
+// fakeStore is a server that knows just the commands replay reads and
+// writes, and records the writes.
+type fakeStore struct {
+	mu     sync.Mutex
+	keys   map[string]*fakeKey
+	writes [][]string
+}
+
+func newFakeStore(t *testing.T, keys map[string]*fakeKey) (*fakeStore, *client.Client) {
+	t.Helper()
+	s := &fakeStore{keys: keys}
+	if s.keys == nil {
+		s.keys = make(map[string]*fakeKey)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				w := resp.NewWriter(c)
+				for {
+					raw, err := r.ReadCommand(nil)
+					if err != nil {
+						return
+					}
+					w.WriteValue(s.handle(resp.CopyArgs(raw)))
+					if r.Buffered() == 0 {
+						if err := w.Flush(); err != nil {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}()
+	c := client.New(ln.Addr().String(), client.Options{Pool: conn.Options{MaxActive: 2}})
+	t.Cleanup(func() { c.Close() })
+	return s, c
+}
+
+// key returns a copy of name, or nil if it does not exist.
+func (s *fakeStore) key(name string) *fakeKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[name]
+	if !ok {
+		return nil
+	}
+	c := *k
+	return &c
+}
+
+func bulks(items []string) resp.Value {
+	v := resp.Value{Typ: "array", Array: []resp.Value{}}
+	for _, it := range items {
+		v.Array = append(v.Array, resp.Value{Typ: "bulk", Bulk: it})
+	}
+	return v
+}
+
+func (s *fakeStore) handle(args []string) resp.Value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ok := resp.Value{Typ: "string", Str: "OK"}
+	cmd, key := strings.ToUpper(args[0]), args[1]
+	k := s.keys[key]
+	switch cmd {
+	case "TYPE":
+		if k == nil {
+			return resp.Value{Typ: "string", Str: "none"}
+		}
+		return resp.Value{Typ: "string", Str: k.typ}
+	case "PTTL":
+		switch {
+		case k == nil:
+			return resp.Value{Typ: "integer", Num: -2}
+		case k.pttl == 0:
+			return resp.Value{Typ: "integer", Num: -1}
+		}
+		return resp.Value{Typ: "integer", Num: k.pttl}
+	case "GET":
+		if k == nil {
+			return resp.Value{Typ: "null"}
+		}
+		return resp.Value{Typ: "bulk", Bulk: k.items[0]}
+	case "LRANGE", "HGETALL", "SMEMBERS", "ZRANGE":
+		if k == nil {
+			return bulks(nil)
+		}
+		return bulks(k.items)
+	}
+
+	s.writes = append(s.writes, args)
+	switch cmd {
+	case "SET":
+		k = &fakeKey{typ: "string", items: []string{args[2]}}
+		if len(args) == 5 {
+			k.pttl, _ = strconv.ParseInt(args[4], 10, 64)
+		}
+		s.keys[key] = k
+	case "DEL":
+		delete(s.keys, key)
+	case "RPUSH", "SADD", "HSET", "ZADD":
+		typ := map[string]string{"RPUSH": "list", "SADD": "set", "HSET": "hash", "ZADD": "zset"}[cmd]
+		if k == nil {
+			k = &fakeKey{typ: typ}
+			s.keys[key] = k
+		}
+		items := args[2:]
+		if cmd == "ZADD" {
+			// Store members before scores, as ZRANGE WITHSCORES lists them.
+			items = nil
+			for i := 2; i+1 < len(args); i += 2 {
+				items = append(items, args[i+1], args[i])
+			}
+		}
+		k.items = append(k.items, items...)
+	case "PEXPIRE":
+		k.pttl, _ = strconv.ParseInt(args[2], 10, 64)
+	default:
+		return resp.Value{Typ: "error", Str: "ERR unknown command '" + args[0] + "'"}
+	}
+	return ok
+}
+
+func seq(prefix string, n int, pairs bool) []string {
+	var out []string
+	for i := range n {
+		out = append(out, prefix+strconv.Itoa(i))
+		if pairs {
+			out = append(out, strconv.Itoa(i)+".5")
+		}
+	}
+	return out
+}
+
+func TestReplay(t *testing.T) {
+	keys := map[string]*fakeKey{
+		"str":       {typ: "string", items: []string{"hello"}, pttl: 5000},
+		"plain":     {typ: "string", items: []string{"no ttl"}},
+		"list":      {typ: "list", items: seq("e", 2*chunk+3, false), pttl: 7000},
+		"hash":      {typ: "hash", items: seq("f", chunk+1, true)},
+		"set":       {typ: "set", items: []string{"a", "b", "c"}, pttl: 9000},
+		"zset":      {typ: "zset", items: seq("m", chunk+2, true), pttl: 1234},
+		"emptylist": {typ: "list"},
+		"stream":    {typ: "stream"},
+	}
+	want := make(map[string]fakeKey)
+	for name, k := range keys {
+		want[name] = *k
+	}
+	_, src := newFakeStore(t, keys)
+	dst, dstClient := newFakeStore(t, nil)
+	m := &migrator{src: src, dst: dstClient}
+	ctx := context.Background()
+
+	for _, name := range []string{"str", "plain", "list", "hash", "set", "zset"} {
+		if err := m.replay(ctx, name); err != nil {
+			t.Fatalf("replay %s: %v", name, err)
+		}
+		if got := *dst.key(name); !reflect.DeepEqual(got, want[name]) {
+			t.Errorf("%s copied as %+v, want %+v", name, got, want[name])
+		}
+	}
+	if err := m.replay(ctx, "stream"); err == nil || !strings.Contains(err.Error(), "-restore") {
+		t.Errorf("replay of a stream = %v, want a hint to use -restore", err)
+	}
+	for _, name := range []string{"missing", "emptylist"} {
+		if err := m.replay(ctx, name); err != nil {
+			t.Errorf("replay %s: %v", name, err)
+		}
+		if dst.key(name) != nil {
+			t.Errorf("%s should not have been written", name)
+		}
+	}
+	if m.migrated.Load() != 6 || m.skipped.Load() != 2 {
+		t.Errorf("migrated %d, skipped %d; want 6 and 2", m.migrated.Load(), m.skipped.Load())
+	}
+
+	// Aggregates are written in commands of at most chunk elements.
+	dst.mu.Lock()
+	writes := dst.writes
+	dst.mu.Unlock()
+	var lens []int
+	for _, w := range writes {
+		switch w[0] {
+		case "RPUSH", "SADD":
+			lens = append(lens, len(w)-2)
+		case "HSET", "ZADD":
+			lens = append(lens, (len(w)-2)/2)
+		}
+	}
+	if want := []int{chunk, chunk, 3, chunk, 1, 3, chunk, 2}; !reflect.DeepEqual(lens, want) {
+		t.Errorf("write sizes = %v, want %v", lens, want)
+	}
+
+	// An existing key on the target is replaced, not appended to.
+	dst.handle([]string{"SADD", "set", "stale"})
+	if err := m.replay(ctx, "set"); err != nil {
+		t.Fatal(err)
+	}
+	if got := dst.key("set").items; !reflect.DeepEqual(got, want["set"].items) {
+		t.Errorf("set after a second copy = %q, want %q", got, want["set"].items)
+	}
+}
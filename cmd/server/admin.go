@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// listening is set while the RESP listener accepts connections, and
+	// loading while the keyspace is being loaded at startup.
+	listening atomic.Bool
+	loading   atomic.Bool
+)
+
+// healthStatus is the body of both probe endpoints.
+type healthStatus struct {
+	Status    string `json:"status"`
+	Listening bool   `json:"listening"`
+	Loading   bool   `json:"loading"`
+	// Role is always master: there is no replication link to report on.
+	Role string `json:"role"`
+}
+
+func currentHealth() healthStatus {
+	return healthStatus{
+		Status:    "ok",
+		Listening: listening.Load(),
+		Loading:   loading.Load(),
+		Role:      "master",
+	}
+}
+
+// handleHealthz reports that the process is alive and responsive; it
+// succeeds while loading too, so a slow load is not mistaken for a hang.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, currentHealth())
+}
+
+// handleReadyz reports whether the server should be sent traffic: the
+// keyspace is loaded and the RESP listener is accepting connections.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	h := currentHealth()
+	code := http.StatusOK
+	switch {
+	case h.Loading:
+		h.Status, code = "loading", http.StatusServiceUnavailable
+	case !h.Listening:
+		h.Status, code = "down", http.StatusServiceUnavailable
+	}
+	writeHealth(w, code, h)
+}
+
+func writeHealth(w http.ResponseWriter, code int, h healthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(h)
+}
+
+func adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", handleHealthz)
+	mux.HandleFunc("GET /readyz", handleReadyz)
+	return mux
+}
+
+// startAdmin serves the probe endpoints on port until ctx is done.
+func startAdmin(ctx context.Context, port int) error {
+	addr := ":" + strconv.Itoa(port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: adminHandler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("admin server stopped: %v", err)
+		}
+	}()
+	log.Printf("admin endpoints listening on %s", addr)
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminProbes(t *testing.T) {
+	srv := httptest.NewServer(adminHandler())
+	defer srv.Close()
+	t.Cleanup(func() {
+		listening.Store(false)
+		loading.Store(false)
+	})
+
+	probe := func(path string) (int, healthStatus) {
+		t.Helper()
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if ct := res.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s Content-Type = %q", path, ct)
+		}
+		var h healthStatus
+		if err := json.NewDecoder(res.Body).Decode(&h); err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, h
+	}
+
+	for _, tc := range []struct {
+		name               string
+		listening, loading bool
+		readyCode          int
+		readyStatus        string
+	}{
+		{"starting", false, false, http.StatusServiceUnavailable, "down"},
+		{"loading", false, true, http.StatusServiceUnavailable, "loading"},
+		{"loading while listening", true, true, http.StatusServiceUnavailable, "loading"},
+		{"serving", true, false, http.StatusOK, "ok"},
+	} {
+		listening.Store(tc.listening)
+		loading.Store(tc.loading)
+
+		code, h := probe("/healthz")
+		if code != http.StatusOK || h.Status != "ok" || h.Listening != tc.listening || h.Loading != tc.loading || h.Role != "master" {
+			t.Errorf("%s: /healthz = %d %+v", tc.name, code, h)
+		}
+		code, h = probe("/readyz")
+		if code != tc.readyCode || h.Status != tc.readyStatus {
+			t.Errorf("%s: /readyz = %d %q, want %d %q", tc.name, code, h.Status, tc.readyCode, tc.readyStatus)
+		}
+	}
+
+	res, err := http.Post(srv.URL+"/readyz", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /readyz = %d, want 405", res.StatusCode)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+type blockingPopFunc func(ctx context.Context, keys []string, timeout time.Duration, db int) (key, value string, ok bool, err error)
+
+// handleBlockingPop serves BLPOP and BRPOP key [key ...] timeout, replying
+// with the key that was served and the element popped from it. The wait
+// ends at once if the client hangs up or the server shuts down.
+func handleBlockingPop(cmd *Command, c *client, pop blockingPopFunc) resp.Value {
+	if len(cmd.Args) < 2 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	keys := cmd.Args[:len(cmd.Args)-1]
+	timeout, errReply := parseBlockTimeout(cmd.Args[len(cmd.Args)-1])
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	ctx, stop := c.watchHangup()
+	key, value, ok, err := pop(ctx, keys, timeout, cmd.DB)
+	stop()
+	if err != nil {
+		return errorReply(err)
+	}
+	if !ok {
+		return resp.Value{Typ: "array"}
+	}
+	return resp.Value{Typ: "array", Array: []resp.Value{
+		{Typ: "bulk", Bulk: key},
+		{Typ: "bulk", Bulk: value},
+	}}
+}
+
+// watchHangup returns a context for a blocking command that is also done
+// once the peer closes the connection, which the connection loop cannot
+// notice while it waits for the command to finish. It does so by peeking at
+// the socket, which leaves any pipelined request buffered for the loop; a
+// pipelined request ends the watch early. stop must be called before the
+// loop reads again.
+func (c *client) watchHangup() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(c.ctx)
+	if c.reader == nil {
+		return ctx, cancel
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := c.reader.Peek(); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			cancel()
+		}
+	}()
+	return ctx, func() {
+		// An expired deadline pulls the peek out of its read; bufio hands
+		// the timeout to the peek alone, so the loop's next read is clean.
+		c.conn.SetReadDeadline(time.Now())
+		<-done
+		c.conn.SetReadDeadline(time.Time{})
+		cancel()
+	}
+}
+
+// parseBlockTimeout reads a blocking command's timeout in seconds, which
+// may be fractional; 0 blocks forever. A positive timeout waits at least
+// a millisecond, so one too small to represent does not block forever.
+func parseBlockTimeout(s string) (time.Duration, *resp.Error) {
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(secs) || math.IsInf(secs, 0) || secs > math.MaxInt64/float64(time.Second) {
+		return 0, resp.NewError("ERR", "timeout is not a float or out of range")
+	}
+	if secs < 0 {
+		return 0, resp.NewError("ERR", "timeout is negative")
+	}
+	timeout := time.Duration(secs * float64(time.Second))
+	if secs > 0 && timeout < time.Millisecond {
+		timeout = time.Millisecond
+	}
+	return timeout, nil
+}
+
+// handleBLMove serves BLMOVE source destination LEFT|RIGHT LEFT|RIGHT
+// timeout, replying with the moved element or, on a timeout, a nil array.
+func handleBLMove(cmd *Command, c *client) resp.Value {
+	if len(cmd.Args) != 5 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	fromLeft, errReply := parseDirection(cmd.Args[2])
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	toLeft, errReply := parseDirection(cmd.Args[3])
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	timeout, errReply := parseBlockTimeout(cmd.Args[4])
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	ctx, stop := c.watchHangup()
+	element, ok, err := keyStorage.BLMove(ctx, cmd.Args[0], cmd.Args[1], fromLeft, toLeft, timeout, cmd.DB)
+	stop()
+	if err != nil {
+		return errorReply(err)
+	}
+	if !ok {
+		return resp.Value{Typ: "array"}
+	}
+	return resp.Value{Typ: "bulk", Bulk: element}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBlockTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    time.Duration
+		wantErr string
+	}{
+		{in: "0", want: 0},
+		{in: "0.0", want: 0},
+		{in: "1", want: time.Second},
+		{in: "0.25", want: 250 * time.Millisecond},
+		{in: "0.0005", want: time.Millisecond},
+		{in: "1e-12", want: time.Millisecond},
+		{in: "5e-324", want: time.Millisecond},
+		{in: "-1", wantErr: "ERR timeout is negative"},
+		{in: "soon", wantErr: "ERR timeout is not a float or out of range"},
+		{in: "NaN", wantErr: "ERR timeout is not a float or out of range"},
+		{in: "+Inf", wantErr: "ERR timeout is not a float or out of range"},
+		{in: "1e300", wantErr: "ERR timeout is not a float or out of range"},
+	} {
+		got, err := parseBlockTimeout(tc.in)
+		switch {
+		case tc.wantErr != "":
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("parseBlockTimeout(%q) = %v, %v; want error %q", tc.in, got, err, tc.wantErr)
+			}
+		case err != nil || got != tc.want:
+			t.Errorf("parseBlockTimeout(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestTinyBlockTimeout(t *testing.T) {
+	c := dial(t, startServer(t))
+	c.do(t, "DEL", "tiny")
+	start := time.Now()
+	if v := c.do(t, "BLPOP", "tiny", "0.0000001"); !v.IsNull() {
+		t.Fatalf("BLPOP = %+v, want a timeout", v)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("BLPOP with a tiny timeout took %v", d)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// client is the server side of one connection: the state its commands
+// leave behind for the ones that follow.
+type client struct {
+	conn net.Conn
+	// reader is the connection's request reader, which blocking commands
+	// watch for the peer hanging up.
+	reader *resp.Reader
+	// ctx is done when the connection closes or the server shuts down;
+	// blocking commands give up waiting when it is.
+	ctx context.Context
+
+	// noEvict exempts the connection from being dropped to reclaim
+	// memory once maxmemory eviction exists. noTouch keeps its reads,
+	// other than TOUCH, from updating the idle time and LFU counter that
+	// OBJECT reports. Both are set by CLIENT.
+	noEvict bool
+	noTouch bool
+
+	// db is the database SELECT chose; commands run against it.
+	db int
+}
+
+func newClient(ctx context.Context, conn net.Conn, reader *resp.Reader) *client {
+	return &client{conn: conn, reader: reader, ctx: ctx}
+}
+
+func handleClient(cmd *Command, c *client) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("CLIENT"))
+	}
+	sub := strings.ToUpper(cmd.Args[0])
+	switch sub {
+	case "NO-EVICT", "NO-TOUCH":
+		if len(cmd.Args) != 2 {
+			return resp.ErrorValue(resp.WrongArgs("CLIENT|" + sub))
+		}
+		var on bool
+		switch strings.ToUpper(cmd.Args[1]) {
+		case "ON":
+			on = true
+		case "OFF":
+		default:
+			return resp.ErrorValue(resp.ErrSyntax)
+		}
+		if sub == "NO-EVICT" {
+			c.noEvict = on
+		} else {
+			c.noTouch = on
+		}
+		return resp.Value{Typ: "string", Str: "OK"}
+	default:
+		return resp.ErrorValue(resp.Errorf("ERR", "unknown subcommand '%s'. Try CLIENT HELP.", cmd.Args[0]))
+	}
+}
+
+func handleSelect(cmd *Command, c *client) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("SELECT"))
+	}
+	db, err := parseDB(cmd.Args[0])
+	if err != nil {
+		return resp.ErrorValue(err)
+	}
+	c.db = db
+	return resp.Value{Typ: "string", Str: "OK"}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientNoTouch(t *testing.T) {
+	addr := startServer(t)
+	c, quiet := dial(t, addr), dial(t, addr)
+	c.do(t, "DEL", "notouch")
+	c.do(t, "SET", "notouch", "v")
+	if v := quiet.do(t, "CLIENT", "NO-TOUCH", "ON"); v.Str != "OK" {
+		t.Fatalf("CLIENT NO-TOUCH ON = %+v", v)
+	}
+	time.Sleep(1100 * time.Millisecond)
+
+	if v := quiet.do(t, "GET", "notouch"); v.Bulk != "v" {
+		t.Fatalf("GET = %+v", v)
+	}
+	if v := c.do(t, "OBJECT", "IDLETIME", "notouch"); v.Num < 1 {
+		t.Fatalf("IDLETIME after a NO-TOUCH GET = %d, want it unchanged", v.Num)
+	}
+	if v := c.do(t, "OBJECT", "FREQ", "notouch"); v.Num != 5 {
+		t.Fatalf("FREQ after a NO-TOUCH GET = %d, want the initial 5", v.Num)
+	}
+
+	// TOUCH still counts as an access.
+	quiet.do(t, "TOUCH", "notouch")
+	if v := c.do(t, "OBJECT", "IDLETIME", "notouch"); v.Num != 0 {
+		t.Fatalf("IDLETIME after TOUCH = %d, want 0", v.Num)
+	}
+	if v := c.do(t, "OBJECT", "FREQ", "notouch"); v.Num != 6 {
+		t.Fatalf("FREQ after TOUCH = %d, want 6", v.Num)
+	}
+}
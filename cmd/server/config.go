@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// Config holds the server settings. Values come from an optional
+// redis.conf style file (one "directive value" pair per line) and are
+// then overridden by any command line flags that were set explicitly.
+type Config struct {
+	Port      int
+	Databases int
+	// AdminPort, when set, serves the HTTP /healthz and /readyz probes.
+	AdminPort int
+	// WriteTimeout is how many seconds a reply may wait on a client that
+	// is not reading before the write is retried; after a few such
+	// timeouts in a row the client is disconnected. 0 waits forever. A
+	// reload applies to open connections too.
+	WriteTimeout int
+	// WatchdogPeriod is how many milliseconds a command may run before a
+	// warning with a goroutine dump is logged. 0 turns the watchdog off.
+	WatchdogPeriod int
+	// OTLPEndpoint, when set, is the OTLP/HTTP collector command spans
+	// are exported to.
+	OTLPEndpoint string
+	// LoadJSON is a JSON dump to seed the keyspace from at startup, and
+	// SaveJSON where to write one on shutdown.
+	LoadJSON string
+	SaveJSON string
+	// Pidfile, when set, is where the server writes its pid while it runs.
+	Pidfile string
+	// HotKeys is how many of the most accessed keys INFO hotkeys
+	// estimates; 0 turns tracking off.
+	HotKeys int
+	// Renames are the rename-command directives; only the config file
+	// sets them.
+	Renames commandRenames
+
+	// path is the config file, if any, and args the command line, kept
+	// so CONFIG RELOAD can load them again.
+	path string
+	args []string
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Port:         8090,
+		Databases:    storage.DefaultDatabases,
+		WriteTimeout: 10,
+	}
+}
+
+func loadConfig(args []string) (*Config, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a redis.conf style config file")
+	port := fs.Int("port", cfg.Port, "TCP port to listen on")
+	adminPort := fs.Int("admin-port", cfg.AdminPort, "HTTP port for /healthz and /readyz, 0 to disable")
+	databases := fs.Int("databases", cfg.Databases, "number of logical databases")
+	writeTimeout := fs.Int("write-timeout", cfg.WriteTimeout, "seconds a reply may wait on a client that is not reading, 0 to wait forever")
+	watchdogPeriod := fs.Int("watchdog-period", cfg.WatchdogPeriod, "milliseconds a command may run before it is logged with a stack dump, 0 to disable")
+	loadJSON := fs.String("load-json", cfg.LoadJSON, "JSON dump to load at startup")
+	pidfile := fs.String("pidfile", cfg.Pidfile, "file to write the server's pid to")
+	saveJSON := fs.String("save-json", cfg.SaveJSON, "file to write a JSON dump to on shutdown")
+	hotKeys := fs.Int("hotkeys", cfg.HotKeys, "number of most accessed keys to track, 0 to disable")
+	otlpEndpoint := fs.String("otlp-endpoint", cfg.OTLPEndpoint, "OTLP/HTTP collector URL to export command traces to")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg.path, cfg.args = *configPath, args
+	if cfg.path != "" {
+		if err := cfg.loadFile(cfg.path); err != nil {
+			return nil, err
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "databases":
+			cfg.Databases = *databases
+		case "admin-port":
+			cfg.AdminPort = *adminPort
+		case "otlp-endpoint":
+			cfg.OTLPEndpoint = *otlpEndpoint
+		case "write-timeout":
+			cfg.WriteTimeout = *writeTimeout
+		case "watchdog-period":
+			cfg.WatchdogPeriod = *watchdogPeriod
+		case "load-json":
+			cfg.LoadJSON = *loadJSON
+		case "save-json":
+			cfg.SaveJSON = *saveJSON
+		case "pidfile":
+			cfg.Pidfile = *pidfile
+		case "hotkeys":
+			cfg.HotKeys = *hotKeys
+		}
+	})
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields, err := resp.SplitArgs(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: unbalanced quotes", path, lineNo)
+		}
+		if err := c.apply(strings.ToLower(fields[0]), fields[1:]); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Config) apply(directive string, args []string) error {
+	switch directive {
+	case "port":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid port value %q", args[0])
+		}
+		c.Port = n
+	case "databases":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid databases value %q", args[0])
+		}
+		c.Databases = n
+	case "admin-port":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid admin-port value %q", args[0])
+		}
+		c.AdminPort = n
+	case "otlp-endpoint":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		c.OTLPEndpoint = args[0]
+	case "write-timeout":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid write-timeout value %q", args[0])
+		}
+		c.WriteTimeout = n
+	case "watchdog-period":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid watchdog-period value %q", args[0])
+		}
+		c.WatchdogPeriod = n
+	case "load-json", "save-json":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		if directive == "load-json" {
+			c.LoadJSON = args[0]
+		} else {
+			c.SaveJSON = args[0]
+		}
+	case "pidfile":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		c.Pidfile = args[0]
+	case "hotkeys":
+		if len(args) != 1 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid hotkeys value %q", args[0])
+		}
+		c.HotKeys = n
+	case "rename-command":
+		if len(args) != 2 {
+			return fmt.Errorf("wrong number of arguments for '%s'", directive)
+		}
+		return c.Renames.add(args[0], args[1])
+	default:
+		return fmt.Errorf("unknown directive '%s'", directive)
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+	if c.AdminPort != 0 && (c.AdminPort < 1 || c.AdminPort > 65535 || c.AdminPort == c.Port) {
+		return fmt.Errorf("admin-port must be between 1 and 65535 and differ from port, got %d", c.AdminPort)
+	}
+	if c.Databases < 1 {
+		return fmt.Errorf("databases must be at least 1, got %d", c.Databases)
+	}
+	if c.WriteTimeout < 0 {
+		return fmt.Errorf("write-timeout must not be negative, got %d", c.WriteTimeout)
+	}
+	if c.WatchdogPeriod < 0 {
+		return fmt.Errorf("watchdog-period must not be negative, got %d", c.WatchdogPeriod)
+	}
+	if c.HotKeys < 0 {
+		return fmt.Errorf("hotkeys must not be negative, got %d", c.HotKeys)
+	}
+	return nil
+}
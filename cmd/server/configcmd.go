@@ -0,0 +1,33 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+func handleConfig(cmd *Command) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("CONFIG"))
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "RESETSTAT":
+		if len(cmd.Args) != 1 {
+			return resp.ErrorValue(resp.WrongArgs("CONFIG|RESETSTAT"))
+		}
+		cmdStats.reset()
+		keyStorage.ResetCounters()
+		resetMetrics()
+		return resp.Value{Typ: "string", Str: "OK"}
+	case "RELOAD":
+		if len(cmd.Args) != 1 {
+			return resp.ErrorValue(resp.WrongArgs("CONFIG|RELOAD"))
+		}
+		if err := reloadConfig(); err != nil {
+			return resp.ErrorValue(err)
+		}
+		return resp.Value{Typ: "string", Str: "OK"}
+	default:
+		return resp.ErrorValue(resp.Errorf("ERR", "unknown subcommand '%s'. Try CONFIG HELP.", cmd.Args[0]))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// maxWriteStalls is how many write timeouts in a row, with no bytes
+// accepted in between, mark a client as stalled.
+const maxWriteStalls = 3
+
+// errClientStalled is returned once a client has stopped reading replies.
+var errClientStalled = errors.New("client stopped reading replies")
+
+// writeTimeout is the write-timeout setting; 0 waits forever. It is read
+// on every write, so a reload applies to connections already open.
+var writeTimeout atomic.Int64
+
+// stallWriter writes replies to a client under a deadline per attempt, so
+// a client that never reads cannot park its handler in Write forever. A
+// write that times out is retried from where it stopped; a slow reader
+// that keeps accepting bytes is never cut off.
+type stallWriter struct {
+	conn net.Conn
+	// deadline is set while the last write ran under a deadline.
+	deadline bool
+}
+
+func (w *stallWriter) Write(p []byte) (int, error) {
+	timeout := time.Duration(writeTimeout.Load())
+	if timeout <= 0 {
+		if w.deadline {
+			w.conn.SetWriteDeadline(time.Time{})
+			w.deadline = false
+		}
+		return w.conn.Write(p)
+	}
+	w.deadline = true
+	written, stalls := 0, 0
+	for written < len(p) {
+		w.conn.SetWriteDeadline(time.Now().Add(timeout))
+		n, err := w.conn.Write(p[written:])
+		written += n
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			return written, err
+		}
+		if n > 0 {
+			stalls = 0
+		}
+		if stalls++; stalls >= maxWriteStalls {
+			return written, fmt.Errorf("%w for %s", errClientStalled, time.Duration(stalls)*timeout)
+		}
+	}
+	return written, nil
+}
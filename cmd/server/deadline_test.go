@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStalledClientIsDropped(t *testing.T) {
+	logs := captureLog(t)
+	addr := startServer(t)
+	other := dial(t, addr)
+
+	// The stalled client connects before the timeout is set, as if a
+	// reload had turned it on.
+	stalled := dial(t, addr)
+	stalled.do(t, "SET", "stall", strings.Repeat("x", 1<<20))
+	writeTimeout.Store(int64(50 * time.Millisecond))
+	t.Cleanup(func() { writeTimeout.Store(0) })
+
+	// Ask for far more than the socket buffers hold and never read it.
+	for range 64 {
+		stalled.w.WriteArrayHeader(2)
+		stalled.w.WriteBulkString("GET")
+		stalled.w.WriteBulkString("stall")
+	}
+	if err := stalled.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "client stopped reading replies") {
+		if time.Now().After(deadline) {
+			t.Fatalf("the stalled client was not dropped; log:\n%s", logs.String())
+		}
+		if v := other.do(t, "PING"); v.Str != "PONG" {
+			t.Fatalf("PING from another client = %+v", v)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// What the server managed to send is still there, then the connection ends.
+	stalled.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err := io.Copy(io.Discard, stalled)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("the server did not close the stalled connection")
+	}
+	if v := other.do(t, "GET", "stall"); len(v.Bulk) != 1<<20 {
+		t.Fatalf("GET from another client returned %d bytes", len(v.Bulk))
+	}
+}
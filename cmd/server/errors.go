@@ -0,0 +1,19 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// errorReply turns an error from storage into the reply Redis sends for it.
+func errorReply(err error) resp.Value {
+	switch {
+	case errors.Is(err, storage.ErrWrongType):
+		return resp.ErrorValue(resp.ErrWrongType)
+	case errors.Is(err, storage.ErrNotInteger):
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	return resp.ErrorValue(err)
+}
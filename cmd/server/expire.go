@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handleExpire serves EXPIRE and PEXPIRE, whose TTL argument counts in
+// unit.
+func handleExpire(cmd *Command, unit time.Duration) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	n, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return resp.ErrorValue(invalidExpireTime(cmd.Name))
+	}
+	ok, err := keyStorage.Expire(cmd.Args[0], time.Duration(n)*unit, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+// handleExpireAt serves EXPIREAT and PEXPIREAT, whose timestamp argument
+// is a Unix time in unit, which is either seconds or milliseconds.
+func handleExpireAt(cmd *Command, unit time.Duration) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	n, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	scale := int64(unit / time.Millisecond)
+	if n > math.MaxInt64/scale || n < math.MinInt64/scale {
+		return resp.ErrorValue(invalidExpireTime(cmd.Name))
+	}
+	ok, err := keyStorage.SetExpiryAt(cmd.Args[0], time.UnixMilli(n*scale), cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+// handleExpireTime serves EXPIRETIME and PEXPIRETIME: -2 for a missing key,
+// -1 for one without an expiry, otherwise the Unix time it expires at in
+// unit, rounded to the nearest.
+func handleExpireTime(cmd *Command, unit time.Duration) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	at, ok, err := keyStorage.ExpiryAt(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	switch {
+	case !ok:
+		return resp.Value{Typ: "integer", Num: -2}
+	case at.IsZero():
+		return resp.Value{Typ: "integer", Num: -1}
+	}
+	ms := at.Round(unit).UnixMilli()
+	return resp.Value{Typ: "integer", Num: ms / int64(unit/time.Millisecond)}
+}
+
+// handleTTL serves TTL and PTTL: -2 for a missing key, -1 for one without
+// an expiry, otherwise the time left in unit, rounded to the nearest.
+func handleTTL(cmd *Command, unit time.Duration) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	ttl, ok, err := keyStorage.TTL(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	switch {
+	case !ok:
+		return resp.Value{Typ: "integer", Num: -2}
+	case ttl < 0:
+		return resp.Value{Typ: "integer", Num: -1}
+	}
+	return resp.Value{Typ: "integer", Num: int64(ttl.Round(unit) / unit)}
+}
+
+func handlePersist(cmd *Command) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("PERSIST"))
+	}
+	ok, err := keyStorage.Persist(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+func invalidExpireTime(cmd string) *resp.Error {
+	return resp.Errorf("ERR", "invalid expire time in '%s' command", strings.ToLower(cmd))
+}
+
+// boolReply is the 1/0 integer reply commands use for yes and no.
+func boolReply(ok bool) resp.Value {
+	if ok {
+		return resp.Value{Typ: "integer", Num: 1}
+	}
+	return resp.Value{Typ: "integer", Num: 0}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handleHSet serves HSET key field value [field value ...], replying with
+// how many fields were added.
+func handleHSet(cmd *Command) resp.Value {
+	if len(cmd.Args) < 3 || len(cmd.Args)%2 != 1 {
+		return resp.ErrorValue(resp.WrongArgs("HSET"))
+	}
+	pairs := make([][2]string, 0, len(cmd.Args)/2)
+	for i := 1; i < len(cmd.Args); i += 2 {
+		pairs = append(pairs, [2]string{cmd.Args[i], cmd.Args[i+1]})
+	}
+	n, err := keyStorage.HSet(cmd.Args[0], pairs, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleHSetNX(cmd *Command) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs("HSETNX"))
+	}
+	ok, err := keyStorage.HSetNX(cmd.Args[0], cmd.Args[1], cmd.Args[2], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+func handleHGet(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("HGET"))
+	}
+	return stringReply(keyStorage.HGet(cmd.Args[0], cmd.Args[1], cmd.DB))
+}
+
+func handleHDel(cmd *Command) resp.Value {
+	if len(cmd.Args) < 2 {
+		return resp.ErrorValue(resp.WrongArgs("HDEL"))
+	}
+	n, err := keyStorage.HDel(cmd.Args[0], cmd.Args[1:], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+// handleHGetAll replies with the fields and values of a hash as one flat
+// array, field first.
+func handleHGetAll(cmd *Command) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("HGETALL"))
+	}
+	pairs, err := keyStorage.HGetAll(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	items := make([]string, 0, 2*len(pairs))
+	for _, p := range pairs {
+		items = append(items, p[0], p[1])
+	}
+	return bulkArray(items)
+}
+
+func handleHMGet(cmd *Command) resp.Value {
+	if len(cmd.Args) < 2 {
+		return resp.ErrorValue(resp.WrongArgs("HMGET"))
+	}
+	vals, err := keyStorage.HMGet(cmd.Args[0], cmd.Args[1:], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return nullableArray(vals)
+}
+
+func handleHExists(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("HEXISTS"))
+	}
+	ok, err := keyStorage.HExists(cmd.Args[0], cmd.Args[1], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+func handleHLen(cmd *Command) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("HLEN"))
+	}
+	n, err := keyStorage.HLen(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleHStrLen(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("HSTRLEN"))
+	}
+	n, err := keyStorage.HStrLen(cmd.Args[0], cmd.Args[1], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+// handleHList serves HKEYS and HVALS, which take just a key and reply with
+// an array.
+func handleHList(cmd *Command, list func(key string, db int) ([]string, error)) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	items, err := list(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return bulkArray(items)
+}
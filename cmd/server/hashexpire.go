@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handleHExpire serves HEXPIRE and HPEXPIRE, whose TTL counts in unit, and
+// with absolute set HEXPIREAT and HPEXPIREAT, whose argument is a Unix time
+// in unit: key time [NX|XX|GT|LT] FIELDS numfields field [field ...]. The
+// reply has one result per field.
+func handleHExpire(cmd *Command, unit time.Duration, absolute bool) resp.Value {
+	if len(cmd.Args) < 5 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	n, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	scale := int64(unit)
+	if absolute {
+		scale = int64(unit / time.Millisecond)
+	}
+	if n < 0 || n > math.MaxInt64/scale {
+		return resp.ErrorValue(invalidExpireTime(cmd.Name))
+	}
+	var at time.Time
+	if absolute {
+		at = time.UnixMilli(n * scale)
+	} else {
+		at = time.Now().Add(time.Duration(n * scale))
+	}
+
+	rest := cmd.Args[2:]
+	cond := storage.ExpireAlways
+	switch strings.ToUpper(rest[0]) {
+	case "NX":
+		cond = storage.ExpireNX
+	case "XX":
+		cond = storage.ExpireXX
+	case "GT":
+		cond = storage.ExpireGT
+	case "LT":
+		cond = storage.ExpireLT
+	}
+	if cond != storage.ExpireAlways {
+		rest = rest[1:]
+	}
+	fields, errReply := parseFields(rest)
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	results, err := keyStorage.HExpireAt(cmd.Args[0], fields, at, cond, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return integerArray(results)
+}
+
+// handleFieldExpiry serves HTTL, HPTTL, HEXPIRETIME and HPEXPIRETIME key
+// FIELDS numfields field [field ...]: per field, -2 when it does not exist,
+// -1 when it has no expiry, otherwise what report makes of its expiry.
+func handleFieldExpiry(cmd *Command, report func(at time.Time) int) resp.Value {
+	if len(cmd.Args) < 4 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	fields, errReply := parseFields(cmd.Args[1:])
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	ats, err := keyStorage.HExpiryAt(cmd.Args[0], fields, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	results := make([]int, len(ats))
+	for i, at := range ats {
+		switch {
+		case at == nil:
+			results[i] = storage.FieldMissing
+		case at.IsZero():
+			results[i] = storage.FieldNoExpiry
+		default:
+			results[i] = report(*at)
+		}
+	}
+	return integerArray(results)
+}
+
+// fieldTTL reports, for HTTL and HPTTL, the time left until at in unit,
+// rounded to the nearest.
+func fieldTTL(unit time.Duration) func(time.Time) int {
+	return func(at time.Time) int {
+		return int(max(time.Until(at), 0).Round(unit) / unit)
+	}
+}
+
+// fieldExpireTime reports, for HEXPIRETIME and HPEXPIRETIME, at as a Unix
+// time in unit, rounded to the nearest.
+func fieldExpireTime(unit time.Duration) func(time.Time) int {
+	return func(at time.Time) int {
+		return int(at.Round(unit).UnixMilli() / int64(unit/time.Millisecond))
+	}
+}
+
+// handleHPersist serves HPERSIST key FIELDS numfields field [field ...].
+func handleHPersist(cmd *Command) resp.Value {
+	if len(cmd.Args) < 4 {
+		return resp.ErrorValue(resp.WrongArgs("HPERSIST"))
+	}
+	fields, errReply := parseFields(cmd.Args[1:])
+	if errReply != nil {
+		return resp.ErrorValue(errReply)
+	}
+	results, err := keyStorage.HPersist(cmd.Args[0], fields, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return integerArray(results)
+}
+
+// parseFields reads the FIELDS numfields field [field ...] arguments the
+// hash field expiry commands end with.
+func parseFields(args []string) ([]string, *resp.Error) {
+	if len(args) < 2 || !strings.EqualFold(args[0], "FIELDS") {
+		return nil, resp.NewError("ERR", "Mandatory argument FIELDS is missing or not at the right position")
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return nil, resp.NewError("ERR", "Parameter `numFields` should be greater than 0")
+	}
+	if n != len(args)-2 {
+		return nil, resp.NewError("ERR", "The `numfields` parameter must match the number of arguments")
+	}
+	return args[2:], nil
+}
+
+func integerArray(nums []int) resp.Value {
+	arr := make([]resp.Value, len(nums))
+	for i, n := range nums {
+		arr[i] = resp.Value{Typ: "integer", Num: int64(n)}
+	}
+	return resp.Value{Typ: "array", Array: arr}
+}
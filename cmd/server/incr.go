@@ -0,0 +1,39 @@
+package main
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handleIncr serves INCR and DECR, which step the counter by sign, and
+// INCRBY and DECRBY, which take the step as their second argument.
+func handleIncr(cmd *Command, sign int64, withStep bool) resp.Value {
+	arity := 1
+	if withStep {
+		arity = 2
+	}
+	if len(cmd.Args) != arity {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	delta := sign
+	if withStep {
+		n, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+		if err != nil {
+			return resp.ErrorValue(resp.ErrNotInteger)
+		}
+		if sign < 0 {
+			if n == math.MinInt64 {
+				return resp.ErrorValue(resp.NewError("ERR", "decrement would overflow"))
+			}
+			n = -n
+		}
+		delta = n
+	}
+	n, err := keyStorage.IncrBy(cmd.Args[0], delta, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: n}
+}
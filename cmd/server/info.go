@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+type infoSection struct {
+	name   string
+	render func(b *strings.Builder)
+	// extra sections are left out of a plain INFO and only shown when
+	// named or with INFO all.
+	extra bool
+}
+
+// infoSections lists the INFO sections in the order they are printed.
+var infoSections = []infoSection{
+	{name: "Stats", render: writeStatsInfo},
+	{name: "Commandstats", render: writeCommandstatsInfo, extra: true},
+	{name: "Latencystats", render: writeLatencystatsInfo, extra: true},
+	{name: "Keyspace", render: writeKeyspaceInfo},
+	{name: "Hotkeys", render: writeHotkeysInfo, extra: true},
+}
+
+func handleInfo(cmd *Command) resp.Value {
+	wanted := make(map[string]bool, len(cmd.Args))
+	for _, arg := range cmd.Args {
+		wanted[strings.ToLower(arg)] = true
+	}
+	all := wanted["all"] || wanted["everything"]
+	defaults := all || len(wanted) == 0 || wanted["default"]
+
+	var b strings.Builder
+	for _, section := range infoSections {
+		if !wanted[strings.ToLower(section.name)] && ((section.extra && !all) || !defaults) {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# " + section.name + "\r\n")
+		section.render(&b)
+	}
+	return resp.Value{Typ: "bulk", Bulk: b.String()}
+}
+
+func writeKeyspaceInfo(b *strings.Builder) {
+	for db := 0; db < keyStorage.Databases(); db++ {
+		stats, err := keyStorage.Stats(db)
+		if err != nil || stats.Keys == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "db%d:keys=%d,expires=%d,avg_ttl=%d\r\n", db, stats.Keys, stats.Expires, stats.AvgTTL.Milliseconds())
+	}
+}
+
+func writeStatsInfo(b *strings.Builder) {
+	writeThroughputInfo(b)
+	c := keyStorage.Counters()
+	fmt.Fprintf(b, "expired_keys:%d\r\n", c.Expired)
+	fmt.Fprintf(b, "evicted_keys:%d\r\n", c.Evicted)
+	fmt.Fprintf(b, "keyspace_hits:%d\r\n", c.Hits)
+	fmt.Fprintf(b, "keyspace_misses:%d\r\n", c.Misses)
+}
+
+// writeHotkeysInfo lists the tracked keys, most accessed first, with
+// their estimated recent access counts.
+func writeHotkeysInfo(b *strings.Builder) {
+	for i, hk := range keyStorage.HotKeys() {
+		fmt.Fprintf(b, "hotkey%d:db=%d,key=%s,accesses=%d\r\n", i, hk.DB, strconv.Quote(hk.Key), hk.Count)
+	}
+}
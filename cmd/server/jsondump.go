@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// loadJSON seeds the keyspace from a file written by saveJSON.
+func loadJSON(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return keyStorage.ImportJSON(f)
+}
+
+// saveJSON writes every database to path as JSON. The file is written
+// next to path and renamed into place, so a crash never leaves half a dump.
+func saveJSON(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := keyStorage.ExportJSON(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("export failed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handleExists serves EXISTS and TOUCH, which both reply with how many of
+// their keys exist; TOUCH also counts as an access to each.
+func handleExists(cmd *Command, touch bool) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	count := keyStorage.Exists
+	if touch {
+		count = keyStorage.Touch
+	}
+	n, err := count(cmd.Args, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+// errDBRange is the reply for a database index that does not exist.
+var errDBRange = resp.NewError("ERR", "DB index is out of range")
+
+// parseDB parses a database index argument.
+func parseDB(arg string) (int, *resp.Error) {
+	db, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, resp.ErrNotInteger
+	}
+	if db < 0 || db >= keyStorage.Databases() {
+		return 0, errDBRange
+	}
+	return db, nil
+}
+
+// handleCopy serves COPY source destination [DB destination-db] [REPLACE].
+func handleCopy(cmd *Command) resp.Value {
+	if len(cmd.Args) < 2 {
+		return resp.ErrorValue(resp.WrongArgs("COPY"))
+	}
+	dstDB, replace := cmd.DB, false
+	for i := 2; i < len(cmd.Args); i++ {
+		switch strings.ToUpper(cmd.Args[i]) {
+		case "DB":
+			if i+1 == len(cmd.Args) {
+				return resp.ErrorValue(resp.ErrSyntax)
+			}
+			i++
+			db, err := parseDB(cmd.Args[i])
+			if err != nil {
+				return resp.ErrorValue(err)
+			}
+			dstDB = db
+		case "REPLACE":
+			replace = true
+		default:
+			return resp.ErrorValue(resp.ErrSyntax)
+		}
+	}
+	ok, err := keyStorage.Copy(cmd.Args[0], cmd.Args[1], cmd.DB, dstDB, replace)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+func handleMove(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("MOVE"))
+	}
+	db, perr := parseDB(cmd.Args[1])
+	if perr != nil {
+		return resp.ErrorValue(perr)
+	}
+	ok, err := keyStorage.Move(cmd.Args[0], cmd.DB, db)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(ok)
+}
+
+func handleDBSize(cmd *Command) resp.Value {
+	if len(cmd.Args) != 0 {
+		return resp.ErrorValue(resp.WrongArgs("DBSIZE"))
+	}
+	n, err := keyStorage.DBSize(cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleRandomKey(cmd *Command) resp.Value {
+	if len(cmd.Args) != 0 {
+		return resp.ErrorValue(resp.WrongArgs("RANDOMKEY"))
+	}
+	return stringReply(keyStorage.RandomKey(cmd.DB))
+}
+
+func handleType(cmd *Command) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("TYPE"))
+	}
+	typ, err := keyStorage.TypeCmd(cmd.Args[0], cmd.DB)
+	if errors.Is(err, storage.ErrNoSuchKey) {
+		return resp.Value{Typ: "string", Str: "none"}
+	}
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "string", Str: typ.String()}
+}
+
+var objectHelp = []string{
+	"OBJECT <subcommand> [<arg> [value] [opt] ...]. Subcommands are:",
+	"ENCODING <key>",
+	"    Return the kind of internal representation used to store the value of a key.",
+	"FREQ <key>",
+	"    Return the access frequency index of the key.",
+	"IDLETIME <key>",
+	"    Return the idle time of the key, that is the approximated number of",
+	"    seconds elapsed since the last access to the key.",
+	"REFCOUNT <key>",
+	"    Return the number of references of the value associated with the specified",
+	"    key.",
+	"HELP",
+	"    Print this help.",
+}
+
+// handleObject serves OBJECT ENCODING, IDLETIME, FREQ, REFCOUNT and HELP.
+// Unlike Redis, IDLETIME and FREQ are both tracked whatever the eviction
+// policy.
+func handleObject(cmd *Command) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("OBJECT"))
+	}
+	sub := strings.ToUpper(cmd.Args[0])
+	switch sub {
+	case "HELP":
+		arr := make([]resp.Value, len(objectHelp))
+		for i, line := range objectHelp {
+			arr[i] = resp.Value{Typ: "string", Str: line}
+		}
+		return resp.Value{Typ: "array", Array: arr}
+	case "ENCODING", "IDLETIME", "FREQ", "REFCOUNT":
+	default:
+		return resp.ErrorValue(resp.Errorf("ERR", "unknown subcommand '%s'. Try OBJECT HELP.", cmd.Args[0]))
+	}
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("OBJECT|" + sub))
+	}
+
+	info, ok, err := keyStorage.Object(cmd.Args[1], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	if !ok {
+		return resp.Null
+	}
+	switch sub {
+	case "ENCODING":
+		return resp.Value{Typ: "bulk", Bulk: info.Encoding}
+	case "IDLETIME":
+		return resp.Value{Typ: "integer", Num: int64(info.Idle / time.Second)}
+	case "FREQ":
+		return resp.Value{Typ: "integer", Num: int64(info.Freq)}
+	default:
+		return resp.Value{Typ: "integer", Num: 1}
+	}
+}
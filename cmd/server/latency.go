@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// latencyBuckets is the number of histogram buckets per command. Bucket i
+// counts calls that took at most 2^i microseconds, and the last one
+// everything slower, so the histogram spans 1us to about 16s.
+const latencyBuckets = 25
+
+func latencyBucket(usec int64) int {
+	if usec <= 1 {
+		return 0
+	}
+	return min(bits.Len64(uint64(usec-1)), latencyBuckets-1)
+}
+
+func handleLatency(cmd *Command) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("LATENCY"))
+	}
+	switch strings.ToUpper(cmd.Args[0]) {
+	case "HISTOGRAM":
+		return latencyHistogram(cmd.Args[1:])
+	default:
+		return resp.ErrorValue(resp.Errorf("ERR", "unknown subcommand '%s'. Try LATENCY HELP.", cmd.Args[0]))
+	}
+}
+
+// latencyHistogram replies, like Redis, with a map from each command name
+// to its call count and its cumulative latency distribution: for every
+// power-of-two bucket in microseconds where the count grows, how many
+// calls took at most that long. Without names every command that has
+// been called is listed; names that were never called are left out.
+func latencyHistogram(names []string) resp.Value {
+	cmdStats.mu.Lock()
+	defer cmdStats.mu.Unlock()
+
+	if len(names) == 0 {
+		for name := range cmdStats.stats {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+	}
+	reply := resp.Value{Typ: "map", Map: []resp.Pair{}}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		st, ok := cmdStats.stats[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		hist := resp.Value{Typ: "map", Map: []resp.Pair{}}
+		var cumulative int64
+		for i, n := range st.hist {
+			if n == 0 {
+				continue
+			}
+			cumulative += n
+			hist.Map = append(hist.Map, resp.Pair{
+				Key:   resp.Value{Typ: "integer", Num: 1 << i},
+				Value: resp.Value{Typ: "integer", Num: cumulative},
+			})
+		}
+		reply.Map = append(reply.Map, resp.Pair{
+			Key: resp.Value{Typ: "bulk", Bulk: strings.ToLower(name)},
+			Value: resp.Value{Typ: "map", Map: []resp.Pair{
+				{Key: resp.Value{Typ: "bulk", Bulk: "calls"}, Value: resp.Value{Typ: "integer", Num: st.calls}},
+				{Key: resp.Value{Typ: "bulk", Bulk: "histogram_usec"}, Value: hist},
+			}},
+		})
+	}
+	return reply
+}
+
+// latencyPercentiles are the percentiles INFO latencystats reports.
+var latencyPercentiles = []float64{50, 99, 99.9}
+
+// percentile returns the upper bound, in microseconds, of the bucket
+// holding the p-th percentile call, so it overestimates by up to 2x.
+func (st *commandStat) percentile(p float64) int64 {
+	target := int64(math.Ceil(p / 100 * float64(st.calls)))
+	var cumulative int64
+	for i, n := range st.hist {
+		if cumulative += n; cumulative >= max(target, 1) {
+			return 1 << i
+		}
+	}
+	return 1 << (latencyBuckets - 1)
+}
+
+func writeLatencystatsInfo(b *strings.Builder) {
+	cmdStats.mu.Lock()
+	defer cmdStats.mu.Unlock()
+	names := make([]string, 0, len(cmdStats.stats))
+	for name := range cmdStats.stats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		st := cmdStats.stats[name]
+		fmt.Fprintf(b, "latency_percentiles_usec_%s:", strings.ToLower(name))
+		for i, p := range latencyPercentiles {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(b, "p%s=%d.000", strconv.FormatFloat(p, 'f', -1, 64), st.percentile(p))
+		}
+		b.WriteString("\r\n")
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handlePush serves LPUSH, RPUSH and their X variants, replying with the
+// list's new length.
+func handlePush(cmd *Command, push func(key string, items []string, db int) (int, error)) resp.Value {
+	if len(cmd.Args) < 2 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	n, err := push(cmd.Args[0], cmd.Args[1:], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleRLen(cmd *Command) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("RLEN"))
+	}
+	n, err := keyStorage.RLen(cmd.Args[0], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+// handleRange serves LRANGE and RRANGE key start stop.
+func handleRange(cmd *Command, lrange func(key, from, to string, db int) ([]string, error)) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	for _, arg := range cmd.Args[1:] {
+		if _, err := strconv.Atoi(arg); err != nil {
+			return resp.ErrorValue(resp.ErrNotInteger)
+		}
+	}
+	items, err := lrange(cmd.Args[0], cmd.Args[1], cmd.Args[2], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return bulkArray(items)
+}
+
+// handlePop serves LPOP and RPOP key [count]. Without a count the reply is
+// the popped element or nil; with one it is an array, or a nil array when
+// the key does not exist.
+func handlePop(cmd *Command, pop func(key string, count, db int) ([]string, error)) resp.Value {
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	if len(cmd.Args) == 1 {
+		items, err := pop(cmd.Args[0], 1, cmd.DB)
+		if err != nil {
+			return errorReply(err)
+		}
+		if len(items) == 0 {
+			return resp.Null
+		}
+		return resp.Value{Typ: "bulk", Bulk: items[0]}
+	}
+
+	count, err := strconv.Atoi(cmd.Args[1])
+	if err != nil || count < 0 {
+		return resp.ErrorValue(resp.NewError("ERR", "value is out of range, must be positive"))
+	}
+	if count == 0 {
+		// The storage pops treat 0 as 1, so only look the key up.
+		n, err := keyStorage.RLen(cmd.Args[0], cmd.DB)
+		if err != nil {
+			return errorReply(err)
+		}
+		if n == 0 {
+			return resp.Value{Typ: "array"}
+		}
+		return resp.Value{Typ: "array", Array: []resp.Value{}}
+	}
+	items, err := pop(cmd.Args[0], count, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	if len(items) == 0 {
+		return resp.Value{Typ: "array"}
+	}
+	return bulkArray(items)
+}
+
+// bulkArray replies with items as an array of bulk strings; an empty or
+// nil items is the empty array, not the nil one.
+func bulkArray(items []string) resp.Value {
+	arr := make([]resp.Value, len(items))
+	for i, item := range items {
+		arr[i] = resp.Value{Typ: "bulk", Bulk: item}
+	}
+	return resp.Value{Typ: "array", Array: arr}
+}
+
+func handleLIndex(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("LINDEX"))
+	}
+	index, err := strconv.Atoi(cmd.Args[1])
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	return stringReply(keyStorage.LIndex(cmd.Args[0], index, cmd.DB))
+}
+
+func handleLSet(cmd *Command) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs("LSET"))
+	}
+	index, err := strconv.Atoi(cmd.Args[1])
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	if err := keyStorage.LSet(cmd.Args[0], index, cmd.Args[2], cmd.DB); err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "string", Str: "OK"}
+}
+
+// handleLInsert serves LINSERT key BEFORE|AFTER pivot element.
+func handleLInsert(cmd *Command) resp.Value {
+	if len(cmd.Args) != 4 {
+		return resp.ErrorValue(resp.WrongArgs("LINSERT"))
+	}
+	var before bool
+	switch strings.ToUpper(cmd.Args[1]) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+	default:
+		return resp.ErrorValue(resp.ErrSyntax)
+	}
+	n, err := keyStorage.LInsert(cmd.Args[0], before, cmd.Args[2], cmd.Args[3], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleLRem(cmd *Command) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs("LREM"))
+	}
+	count, err := strconv.Atoi(cmd.Args[1])
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	n, err := keyStorage.LRem(cmd.Args[0], count, cmd.Args[2], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleLTrim(cmd *Command) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs("LTRIM"))
+	}
+	start, err := strconv.Atoi(cmd.Args[1])
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	stop, err := strconv.Atoi(cmd.Args[2])
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	if err := keyStorage.LTrim(cmd.Args[0], start, stop, cmd.DB); err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "string", Str: "OK"}
+}
+
+// parseDirection reads a LEFT or RIGHT argument, reporting whether it is
+// LEFT.
+func parseDirection(arg string) (left bool, err *resp.Error) {
+	switch strings.ToUpper(arg) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, resp.ErrSyntax
+}
+
+// handleLMove serves LMOVE source destination LEFT|RIGHT LEFT|RIGHT, and
+// RPOPLPUSH source destination, which is LMOVE RIGHT LEFT.
+func handleLMove(cmd *Command) resp.Value {
+	fromLeft, toLeft := false, true
+	if cmd.Name == string(pkg.RPOPLPUSH_CMD) {
+		if len(cmd.Args) != 2 {
+			return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+		}
+	} else {
+		if len(cmd.Args) != 4 {
+			return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+		}
+		var err *resp.Error
+		if fromLeft, err = parseDirection(cmd.Args[2]); err != nil {
+			return resp.ErrorValue(err)
+		}
+		if toLeft, err = parseDirection(cmd.Args[3]); err != nil {
+			return resp.ErrorValue(err)
+		}
+	}
+	return stringReply(keyStorage.LMove(cmd.Args[0], cmd.Args[1], fromLeft, toLeft, cmd.DB))
+}
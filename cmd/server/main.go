@@ -1,13 +1,13 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"os"
 	"os/signal"
 	"strconv"
 	"strings"
@@ -24,24 +24,80 @@ var once sync.Once
 var keyStorage *storage.Storage
 var queues map[string][]string // connectionIp-transactionTImestamp => list of commands
 func main() {
+	cfg, err := loadConfig(os.Args[1:])
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
 	once.Do(func() {
-		keyStorage = storage.NewStorage()
+		keyStorage = storage.NewStorageWithConfig(storage.Config{Databases: cfg.Databases})
 		queues = make(map[string][]string)
+		renames = cfg.Renames
 	})
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	ln, err := net.Listen("tcp", ":8090")
+	if cfg.Pidfile != "" {
+		removePidfile, err := writePidfile(cfg.Pidfile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer removePidfile()
+	}
+
+	if cfg.AdminPort != 0 {
+		if err := startAdmin(ctx, cfg.AdminPort); err != nil {
+			log.Fatalf("failed to start admin endpoints: %v", err)
+		}
+	}
+	if cfg.LoadJSON != "" {
+		loading.Store(true)
+		if err := loadJSON(cfg.LoadJSON); err != nil {
+			log.Fatalf("failed to load %s: %v", cfg.LoadJSON, err)
+		}
+		loading.Store(false)
+		log.Printf("loaded keys from %s", cfg.LoadJSON)
+	}
+	if err := applyConfig(cfg); err != nil {
+		log.Fatalf("failed to apply config: %v", err)
+	}
+	defer shutdownConfig()
+
+	go runCron(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloadConfig(); err != nil {
+				log.Printf("config reload failed: %v", err)
+				continue
+			}
+			log.Println("config reloaded")
+		}
+	}()
+
+	// Under socket activation systemd owns the address and port is unused.
+	ln, err := systemdListener()
 	if err != nil {
-		log.Fatalf("failed to listen on :8090: %v", err)
+		log.Fatalf("failed to use the socket from systemd: %v", err)
+	}
+	if ln == nil {
+		addr := ":" + strconv.Itoa(cfg.Port)
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			log.Fatalf("failed to listen on %s: %v", addr, err)
+		}
 	}
 	defer ln.Close()
+	listening.Store(true)
+	notifySystemd("READY=1")
 
-	log.Println("server listening on :8090")
+	log.Printf("server listening on %s", ln.Addr())
 
 	go func() {
 		<-ctx.Done()
 		log.Println("shutting down, closing listener...")
+		notifySystemd("STOPPING=1")
+		listening.Store(false)
 		ln.Close()
 	}()
 
@@ -50,6 +106,13 @@ func main() {
 		if err != nil {
 
 			if ctx.Err() != nil {
+				if cfg.SaveJSON != "" {
+					if err := saveJSON(cfg.SaveJSON); err != nil {
+						log.Printf("failed to save %s: %v", cfg.SaveJSON, err)
+					} else {
+						log.Printf("saved keys to %s", cfg.SaveJSON)
+					}
+				}
 				log.Println("server stopped")
 				return
 			}
@@ -70,19 +133,43 @@ func handleConn(parentCtx context.Context, conn net.Conn) {
 	go func() {
 		defer cancel()
 
-		reader := bufio.NewReader(conn)
+		metered := meteredConn{conn}
+		reader := resp.NewReader(metered)
+		c := newClient(ctx, conn, reader)
+		writer := resp.NewWriter(&stallWriter{conn: metered})
+		var args [][]byte
 		for {
-			cmd, err := readCommand(reader)
+			var cmd *Command
+			var err error
+			cmd, args, err = readCommand(reader, args)
 			if err != nil {
 				if isClientDisconnect(err) {
 					return
 				}
 				log.Printf("Protocol error from %s: %v", conn.RemoteAddr(), err)
+				if errors.Is(err, resp.ErrProtocol) {
+					writer.WriteError("ERR " + err.Error())
+					writer.Flush()
+				}
 				return
 			}
+			if cmd == nil {
+				continue
+			}
 
-			response := dispatchCommand(cmd, conn)
-			if err := resp.WriteValue(conn, response); err != nil {
+			response := dispatch(cmd, c)
+			err = writer.WriteValue(response)
+			if err == nil {
+				err = writer.Flush()
+			}
+			if err != nil {
+				// Large replies reach the socket before Flush, so a
+				// stalled client can surface from either call.
+				if errors.Is(err, errClientStalled) {
+					log.Printf("closing connection from %s: %v", conn.RemoteAddr(), err)
+				} else if !isClientDisconnect(err) {
+					log.Printf("failed to write reply to %s: %v", conn.RemoteAddr(), err)
+				}
 				return
 			}
 		}
@@ -97,65 +184,223 @@ func isClientDisconnect(err error) bool {
 		isConnectionReset(err)
 }
 
-func readCommand(r *bufio.Reader) (*Command, error) {
-	val, err := resp.UnmarshalOne(r)
+// readCommand reads the next request, reusing args as scratch space between
+// calls; the returned Command holds its own copies of the arguments. Requests
+// that do not start with '*' are parsed as inline commands, so the server can
+// be driven by hand over telnet. Empty requests yield a nil Command.
+func readCommand(r *resp.Reader, args [][]byte) (*Command, [][]byte, error) {
+	prefix, err := r.Peek()
 	if err != nil {
-		return nil, err
+		return nil, args, err
 	}
-	if val.Typ != "array" || len(val.Array) == 0 {
-		return nil, fmt.Errorf("expected array, got %s", val.Typ)
+	if prefix != '*' {
+		line, err := r.ReadLine()
+		if err != nil {
+			return nil, args, err
+		}
+		fields, err := resp.SplitArgs(line)
+		if err != nil {
+			return nil, args, &resp.ProtocolError{Reason: "unbalanced quotes in request"}
+		}
+		if len(fields) == 0 {
+			return nil, args, nil
+		}
+		return &Command{Name: strings.ToUpper(fields[0]), Args: fields[1:]}, args, nil
 	}
 
-	cmdName := strings.ToUpper(getString(val.Array[0]))
-	args := make([]string, len(val.Array)-1)
-	for i, v := range val.Array[1:] {
-		args[i] = getString(v)
+	args, err = r.ReadCommand(args)
+	if err != nil {
+		return nil, args, err
+	}
+	if len(args) == 0 {
+		return nil, args, nil
 	}
 
-	return &Command{Name: cmdName, Args: args}, nil
+	cmdName := strings.ToUpper(string(args[0]))
+	return &Command{Name: cmdName, Args: resp.CopyArgs(args[1:])}, args, nil
 }
 
 type Command struct {
 	Name string
 	Args []string
+	// DB is the database the connection has selected; dispatch fills it
+	// in before the handler runs.
+	DB int
 }
 
-func getString(v resp.Value) string {
-	if v.Typ == "bulk" {
-		return v.Bulk
-	}
-	return v.Str
-}
-
-func dispatchCommand(cmd *Command, conn net.Conn) resp.Value {
+func dispatchCommand(cmd *Command, c *client) resp.Value {
 	switch cmd.Name {
 	case string(pkg.PING_CMD):
 		return handlePing(cmd)
+	case string(pkg.TIME_CMD):
+		return handleTime(cmd)
+	case string(pkg.INFO_CMD):
+		return handleInfo(cmd)
+	case string(pkg.CONFIG_CMD):
+		return handleConfig(cmd)
+	case string(pkg.CLIENT_CMD):
+		return handleClient(cmd, c)
+	case string(pkg.SELECT_CMD):
+		return handleSelect(cmd, c)
+	case string(pkg.LATENCY_CMD):
+		return handleLatency(cmd)
 	case string(pkg.SET_CMD):
 		return handleSet(cmd)
+	case string(pkg.SETNX_CMD):
+		return handleSetNX(cmd)
+	case string(pkg.SETEX_CMD):
+		return handleSetEX(cmd, "EX")
+	case string(pkg.PSETEX_CMD):
+		return handleSetEX(cmd, "PX")
 	case string(pkg.GET_CMD):
 		return handleGet(cmd)
-	case string(pkg.DEL_CMD):
+	case string(pkg.GETSET_CMD):
+		return handleGetSet(cmd)
+	case string(pkg.GETDEL_CMD):
+		return handleGetDel(cmd)
+	case string(pkg.GETEX_CMD):
+		return handleGetEx(cmd)
+	case string(pkg.DEL_CMD), string(pkg.UNLINK_CMD):
 		return handleDel(cmd)
+	case string(pkg.EXISTS_CMD):
+		return handleExists(cmd, false)
+	case string(pkg.TOUCH_CMD):
+		return handleExists(cmd, true)
+	case string(pkg.COPY_CMD):
+		return handleCopy(cmd)
+	case string(pkg.MOVE_CMD):
+		return handleMove(cmd)
+	case string(pkg.TYPE_CMD):
+		return handleType(cmd)
+	case string(pkg.OBJECT_CMD):
+		return handleObject(cmd)
+	case string(pkg.DBSIZE_CMD):
+		return handleDBSize(cmd)
+	case string(pkg.RANDOMKEY_CMD):
+		return handleRandomKey(cmd)
+	case string(pkg.GETRANGE_CMD):
+		return handleGetRange(cmd)
+	case string(pkg.SETRANGE_CMD):
+		return handleSetRange(cmd)
+	case string(pkg.MGET_CMD):
+		return handleMGet(cmd)
+	case string(pkg.MSET_CMD):
+		return handleMSet(cmd, false)
+	case string(pkg.MSETNX_CMD):
+		return handleMSet(cmd, true)
+	case string(pkg.EXPIRE_CMD):
+		return handleExpire(cmd, time.Second)
+	case string(pkg.PEXPIRE_CMD):
+		return handleExpire(cmd, time.Millisecond)
+	case string(pkg.EXPIREAT_CMD):
+		return handleExpireAt(cmd, time.Second)
+	case string(pkg.PEXPIREAT_CMD):
+		return handleExpireAt(cmd, time.Millisecond)
+	case string(pkg.TTL_CMD):
+		return handleTTL(cmd, time.Second)
+	case string(pkg.PTTL_CMD):
+		return handleTTL(cmd, time.Millisecond)
+	case string(pkg.EXPIRETIME_CMD):
+		return handleExpireTime(cmd, time.Second)
+	case string(pkg.PEXPIRETIME_CMD):
+		return handleExpireTime(cmd, time.Millisecond)
+	case string(pkg.PERSIST_CMD):
+		return handlePersist(cmd)
+	case string(pkg.INCR_CMD):
+		return handleIncr(cmd, 1, false)
+	case string(pkg.DECR_CMD):
+		return handleIncr(cmd, -1, false)
+	case string(pkg.INCRBY_CMD):
+		return handleIncr(cmd, 1, true)
+	case string(pkg.DECRBY_CMD):
+		return handleIncr(cmd, -1, true)
+	case string(pkg.SCAN_CMD):
+		return handleScan(cmd)
 	case string(pkg.RPUSH_CMD):
-		return handleRPush(cmd)
+		return handlePush(cmd, keyStorage.RPush)
 	case string(pkg.RLEN_CMD):
 		return handleRLen(cmd)
+	case string(pkg.LPUSH_CMD):
+		return handlePush(cmd, keyStorage.LPush)
+	case string(pkg.LPUSHX_CMD):
+		return handlePush(cmd, keyStorage.LPushX)
+	case string(pkg.RPUSHX_CMD):
+		return handlePush(cmd, keyStorage.RPushX)
+	case string(pkg.LRANGE_CMD):
+		return handleRange(cmd, keyStorage.LRange)
 	case string(pkg.RRANGE_CMD):
-		return handleRRange(cmd)
+		return handleRange(cmd, keyStorage.RRange)
+	case string(pkg.LINDEX_CMD):
+		return handleLIndex(cmd)
+	case string(pkg.LSET_CMD):
+		return handleLSet(cmd)
+	case string(pkg.LINSERT_CMD):
+		return handleLInsert(cmd)
+	case string(pkg.LREM_CMD):
+		return handleLRem(cmd)
+	case string(pkg.LTRIM_CMD):
+		return handleLTrim(cmd)
 	case string(pkg.LPOP_CMD):
-		return handleLpop(cmd)
+		return handlePop(cmd, keyStorage.LPOP)
 	case string(pkg.RPOP_CMD):
-		return handleRpop(cmd)
+		return handlePop(cmd, keyStorage.RPOP)
+	case string(pkg.BLPOP_CMD):
+		return handleBlockingPop(cmd, c, keyStorage.BLPOP)
+	case string(pkg.BRPOP_CMD):
+		return handleBlockingPop(cmd, c, keyStorage.BRPOP)
+	case string(pkg.LMOVE_CMD), string(pkg.RPOPLPUSH_CMD):
+		return handleLMove(cmd)
+	case string(pkg.BLMOVE_CMD):
+		return handleBLMove(cmd, c)
+	case string(pkg.HSET_CMD):
+		return handleHSet(cmd)
+	case string(pkg.HSETNX_CMD):
+		return handleHSetNX(cmd)
+	case string(pkg.HGET_CMD):
+		return handleHGet(cmd)
+	case string(pkg.HDEL_CMD):
+		return handleHDel(cmd)
+	case string(pkg.HGETALL_CMD):
+		return handleHGetAll(cmd)
+	case string(pkg.HMGET_CMD):
+		return handleHMGet(cmd)
+	case string(pkg.HEXISTS_CMD):
+		return handleHExists(cmd)
+	case string(pkg.HLEN_CMD):
+		return handleHLen(cmd)
+	case string(pkg.HSTRLEN_CMD):
+		return handleHStrLen(cmd)
+	case string(pkg.HKEYS_CMD):
+		return handleHList(cmd, keyStorage.HKeys)
+	case string(pkg.HVALS_CMD):
+		return handleHList(cmd, keyStorage.HVals)
+	case string(pkg.HEXPIRE_CMD):
+		return handleHExpire(cmd, time.Second, false)
+	case string(pkg.HPEXPIRE_CMD):
+		return handleHExpire(cmd, time.Millisecond, false)
+	case string(pkg.HEXPIREAT_CMD):
+		return handleHExpire(cmd, time.Second, true)
+	case string(pkg.HPEXPIREAT_CMD):
+		return handleHExpire(cmd, time.Millisecond, true)
+	case string(pkg.HTTL_CMD):
+		return handleFieldExpiry(cmd, fieldTTL(time.Second))
+	case string(pkg.HPTTL_CMD):
+		return handleFieldExpiry(cmd, fieldTTL(time.Millisecond))
+	case string(pkg.HEXPIRETIME_CMD):
+		return handleFieldExpiry(cmd, fieldExpireTime(time.Second))
+	case string(pkg.HPEXPIRETIME_CMD):
+		return handleFieldExpiry(cmd, fieldExpireTime(time.Millisecond))
+	case string(pkg.HPERSIST_CMD):
+		return handleHPersist(cmd)
 
 	case string(pkg.MULTI_CMD):
-		return handleMulti(cmd, conn.RemoteAddr())
+		return handleMulti(cmd, c.conn.RemoteAddr())
 	case string(pkg.DISCARD_CMD):
-		return handleDiscard(cmd, conn.RemoteAddr())
+		return handleDiscard(cmd, c.conn.RemoteAddr())
 	case string(pkg.EXEC_CMD):
-		return handleExec(cmd, conn.RemoteAddr())
+		return handleExec(cmd, c.conn.RemoteAddr())
 	default:
-		return resp.Value{Typ: "error", Str: "ERR unknown command '" + cmd.Name + "'"}
+		return resp.ErrorValue(resp.UnknownCommand(cmd.Name))
 	}
 }
 
@@ -203,6 +448,7 @@ func handleExec(cmd *Command, remoteAddr net.Addr) resp.Value {
 		cmd := Command{
 			Name: command,
 			Args: strings.Split(command, " ")[1:],
+			DB:   cmd.DB,
 		}
 		fmt.Printf("cmd: %v\n", cmd)
 		resp := dispatchCommand(&cmd, nil)
@@ -211,144 +457,79 @@ func handleExec(cmd *Command, remoteAddr net.Addr) resp.Value {
 	return resp.Value{Str: "OK", Typ: "string"} // TODO: return failed if any command failed to execute
 }
 
-func handleLpop(cmd *Command) resp.Value {
-	if len(cmd.Args) < 1 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'LPOP' command"}
-	}
-	var err error
-	var count int
-	if len(cmd.Args) > 1 {
-		count, err = strconv.Atoi(cmd.Args[1])
-		if err != nil {
-			count = 0
-		}
-	} else {
-		count = 0
-	}
-	items, err := keyStorage.LPOP(cmd.Args[0], count, 0)
-	if err != nil {
-		return resp.Value{Typ: "null"}
-	}
-	arr := make([]resp.Value, len(items))
-	for _, item := range items {
-		arr = append(arr, resp.Value{Typ: "string", Str: item})
-	}
-	return resp.Value{Typ: "array", Array: arr}
-}
-func handleRpop(cmd *Command) resp.Value {
-	if len(cmd.Args) < 1 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'RPOP' command"}
-	}
-	var err error
-	var count int
-	if len(cmd.Args) > 1 {
-		count, err = strconv.Atoi(cmd.Args[1])
-		if err != nil {
-			count = 0
-		}
-	} else {
-		count = 0
-	}
-	items, err := keyStorage.RPOP(cmd.Args[0], count, 0)
-	if err != nil {
-		return resp.Value{Typ: "null"}
-	}
-	arr := make([]resp.Value, len(items))
-	for _, item := range items {
-		arr = append(arr, resp.Value{Typ: "string", Str: item})
-	}
-	return resp.Value{Typ: "array", Array: arr}
-}
-func handleRRange(cmd *Command) resp.Value {
-	if len(cmd.Args) < 3 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'RRANGE' command"}
-	}
-
-	items, err := keyStorage.RRange(cmd.Args[0], cmd.Args[1], cmd.Args[2], 0)
-	if err != nil {
-		return resp.Value{Typ: "null"}
-	}
-
-	return resp.Value{Typ: "string", Str: items}
-}
 func handlePing(cmd *Command) resp.Value {
 	if len(cmd.Args) == 0 {
 		return resp.Value{Typ: "string", Str: "PONG"}
 	}
 	return resp.Value{Typ: "bulk", Bulk: cmd.Args[0]}
 }
-func handleRPush(cmd *Command) resp.Value {
-	if len(cmd.Args) < 2 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'RPUSH' command"}
-	}
 
-	key := cmd.Args[0]
-	items := cmd.Args[1:]
-
-	length, err := keyStorage.RPush(key, items, 0)
-	if err != nil {
-		return resp.Value{Typ: "error", Str: "ERR " + err.Error()}
-	}
-
-	return resp.Value{Typ: "string", Str: strconv.Itoa(length)}
+// handleTime replies with the server's Unix time as seconds and the
+// microseconds into the current second, both as bulk strings.
+func handleTime(cmd *Command) resp.Value {
+	if len(cmd.Args) != 0 {
+		return resp.ErrorValue(resp.WrongArgs("TIME"))
+	}
+	now := time.Now()
+	return resp.Value{Typ: "array", Array: []resp.Value{
+		{Typ: "bulk", Bulk: strconv.FormatInt(now.Unix(), 10)},
+		{Typ: "bulk", Bulk: strconv.Itoa(now.Nanosecond() / 1000)},
+	}}
 }
-func handleRLen(cmd *Command) resp.Value {
-	if len(cmd.Args) != 1 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'RLEN' command"}
-	}
 
-	length, err := keyStorage.RLen(cmd.Args[0], 0)
-	if err != nil {
-		return resp.Value{Typ: "null"}
-	}
-	fmt.Printf("length: %v\n", length)
-	return resp.Value{Typ: "string", Str: strconv.Itoa(length)}
-}
 func handleSet(cmd *Command) resp.Value {
 	if len(cmd.Args) < 2 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'SET' command"}
+		return resp.ErrorValue(resp.WrongArgs("SET"))
 	}
 
-	key := cmd.Args[0]
-	value := cmd.Args[1]
-	expiry := time.Duration(0)
-
-	if len(cmd.Args) >= 3 {
-		if seconds, err := strconv.Atoi(cmd.Args[2]); err == nil {
-			expiry = time.Duration(seconds) * time.Second
-		}
+	opts, err := parseSetOptions(cmd.Args[2:])
+	if err != nil {
+		return resp.ErrorValue(err)
 	}
-
-	if err := keyStorage.Set(key, value, expiry, 0); err != nil {
-		return resp.Value{Typ: "error", Str: "ERR " + err.Error()}
+	res, err := keyStorage.SetWithOptions(cmd.Args[0], cmd.Args[1], opts, cmd.DB)
+	if err != nil {
+		return errorReply(err)
 	}
 
+	switch {
+	case opts.Get && !res.Existed:
+		return resp.Null
+	case opts.Get:
+		return resp.Value{Typ: "bulk", Bulk: res.Old}
+	case !res.Set:
+		return resp.Null
+	}
 	return resp.Value{Typ: "string", Str: "OK"}
 }
 
 func handleGet(cmd *Command) resp.Value {
 	if len(cmd.Args) != 1 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'GET' command"}
+		return resp.ErrorValue(resp.WrongArgs("GET"))
 	}
 
-	entry, err := keyStorage.Get(cmd.Args[0], 0)
+	entry, err := keyStorage.Get(cmd.Args[0], cmd.DB)
 	if err != nil {
-		return resp.Value{Typ: "error", Str: err.Error()}
+		return resp.ErrorValue(err)
 	}
 	if entry == nil {
 		return resp.Value{Typ: "null"}
 	}
+	if !entry.Value.IsString() {
+		return errorReply(storage.ErrWrongType)
+	}
 	return resp.Value{Typ: "bulk", Bulk: entry.Value.String}
 }
 
+// handleDel serves DEL and UNLINK. UNLINK exists in Redis so that freeing
+// a big value does not block the server; here removal only drops the
+// reference under the lock and the garbage collector reclaims the memory
+// concurrently, so both commands already behave like UNLINK.
 func handleDel(cmd *Command) resp.Value {
-	if len(cmd.Args) != 1 {
-		return resp.Value{Typ: "error", Str: "ERR wrong number of arguments for 'DEL' command"}
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
 	}
-
-	count := strconv.Itoa(keyStorage.Del(cmd.Args[0], 0))
-
-	return resp.Value{Typ: "bulk", Str: count}
+	n := keyStorage.Del(cmd.Args, cmd.DB)
+	return resp.Value{Typ: "integer", Num: int64(n)}
 }
 
 func isConnectionReset(err error) bool {
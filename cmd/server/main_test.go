@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+func TestMain(m *testing.M) {
+	keyStorage = storage.NewStorageWithConfig(storage.Config{Databases: 16})
+	queues = make(map[string][]string)
+	os.Exit(m.Run())
+}
+
+// startServer serves connections the way main does, on a random port,
+// until the test ends.
+func startServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		ln.Close()
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go handleConn(ctx, conn)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// testConn is a raw client connection to a test server.
+type testConn struct {
+	net.Conn
+	r *resp.Reader
+	w *resp.Writer
+}
+
+func dial(t *testing.T, addr string) *testConn {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return &testConn{Conn: c, r: resp.NewReader(c), w: resp.NewWriter(c)}
+}
+
+// do sends one command and waits up to five seconds for its reply.
+func (c *testConn) do(t *testing.T, args ...string) resp.Value {
+	t.Helper()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	defer c.SetDeadline(time.Time{})
+	c.w.WriteArrayHeader(len(args))
+	for _, a := range args {
+		c.w.WriteBulkString(a)
+	}
+	if err := c.w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.r.ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// logBuffer collects the server log; handlers write to it while the test
+// reads it.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// captureLog sends the log to a buffer until the test ends.
+func captureLog(t *testing.T) *logBuffer {
+	b := &logBuffer{}
+	log.SetOutput(b)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return b
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cronInterval is how often the server cron runs.
+	cronInterval = 100 * time.Millisecond
+	// fieldExpireBatch is how many hashes with field TTLs each cron run
+	// checks per database, the sample size of Redis's expire cycle.
+	fieldExpireBatch = 20
+	// metricSamples is how many samples the instantaneous rates average,
+	// so they cover the last 1.6s as in Redis.
+	metricSamples = 16
+)
+
+var (
+	totalCommands atomic.Int64
+	netInput      atomic.Int64
+	netOutput     atomic.Int64
+
+	metricsMu                      sync.Mutex
+	opsMeter, inputMeter, outMeter rateMeter
+)
+
+// rateMeter turns a growing counter into a per-second rate averaged over
+// the last metricSamples samples, and remembers the highest such rate.
+type rateMeter struct {
+	last    int64
+	lastAt  time.Time
+	samples [metricSamples]float64
+	next    int
+	peak    float64
+}
+
+func (m *rateMeter) sample(total int64, now time.Time) {
+	if !m.lastAt.IsZero() {
+		if elapsed := now.Sub(m.lastAt).Seconds(); elapsed > 0 {
+			m.samples[m.next] = float64(total-m.last) / elapsed
+			m.next = (m.next + 1) % metricSamples
+		}
+	}
+	m.last, m.lastAt = total, now
+	m.peak = max(m.peak, m.rate())
+}
+
+func (m *rateMeter) rate() float64 {
+	var sum float64
+	for _, s := range m.samples {
+		sum += s
+	}
+	return sum / metricSamples
+}
+
+// runCron samples the command and network counters and drops expired
+// hash fields until ctx is done.
+func runCron(ctx context.Context) {
+	t := time.NewTicker(cronInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			cronTick(now)
+		}
+	}
+}
+
+// cronTick is one run of the server cron.
+func cronTick(now time.Time) {
+	metricsMu.Lock()
+	opsMeter.sample(totalCommands.Load(), now)
+	inputMeter.sample(netInput.Load(), now)
+	outMeter.sample(netOutput.Load(), now)
+	metricsMu.Unlock()
+	keyStorage.ExpireFields(fieldExpireBatch)
+}
+
+// resetMetrics zeroes the counters and rates, for CONFIG RESETSTAT.
+func resetMetrics() {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	totalCommands.Store(0)
+	netInput.Store(0)
+	netOutput.Store(0)
+	opsMeter, inputMeter, outMeter = rateMeter{}, rateMeter{}, rateMeter{}
+}
+
+// meteredConn counts the bytes a client connection reads and writes.
+type meteredConn struct {
+	net.Conn
+}
+
+func (c meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	netInput.Add(int64(n))
+	return n, err
+}
+
+func (c meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	netOutput.Add(int64(n))
+	return n, err
+}
+
+func writeThroughputInfo(b *strings.Builder) {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	fmt.Fprintf(b, "total_commands_processed:%d\r\n", totalCommands.Load())
+	fmt.Fprintf(b, "instantaneous_ops_per_sec:%.0f\r\n", opsMeter.rate())
+	fmt.Fprintf(b, "total_net_input_bytes:%d\r\n", netInput.Load())
+	fmt.Fprintf(b, "total_net_output_bytes:%d\r\n", netOutput.Load())
+	fmt.Fprintf(b, "instantaneous_input_kbps:%.2f\r\n", inputMeter.rate()/1024)
+	fmt.Fprintf(b, "instantaneous_output_kbps:%.2f\r\n", outMeter.rate()/1024)
+	fmt.Fprintf(b, "peak_ops_per_sec:%.0f\r\n", opsMeter.peak)
+	fmt.Fprintf(b, "peak_input_kbps:%.2f\r\n", inputMeter.peak/1024)
+	fmt.Fprintf(b, "peak_output_kbps:%.2f\r\n", outMeter.peak/1024)
+}
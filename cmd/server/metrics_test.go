@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// infoFields runs INFO for one section and returns its fields by name.
+func infoFields(t *testing.T, section string) map[string]string {
+	t.Helper()
+	v := handleInfo(&Command{Name: "INFO", Args: []string{section}})
+	fields := make(map[string]string)
+	for _, line := range strings.Split(v.Bulk, "\r\n") {
+		if name, val, ok := strings.Cut(line, ":"); ok {
+			fields[name] = val
+		}
+	}
+	return fields
+}
+
+func TestInstantaneousRates(t *testing.T) {
+	c := dial(t, startServer(t))
+	c.do(t, "PING")
+	resetMetrics()
+	t.Cleanup(resetMetrics)
+
+	// Each cron interval sees 10 PINGs: 14 bytes in and 7 out apiece.
+	const perTick = 10
+	now := time.Now()
+	cronTick(now)
+	for tick := 1; tick <= metricSamples; tick++ {
+		for range perTick {
+			if v := c.do(t, "PING"); v.Str != "PONG" {
+				t.Fatalf("PING = %+v", v)
+			}
+		}
+		// The reply can reach the client before the server counts it.
+		for netOutput.Load() < int64(tick*perTick*7) {
+			time.Sleep(time.Millisecond)
+		}
+		now = now.Add(cronInterval)
+		cronTick(now)
+	}
+
+	f := infoFields(t, "stats")
+	want := map[string]string{
+		"total_commands_processed":  "160",
+		"instantaneous_ops_per_sec": "100",
+		"peak_ops_per_sec":          "100",
+		"total_net_input_bytes":     "2240",
+		"total_net_output_bytes":    "1120",
+		"instantaneous_input_kbps":  "1.37",
+		"instantaneous_output_kbps": "0.68",
+		"peak_input_kbps":           "1.37",
+	}
+	for name, val := range want {
+		if f[name] != val {
+			t.Errorf("%s = %q, want %q", name, f[name], val)
+		}
+	}
+
+	// Idle ticks pull the rates down but leave the peaks.
+	for range metricSamples / 2 {
+		now = now.Add(cronInterval)
+		cronTick(now)
+	}
+	f = infoFields(t, "stats")
+	if f["instantaneous_ops_per_sec"] != "50" || f["peak_ops_per_sec"] != "100" {
+		t.Errorf("after idle ticks ops/sec = %s, peak %s; want 50 and 100",
+			f["instantaneous_ops_per_sec"], f["peak_ops_per_sec"])
+	}
+}
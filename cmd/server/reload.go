@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"maps"
+	"sync"
+	"time"
+)
+
+var (
+	// configMu guards config and stopTracing, which change on reload.
+	configMu    sync.Mutex
+	config      *Config
+	stopTracing func()
+)
+
+// applyConfig makes the mutable settings of cfg take effect; on a reload
+// it logs the settings that only apply after a restart.
+func applyConfig(cfg *Config) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	if old := config; old != nil {
+		if cfg.Port != old.Port {
+			log.Printf("config reload: port changed, restart to apply it")
+		}
+		if cfg.AdminPort != old.AdminPort {
+			log.Printf("config reload: admin-port changed, restart to apply it")
+		}
+		if cfg.Pidfile != old.Pidfile {
+			log.Printf("config reload: pidfile changed, restart to apply it")
+		}
+		if cfg.Databases != old.Databases {
+			log.Printf("config reload: databases changed, restart to apply it")
+		}
+		if !maps.Equal(cfg.Renames.from, old.Renames.from) {
+			log.Printf("config reload: rename-command changed, restart to apply it")
+		}
+		cfg.Port, cfg.AdminPort, cfg.Databases, cfg.Renames = old.Port, old.AdminPort, old.Databases, old.Renames
+		cfg.Pidfile = old.Pidfile
+	}
+
+	if config == nil || cfg.OTLPEndpoint != config.OTLPEndpoint {
+		if stopTracing != nil {
+			stopTracing()
+			stopTracing = nil
+		}
+		if cfg.OTLPEndpoint != "" {
+			stop, err := startTracing(cfg.OTLPEndpoint)
+			if err != nil {
+				return err
+			}
+			stopTracing = stop
+		}
+	}
+	writeTimeout.Store(int64(time.Duration(cfg.WriteTimeout) * time.Second))
+	watchdogPeriod.Store(int64(time.Duration(cfg.WatchdogPeriod) * time.Millisecond))
+	if config == nil || cfg.HotKeys != config.HotKeys {
+		keyStorage.TrackHotKeys(cfg.HotKeys)
+	}
+	config = cfg
+	return nil
+}
+
+// reloadConfig reads the config file and command line again and applies
+// what changed, for SIGHUP and CONFIG RELOAD.
+func reloadConfig() error {
+	configMu.Lock()
+	cur := config
+	configMu.Unlock()
+	if cur.path == "" {
+		return errors.New("the server is running without a config file")
+	}
+	cfg, err := loadConfig(cur.args)
+	if err != nil {
+		return err
+	}
+	return applyConfig(cfg)
+}
+
+// shutdownConfig flushes what the settings started, such as queued spans.
+func shutdownConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	if stopTracing != nil {
+		stopTracing()
+		stopTracing = nil
+	}
+}
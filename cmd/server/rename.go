@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// commandRenames holds the rename-command directives. A renamed command
+// answers only to its new name, and a command renamed to "" is disabled.
+type commandRenames struct {
+	from map[string]string // original name -> new name, "" when disabled
+	to   map[string]string // new name -> original name
+}
+
+var renames commandRenames
+
+func (r *commandRenames) add(name, newName string) error {
+	name, newName = strings.ToUpper(name), strings.ToUpper(newName)
+	if r.from == nil {
+		r.from = make(map[string]string)
+		r.to = make(map[string]string)
+	}
+	if _, ok := r.from[name]; ok {
+		return fmt.Errorf("command '%s' is already renamed", name)
+	}
+	if _, ok := r.to[newName]; ok && newName != "" {
+		return fmt.Errorf("another command is already renamed to '%s'", newName)
+	}
+	r.from[name] = newName
+	if newName != "" {
+		r.to[newName] = name
+	}
+	return nil
+}
+
+// resolve maps the name a client sent to the command to run. ok is false
+// for the old name of a renamed command and for disabled commands.
+func (r *commandRenames) resolve(name string) (string, bool) {
+	if orig, ok := r.to[name]; ok {
+		return orig, true
+	}
+	if _, ok := r.from[name]; ok {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useRenames installs the rename-command directives of cfgText for the
+// rest of the test, the way main does at startup.
+func useRenames(t *testing.T, cfgText string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "redis.conf")
+	if err := os.WriteFile(path, []byte(cfgText), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configMu.Lock()
+	config = nil
+	configMu.Unlock()
+	if err := applyConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	renames = cfg.Renames
+	t.Cleanup(func() {
+		configMu.Lock()
+		config = nil
+		configMu.Unlock()
+		renames = commandRenames{}
+	})
+	return cfg
+}
+
+func TestRenameCommand(t *testing.T) {
+	useRenames(t, "rename-command GET FETCH\nrename-command flushall \"\"\n")
+	c := dial(t, startServer(t))
+
+	if v := c.do(t, "SET", "renamed", "v"); v.Str != "OK" {
+		t.Fatalf("SET = %+v", v)
+	}
+	if v := c.do(t, "fetch", "renamed"); v.Typ != "bulk" || v.Bulk != "v" {
+		t.Fatalf("FETCH = %+v, want the value GET returns", v)
+	}
+	for _, args := range [][]string{{"GET", "renamed"}, {"FLUSHALL"}, {"flushall", "SYNC"}} {
+		v := c.do(t, args...)
+		if want := "ERR unknown command '" + strings.ToUpper(args[0]) + "'"; v.Typ != "error" || v.Str != want {
+			t.Errorf("%s = %+v, want %q", args[0], v, want)
+		}
+	}
+	if v := c.do(t, "FETCH", "renamed"); v.Bulk != "v" {
+		t.Fatal("FLUSHALL should not have run")
+	}
+
+	// A command cannot be renamed twice, nor two commands to one name.
+	for _, text := range []string{
+		"rename-command GET A\nrename-command GET B\n",
+		"rename-command GET A\nrename-command SET A\n",
+	} {
+		path := filepath.Join(t.TempDir(), "redis.conf")
+		os.WriteFile(path, []byte(text), 0o644)
+		if _, err := loadConfig([]string{"-config", path}); err == nil {
+			t.Errorf("config %q was accepted", text)
+		}
+	}
+}
+
+func TestRenameCommandSurvivesReload(t *testing.T) {
+	cfg := useRenames(t, "rename-command GET FETCH\n")
+	logs := captureLog(t)
+
+	if err := os.WriteFile(cfg.path, []byte("hotkeys 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "rename-command changed, restart to apply it") {
+		t.Errorf("reload did not say the rename still holds: %q", logs.String())
+	}
+	if _, ok := config.Renames.from["GET"]; !ok {
+		t.Error("the reloaded config dropped the rename")
+	}
+
+	c := dial(t, startServer(t))
+	c.do(t, "SET", "reloaded", "v")
+	if v := c.do(t, "GET", "reloaded"); v.Typ != "error" {
+		t.Errorf("GET after reload = %+v, want it still renamed", v)
+	}
+	if v := c.do(t, "FETCH", "reloaded"); v.Bulk != "v" {
+		t.Errorf("FETCH after reload = %+v", v)
+	}
+}
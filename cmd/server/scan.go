@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+func handleScan(cmd *Command) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("SCAN"))
+	}
+	if len(cmd.Args)%2 != 1 {
+		return resp.ErrorValue(resp.ErrSyntax)
+	}
+	cursor, err := strconv.ParseUint(cmd.Args[0], 10, 64)
+	if err != nil {
+		return resp.ErrorValue(resp.NewError("ERR", "invalid cursor"))
+	}
+	var opts storage.ScanOptions
+	for i := 1; i < len(cmd.Args); i += 2 {
+		val := cmd.Args[i+1]
+		switch strings.ToUpper(cmd.Args[i]) {
+		case "MATCH":
+			if val != "*" {
+				opts.Match = val
+			}
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return resp.ErrorValue(resp.ErrNotInteger)
+			}
+			if n < 1 {
+				return resp.ErrorValue(resp.ErrSyntax)
+			}
+			opts.Count = n
+		case "TYPE":
+			opts.Type = strings.ToLower(val)
+		default:
+			return resp.ErrorValue(resp.ErrSyntax)
+		}
+	}
+
+	next, keys, err := keyStorage.Scan(cursor, opts, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	arr := make([]resp.Value, len(keys))
+	for i, k := range keys {
+		arr[i] = resp.Value{Typ: "bulk", Bulk: k}
+	}
+	return resp.Value{Typ: "array", Array: []resp.Value{
+		{Typ: "bulk", Bulk: strconv.FormatUint(next, 10)},
+		{Typ: "array", Array: arr},
+	}}
+}
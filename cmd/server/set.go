@@ -0,0 +1,110 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// parseSetOptions parses the modifiers after SET key value:
+// [NX|XX] [GET] [EX seconds|PX milliseconds|EXAT unix-seconds|PXAT unix-milliseconds|KEEPTTL].
+func parseSetOptions(args []string) (storage.SetOptions, error) {
+	var opts storage.SetOptions
+	expirySet := false
+	for i := 0; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX", "XX":
+			if opts.Cond != storage.SetAlways {
+				return opts, resp.ErrSyntax
+			}
+			opts.Cond = storage.SetIfAbsent
+			if opt == "XX" {
+				opts.Cond = storage.SetIfExists
+			}
+		case "GET":
+			opts.Get = true
+		case "KEEPTTL":
+			if expirySet {
+				return opts, resp.ErrSyntax
+			}
+			opts.KeepTTL, expirySet = true, true
+		case "EX", "PX", "EXAT", "PXAT":
+			if expirySet || i+1 == len(args) {
+				return opts, resp.ErrSyntax
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return opts, resp.ErrNotInteger
+			}
+			expiry, ok := setExpiry(opt, n)
+			if !ok {
+				return opts, invalidExpireTime("SET")
+			}
+			opts.Expiry, expirySet = expiry, true
+		default:
+			return opts, resp.ErrSyntax
+		}
+	}
+	return opts, nil
+}
+
+// setExpiry turns the argument of a SET expiry option into a time; it
+// must be positive and fit in milliseconds.
+func setExpiry(opt string, n int64) (time.Time, bool) {
+	unit := time.Millisecond
+	if opt == "EX" || opt == "EXAT" {
+		unit = time.Second
+	}
+	if n <= 0 || n > math.MaxInt64/int64(unit) {
+		return time.Time{}, false
+	}
+	if opt == "EXAT" || opt == "PXAT" {
+		return time.UnixMilli(n * int64(unit/time.Millisecond)), true
+	}
+	return time.Now().Add(time.Duration(n) * unit), true
+}
+
+func handleSetNX(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("SETNX"))
+	}
+	res, err := keyStorage.SetWithOptions(cmd.Args[0], cmd.Args[1], storage.SetOptions{Cond: storage.SetIfAbsent}, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return boolReply(res.Set)
+}
+
+// handleSetEX serves SETEX and PSETEX, which are SET with EX or PX; opt
+// names the option.
+func handleSetEX(cmd *Command, opt string) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	n, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	expiry, ok := setExpiry(opt, n)
+	if !ok {
+		return resp.ErrorValue(invalidExpireTime(cmd.Name))
+	}
+	if _, err := keyStorage.SetWithOptions(cmd.Args[0], cmd.Args[2], storage.SetOptions{Expiry: expiry}, cmd.DB); err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "string", Str: "OK"}
+}
+
+// handleGetSet serves GETSET, which is SET key value GET.
+func handleGetSet(cmd *Command) resp.Value {
+	if len(cmd.Args) != 2 {
+		return resp.ErrorValue(resp.WrongArgs("GETSET"))
+	}
+	res, err := keyStorage.SetWithOptions(cmd.Args[0], cmd.Args[1], storage.SetOptions{Get: true}, cmd.DB)
+	return stringReply(res.Old, res.Existed, err)
+}
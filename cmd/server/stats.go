@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/tracing"
+)
+
+// commandStat is one command's line in INFO commandstats.
+type commandStat struct {
+	calls   int64
+	failed  int64 // calls that replied with an error
+	usec    int64
+	usecMin int64
+	usecMax int64
+	// hist counts calls by latency; see latencyBucket.
+	hist [latencyBuckets]int64
+}
+
+// commandStats counts calls, time and errors per command name. Unknown
+// commands are not recorded.
+type commandStats struct {
+	mu    sync.Mutex
+	stats map[string]*commandStat
+}
+
+var cmdStats = &commandStats{stats: make(map[string]*commandStat)}
+
+func (s *commandStats) record(name string, d time.Duration, failed bool) {
+	usec := d.Microseconds()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[name]
+	if !ok {
+		st = &commandStat{usecMin: usec}
+		s.stats[name] = st
+	}
+	st.calls++
+	st.usec += usec
+	st.usecMin = min(st.usecMin, usec)
+	st.usecMax = max(st.usecMax, usec)
+	st.hist[latencyBucket(usec)]++
+	if failed {
+		st.failed++
+	}
+}
+
+// reset forgets every count, for CONFIG RESETSTAT.
+func (s *commandStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.stats)
+}
+
+// dispatch resolves renamed commands, runs the command and records it in
+// the command statistics and, when tracing is on, in a span.
+func dispatch(cmd *Command, c *client) resp.Value {
+	name, ok := renames.resolve(cmd.Name)
+	if !ok {
+		return resp.ErrorValue(resp.UnknownCommand(cmd.Name))
+	}
+	cmd.Name = name
+	cmd.DB = c.db
+	if c.noTouch {
+		cmd.DB = storage.NoTouch(c.db)
+	}
+	totalCommands.Add(1)
+	_, span := tracer.Load().Start(context.Background(), cmd.Name, tracing.KindServer,
+		tracing.String("db.system", "redis"),
+		tracing.String("db.operation", cmd.Name),
+		tracing.Int("db.redis.database_index", c.db),
+		tracing.Int("db.redis.key_count", keyCount(cmd)))
+	start := time.Now()
+	stopWatch := watch(cmd, c)
+	reply := dispatchCommand(cmd, c)
+	stopWatch()
+	if !isUnknownCommand(reply, cmd.Name) {
+		cmdStats.record(cmd.Name, time.Since(start), reply.Typ == "error")
+	}
+	if reply.Typ == "error" {
+		span.SetError(reply.Err())
+	}
+	span.Finish()
+	return reply
+}
+
+// isUnknownCommand reports whether reply is resp.UnknownCommand(name),
+// without building that error on every call.
+func isUnknownCommand(reply resp.Value, name string) bool {
+	const prefix = "ERR unknown command '"
+	s := reply.Str
+	return reply.Typ == "error" && len(s) == len(prefix)+len(name)+1 &&
+		s[:len(prefix)] == prefix && s[len(prefix):len(s)-1] == name && s[len(s)-1] == '\''
+}
+
+func writeCommandstatsInfo(b *strings.Builder) {
+	cmdStats.mu.Lock()
+	defer cmdStats.mu.Unlock()
+	names := make([]string, 0, len(cmdStats.stats))
+	for name := range cmdStats.stats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		st := cmdStats.stats[name]
+		fmt.Fprintf(b, "cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f,usec_min=%d,usec_max=%d,failed_calls=%d\r\n",
+			strings.ToLower(name), st.calls, st.usec, float64(st.usec)/float64(st.calls), st.usecMin, st.usecMax, st.failed)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+func TestIsUnknownCommand(t *testing.T) {
+	for _, tc := range []struct {
+		reply resp.Value
+		name  string
+		want  bool
+	}{
+		{resp.ErrorValue(resp.UnknownCommand("FOO")), "FOO", true},
+		{resp.ErrorValue(resp.UnknownCommand("FOO")), "FO", false},
+		{resp.ErrorValue(resp.UnknownCommand("FOOO")), "FOO", false},
+		{resp.ErrorValue(resp.UnknownCommand("")), "", true},
+		{resp.Value{Typ: "string", Str: "ERR unknown command 'FOO'"}, "FOO", false},
+		{resp.ErrorValue(resp.WrongArgs("FOO")), "FOO", false},
+		{resp.ErrorValue(resp.NewError("ERR", "unknown subcommand 'FOO'")), "FOO", false},
+	} {
+		if got := isUnknownCommand(tc.reply, tc.name); got != tc.want {
+			t.Errorf("isUnknownCommand(%q, %q) = %v, want %v", tc.reply.Str, tc.name, got, tc.want)
+		}
+	}
+
+	reply := resp.Value{Typ: "string", Str: "OK"}
+	if n := testing.AllocsPerRun(100, func() { isUnknownCommand(reply, "GET") }); n != 0 {
+		t.Errorf("isUnknownCommand allocates %v times per call", n)
+	}
+}
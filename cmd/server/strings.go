@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jafari-mohammad-reza/redis-clone/internal/storage"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+func handleGetRange(cmd *Command) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs("GETRANGE"))
+	}
+	start, err1 := strconv.ParseInt(cmd.Args[1], 10, 64)
+	end, err2 := strconv.ParseInt(cmd.Args[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	s, err := keyStorage.GetRange(cmd.Args[0], start, end, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "bulk", Bulk: s}
+}
+
+func handleSetRange(cmd *Command) resp.Value {
+	if len(cmd.Args) != 3 {
+		return resp.ErrorValue(resp.WrongArgs("SETRANGE"))
+	}
+	offset, err := strconv.ParseInt(cmd.Args[1], 10, 64)
+	if err != nil {
+		return resp.ErrorValue(resp.ErrNotInteger)
+	}
+	if offset < 0 {
+		return resp.ErrorValue(resp.NewError("ERR", "offset is out of range"))
+	}
+	if offset > storage.MaxStringLen {
+		return errorReply(storage.ErrStringTooLong)
+	}
+	n, err := keyStorage.SetRange(cmd.Args[0], offset, cmd.Args[2], cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "integer", Num: int64(n)}
+}
+
+func handleMGet(cmd *Command) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("MGET"))
+	}
+	vals, err := keyStorage.MGet(cmd.Args, cmd.DB)
+	if err != nil {
+		return errorReply(err)
+	}
+	return nullableArray(vals)
+}
+
+// nullableArray replies with vals as an array of bulk strings, nil
+// entries as nulls.
+func nullableArray(vals []*string) resp.Value {
+	arr := make([]resp.Value, len(vals))
+	for i, v := range vals {
+		if v == nil {
+			arr[i] = resp.Null
+		} else {
+			arr[i] = resp.Value{Typ: "bulk", Bulk: *v}
+		}
+	}
+	return resp.Value{Typ: "array", Array: arr}
+}
+
+// handleMSet serves MSET and, with nx, MSETNX.
+func handleMSet(cmd *Command, nx bool) resp.Value {
+	if len(cmd.Args) < 2 || len(cmd.Args)%2 != 0 {
+		return resp.ErrorValue(resp.WrongArgs(cmd.Name))
+	}
+	pairs := make([][2]string, 0, len(cmd.Args)/2)
+	for i := 0; i < len(cmd.Args); i += 2 {
+		pairs = append(pairs, [2]string{cmd.Args[i], cmd.Args[i+1]})
+	}
+	if nx {
+		ok, err := keyStorage.MSetNX(pairs, cmd.DB)
+		if err != nil {
+			return errorReply(err)
+		}
+		return boolReply(ok)
+	}
+	if err := keyStorage.MSet(pairs, cmd.DB); err != nil {
+		return errorReply(err)
+	}
+	return resp.Value{Typ: "string", Str: "OK"}
+}
+
+func handleGetDel(cmd *Command) resp.Value {
+	if len(cmd.Args) != 1 {
+		return resp.ErrorValue(resp.WrongArgs("GETDEL"))
+	}
+	return stringReply(keyStorage.GetDel(cmd.Args[0], cmd.DB))
+}
+
+// handleGetEx serves GETEX key [EX seconds|PX milliseconds|EXAT
+// unix-seconds|PXAT unix-milliseconds|PERSIST].
+func handleGetEx(cmd *Command) resp.Value {
+	if len(cmd.Args) < 1 {
+		return resp.ErrorValue(resp.WrongArgs("GETEX"))
+	}
+	var opts storage.GetExOptions
+	args := cmd.Args[1:]
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && strings.EqualFold(args[0], "PERSIST"):
+		opts.Persist = true
+	case len(args) == 2:
+		opt := strings.ToUpper(args[0])
+		if opt != "EX" && opt != "PX" && opt != "EXAT" && opt != "PXAT" {
+			return resp.ErrorValue(resp.ErrSyntax)
+		}
+		n, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return resp.ErrorValue(resp.ErrNotInteger)
+		}
+		expiry, ok := setExpiry(opt, n)
+		if !ok {
+			return resp.ErrorValue(invalidExpireTime("GETEX"))
+		}
+		opts.Expiry = expiry
+	default:
+		return resp.ErrorValue(resp.ErrSyntax)
+	}
+	return stringReply(keyStorage.GetEx(cmd.Args[0], opts, cmd.DB))
+}
+
+// stringReply is the reply to a command that reads one string: the value,
+// or nil when the key did not exist.
+func stringReply(val string, ok bool, err error) resp.Value {
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case !ok:
+		return resp.Null
+	}
+	return resp.Value{Typ: "bulk", Bulk: val}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSystemdListenerEnv(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	for _, tc := range []struct {
+		name, pid, fds string
+		wantErr        string
+	}{
+		{name: "not activated"},
+		{name: "for another process", pid: "1", fds: "1"},
+		{name: "no pid", fds: "1"},
+		{name: "not a number", pid: pid, fds: "one", wantErr: `invalid LISTEN_FDS "one"`},
+		{name: "no sockets", pid: pid, fds: "0", wantErr: `invalid LISTEN_FDS "0"`},
+		{name: "two sockets", pid: pid, fds: "2", wantErr: "got 2 sockets from systemd, want one"},
+	} {
+		t.Setenv("LISTEN_PID", tc.pid)
+		t.Setenv("LISTEN_FDS", tc.fds)
+		t.Setenv("LISTEN_FDNAMES", "resp")
+		ln, err := systemdListener()
+		if ln != nil {
+			ln.Close()
+			t.Errorf("%s: got a listener", tc.name)
+		}
+		if tc.wantErr == "" && err != nil || tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr)
+		}
+		for _, v := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"} {
+			if _, ok := os.LookupEnv(v); ok {
+				t.Errorf("%s: %s was left set", tc.name, v)
+			}
+		}
+	}
+}
+
+// TestSystemdListener hands a socket to a child test process the way
+// systemd does: as descriptor 3, with LISTEN_PID naming the child.
+func TestSystemdListener(t *testing.T) {
+	if addr := os.Getenv("TEST_SYSTEMD_ADDR"); addr != "" {
+		ln, err := systemdListener()
+		if err != nil || ln == nil {
+			t.Fatalf("systemdListener() = %v, %v", ln, err)
+		}
+		defer ln.Close()
+		if ln.Addr().String() != addr {
+			t.Fatalf("got the socket on %s, want %s", ln.Addr(), addr)
+		}
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Write([]byte("hello"))
+		c.Close()
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command("/bin/sh", "-c", `LISTEN_PID=$$ exec "$0" "$@"`, os.Args[0], "-test.run=^TestSystemdListener$", "-test.v")
+	cmd.Env = append(os.Environ(), "LISTEN_FDS=1", "TEST_SYSTEMD_ADDR="+ln.Addr().String())
+	cmd.ExtraFiles = []*os.File{f}
+	var out strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	buf := make([]byte, 5)
+	if _, err := c.Read(buf); err != nil || string(buf) != "hello" {
+		t.Errorf("read %q, %v from the child's listener", buf, err)
+	}
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("child: %v\n%s", err, out.String())
+	}
+}
+
+func TestPidfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.pid")
+	remove, err := writePidfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strconv.Itoa(os.Getpid()) + "\n"; string(b) != want {
+		t.Fatalf("pidfile holds %q, want %q", b, want)
+	}
+	remove()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("pidfile still there after remove: %v", err)
+	}
+
+	if _, err := writePidfile(filepath.Join(t.TempDir(), "missing", "server.pid")); err == nil {
+		t.Fatal("expected an error for a pidfile in a missing directory")
+	}
+}
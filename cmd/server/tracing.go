@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/tracing"
+)
+
+// tracer records a server span per command when otlp-endpoint is set; it
+// holds nil, and tracing is a no-op, otherwise. CONFIG RELOAD can swap it
+// while commands run.
+var tracer atomic.Pointer[tracing.Tracer]
+
+// startTracing points the tracer at an OTLP collector and returns a
+// function that turns tracing off and flushes the spans still queued.
+func startTracing(endpoint string) (stop func(), err error) {
+	exp, err := tracing.NewOTLPExporter(tracing.OTLPOptions{
+		Endpoint:    endpoint,
+		ServiceName: "redis-clone",
+		OnError: func(err error) {
+			log.Printf("trace export: %v", err)
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	t := tracing.NewTracer(exp)
+	tracer.Store(t)
+	return func() {
+		tracer.CompareAndSwap(t, nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := exp.Close(ctx); err != nil {
+			log.Printf("trace export: %v", err)
+		}
+	}, nil
+}
+
+// keyCount is how many keys a command names, for the db.redis.key_count
+// span attribute.
+func keyCount(cmd *Command) int {
+	switch cmd.Name {
+	case string(pkg.PING_CMD), string(pkg.INFO_CMD), string(pkg.CONFIG_CMD), string(pkg.CLIENT_CMD),
+		string(pkg.MULTI_CMD), string(pkg.EXEC_CMD), string(pkg.DISCARD_CMD), string(pkg.SCAN_CMD),
+		string(pkg.SELECT_CMD), string(pkg.LATENCY_CMD):
+		return 0
+	case string(pkg.DEL_CMD), string(pkg.UNLINK_CMD), string(pkg.EXISTS_CMD), string(pkg.TOUCH_CMD), string(pkg.MGET_CMD):
+		return len(cmd.Args)
+	case string(pkg.MSET_CMD), string(pkg.MSETNX_CMD):
+		return len(cmd.Args) / 2
+	case string(pkg.BLPOP_CMD), string(pkg.BRPOP_CMD):
+		return max(len(cmd.Args)-1, 0)
+	case string(pkg.COPY_CMD), string(pkg.LMOVE_CMD), string(pkg.RPOPLPUSH_CMD), string(pkg.BLMOVE_CMD):
+		return min(len(cmd.Args), 2)
+	default:
+		return min(len(cmd.Args), 1)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg"
+)
+
+// watchdogPeriod is how long a command may run before the watchdog logs
+// it, from the watchdog-period setting; 0 turns the watchdog off. It is
+// read on every command, so it lives outside config.
+var watchdogPeriod atomic.Int64
+
+// maxWatchdogStack caps the goroutine dump logged for one slow command.
+const maxWatchdogStack = 256 << 10
+
+// watch arms the watchdog for one command and returns the function that
+// disarms it once the command is done. If the command is still running
+// when the period passes, a warning is logged naming it and the client,
+// with a dump of every goroutine's stack taken while it is stuck.
+// Blocking commands wait by design and are not watched.
+func watch(cmd *Command, c *client) (stop func() bool) {
+	period := time.Duration(watchdogPeriod.Load())
+	if period <= 0 || blocking(cmd.Name) {
+		return func() bool { return false }
+	}
+	start := time.Now()
+	t := time.AfterFunc(period, func() {
+		addr := "unknown"
+		if c != nil && c.conn != nil {
+			addr = c.conn.RemoteAddr().String()
+		}
+		buf := make([]byte, maxWatchdogStack)
+		buf = buf[:runtime.Stack(buf, true)]
+		log.Printf("watchdog: %s from %s still running after %s\n--- goroutines ---\n%s",
+			describeCommand(cmd), addr, time.Since(start).Round(time.Millisecond), buf)
+	})
+	return t.Stop
+}
+
+// describeCommand renders a command for the log with its arguments
+// quoted, eliding long arguments and argument lists.
+func describeCommand(cmd *Command) string {
+	const maxArgs, maxArgLen = 32, 128
+	var b strings.Builder
+	b.WriteString(cmd.Name)
+	for i, arg := range cmd.Args {
+		if i == maxArgs-1 && len(cmd.Args) > maxArgs {
+			b.WriteString(" ... (" + strconv.Itoa(len(cmd.Args)-i) + " more arguments)")
+			break
+		}
+		b.WriteString(" ")
+		if len(arg) > maxArgLen {
+			b.WriteString(strconv.Quote(arg[:maxArgLen]) + "... (" + strconv.Itoa(len(arg)-maxArgLen) + " more bytes)")
+		} else {
+			b.WriteString(strconv.Quote(arg))
+		}
+	}
+	return b.String()
+}
+
+// blocking reports whether name is a command that may wait for data.
+func blocking(name string) bool {
+	switch name {
+	case string(pkg.BLPOP_CMD), string(pkg.BRPOP_CMD), string(pkg.BLMOVE_CMD):
+		return true
+	}
+	return false
+}
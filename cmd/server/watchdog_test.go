@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowHandler stands in for a command handler stuck for d.
+func slowHandler(d time.Duration) {
+	time.Sleep(d)
+}
+
+func TestWatchdog(t *testing.T) {
+	logs := captureLog(t)
+	watchdogPeriod.Store(int64(20 * time.Millisecond))
+	t.Cleanup(func() { watchdogPeriod.Store(0) })
+	server, peer := net.Pipe()
+	defer server.Close()
+	defer peer.Close()
+	c := newClient(t.Context(), server, nil)
+
+	stop := watch(&Command{Name: "GET", Args: []string{"slow", strings.Repeat("x", 200)}}, c)
+	slowHandler(100 * time.Millisecond)
+	if stop() {
+		t.Fatal("the watchdog should have fired before the command finished")
+	}
+	out := logs.String()
+	for _, want := range []string{
+		`watchdog: GET "slow" "` + strings.Repeat("x", 128) + `"... (72 more bytes) from pipe still running after`,
+		"--- goroutines ---",
+		"slowHandler",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log is missing %q:\n%.2000s", want, out)
+		}
+	}
+
+	// Fast commands and blocking ones are not logged.
+	n := len(logs.String())
+	stop = watch(&Command{Name: "GET", Args: []string{"fast"}}, c)
+	if !stop() {
+		t.Error("a fast command should disarm the watchdog")
+	}
+	stop = watch(&Command{Name: "BLPOP", Args: []string{"list", "0"}}, c)
+	slowHandler(50 * time.Millisecond)
+	stop()
+	watchdogPeriod.Store(0)
+	stop = watch(&Command{Name: "GET", Args: []string{"off"}}, c)
+	slowHandler(50 * time.Millisecond)
+	stop()
+	if got := logs.String()[n:]; got != "" {
+		t.Errorf("unexpected log output: %s", got)
+	}
+}
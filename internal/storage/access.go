@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// LFU counter tuning, with the defaults of Redis: a new key starts at
+// lfuInitVal, each access raises the counter with a probability that
+// shrinks as it grows (scaled by lfuLogFactor), and it drops by one for
+// every lfuDecayTime without an access.
+const (
+	lfuInitVal   = 5
+	lfuLogFactor = 10
+	lfuDecayTime = time.Minute
+)
+
+// access is the per-key metadata OBJECT IDLETIME and OBJECT FREQ report.
+// Reads update it while holding d.mu only for reading, so its fields are
+// atomic; entries share it by pointer across the copies Get hands out.
+type access struct {
+	last atomic.Int64 // unix nanoseconds of the last access
+	freq atomic.Uint32
+}
+
+func newAccess() *access {
+	a := &access{}
+	a.last.Store(time.Now().UnixNano())
+	a.freq.Store(lfuInitVal)
+	return a
+}
+
+// touch records an access now. Races between concurrent readers may lose
+// an increment, which the counter's approximate nature tolerates.
+func (a *access) touch() {
+	now := time.Now()
+	counter := a.decayed(now)
+	if counter < 255 {
+		base := max(float64(counter)-lfuInitVal, 0)
+		if rand.Float64() < 1/(base*lfuLogFactor+1) {
+			counter++
+		}
+	}
+	a.freq.Store(counter)
+	a.last.Store(now.UnixNano())
+}
+
+// decayed returns the LFU counter less one for every lfuDecayTime since
+// the last access.
+func (a *access) decayed(now time.Time) uint32 {
+	counter := a.freq.Load()
+	periods := now.Sub(time.Unix(0, a.last.Load())) / lfuDecayTime
+	if int64(periods) >= int64(counter) {
+		return 0
+	}
+	return counter - uint32(periods)
+}
+
+// read records a lookup made on behalf of a read command in the hit and
+// miss counters and, when the key was found, in its access metadata
+// (unless d is a NoTouch handle) and the hot-key tracker.
+func (d *Database) read(key string, e Entry, found bool) {
+	d.counters.read(found)
+	if found {
+		if e.access != nil && !d.noTouch {
+			e.access.touch()
+		}
+		d.hot.record(d.index, key)
+	}
+}
+
+// ObjectInfo is what OBJECT reports about a key.
+type ObjectInfo struct {
+	Encoding string
+	Idle     time.Duration // since the key was last read or written
+	Freq     uint8         // logarithmic LFU counter
+}
+
+// Encoding names how v is stored, using the names Redis reports for the
+// closest encoding: int or raw for strings, quicklist for lists,
+// hashtable for hashes and stream for streams.
+func (v Value) Encoding() string {
+	switch v.Type {
+	case TypeInt:
+		return "int"
+	case TypeString:
+		return "raw"
+	case TypeList:
+		return "quicklist"
+	case TypeHash:
+		return "hashtable"
+	case TypeStream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
+// Object returns the encoding and access metadata of key without counting
+// as an access. ok is false when the key does not exist.
+func (d *Database) Object(key string) (info ObjectInfo, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.peek(key)
+	if !ok {
+		return info, false
+	}
+	info.Encoding = entry.Value.Encoding()
+	if a := entry.access; a != nil {
+		now := time.Now()
+		info.Idle = max(now.Sub(time.Unix(0, a.last.Load())), 0)
+		info.Freq = uint8(min(a.decayed(now), 255))
+	}
+	return info, true
+}
+
+func (s *Storage) Object(key string, db int) (ObjectInfo, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return ObjectInfo{}, false, err
+	}
+	info, ok := d.Object(key)
+	return info, ok, nil
+}
@@ -0,0 +1,114 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// blockedClients is a Database's registry of callers blocked until some
+// key is written, kept per key in the order they started waiting. A write
+// wakes the longest waiting caller that is not already awake, so waiters
+// are served FIFO and nobody polls.
+type blockedClients struct {
+	mu    sync.Mutex
+	byKey map[string][]*waiter
+}
+
+// waiter is one blocked caller. ready holds at most one pending wakeup, so
+// signalling never blocks the writer.
+type waiter struct {
+	ready chan struct{}
+}
+
+func (b *blockedClients) add(w *waiter, keys []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.byKey == nil {
+		b.byKey = make(map[string][]*waiter)
+	}
+	for _, key := range keys {
+		b.byKey[key] = append(b.byKey[key], w)
+	}
+}
+
+func (b *blockedClients) remove(w *waiter, keys []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, key := range keys {
+		queue := b.byKey[key]
+		for i, other := range queue {
+			if other == w {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+		if len(queue) == 0 {
+			delete(b.byKey, key)
+		} else {
+			b.byKey[key] = queue
+		}
+	}
+}
+
+// signal wakes the first waiter on key that has no wakeup pending. It is
+// called by put with d.mu held, so it only takes the registry's own lock.
+func (b *blockedClients) signal(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, w := range b.byKey[key] {
+		select {
+		case w.ready <- struct{}{}:
+			return
+		default:
+		}
+	}
+}
+
+// block calls try until it reports success or fails, sleeping between
+// attempts until one of keys is written. It gives up with a nil error once
+// timeout passes (0 waits forever) and with ctx.Err() as soon as ctx is
+// done. try takes d.mu itself; block must be called without it.
+func (d *Database) block(ctx context.Context, keys []string, timeout time.Duration, try func() (bool, error)) error {
+	w := &waiter{ready: make(chan struct{}, 1)}
+	// Registering before the first attempt means a write landing between
+	// an attempt and the wait below still leaves a wakeup behind.
+	d.blocked.add(w, keys)
+	pass := false
+	defer func() {
+		d.blocked.remove(w, keys)
+		// Whatever is left after being passed on, or a wakeup that arrived
+		// too late to use, belongs to the next waiter in line.
+		select {
+		case <-w.ready:
+			pass = true
+		default:
+		}
+		if pass {
+			for _, key := range keys {
+				d.blocked.signal(key)
+			}
+		}
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		expired = t.C
+	}
+	for {
+		done, err := try()
+		if err != nil || done {
+			pass = done
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-expired:
+			return nil
+		case <-w.ready:
+		}
+	}
+}
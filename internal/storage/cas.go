@@ -0,0 +1,72 @@
+package storage
+
+import "time"
+
+// Version returns the version of key, or 0 when it does not exist. Versions
+// come from a per-database counter, so a key that is deleted and recreated
+// never reuses an old version. A key that is created and deleted again while
+// being watched reads as 0 both times, as it would if nothing had happened.
+func (d *Database) Version(key string) uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.lookup(key)
+	if !ok {
+		return 0
+	}
+	return entry.Version
+}
+
+// CompareAndSet stores val under key only if the key is still at version,
+// where 0 means the key must not exist. It reports whether the write happened
+// and returns the key's version afterwards.
+func (d *Database) CompareAndSet(key, val string, exp time.Duration, version uint64) (bool, uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var current uint64
+	if entry, ok := d.lookup(key); ok {
+		current = entry.Version
+	}
+	if current != version {
+		return false, current
+	}
+
+	value := stringValue(val)
+	if exp > 0 {
+		value.Expiry = time.Now().Add(exp)
+	}
+	d.put(key, Entry{Value: value})
+	return true, d.version
+}
+
+// SetIfAbsent stores val only when key does not exist and reports whether it did.
+func (d *Database) SetIfAbsent(key, val string, exp time.Duration) bool {
+	ok, _ := d.CompareAndSet(key, val, exp, 0)
+	return ok
+}
+
+func (s *Storage) Version(key string, db int) (uint64, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.Version(key), nil
+}
+
+func (s *Storage) CompareAndSet(key, val string, exp time.Duration, version uint64, db int) (bool, uint64, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, 0, err
+	}
+	ok, current := d.CompareAndSet(key, val, exp, version)
+	return ok, current, nil
+}
+
+func (s *Storage) SetIfAbsent(key, val string, exp time.Duration, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.SetIfAbsent(key, val, exp), nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import "sync/atomic"
+
+// Counters are the keyspace statistics INFO stats reports, summed over
+// every database since start or the last ResetCounters.
+type Counters struct {
+	Hits    int64 // reads that found the key
+	Misses  int64 // reads that did not, including keys found expired
+	Expired int64 // keys removed because their TTL passed
+	Evicted int64 // keys removed to free memory; zero until there is a maxmemory policy
+}
+
+type counters struct {
+	hits, misses     atomic.Int64
+	expired, evicted atomic.Int64
+}
+
+// read records the outcome of a key lookup made on behalf of a read
+// command; writes are not counted.
+func (c *counters) read(found bool) {
+	if found {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+func (c *counters) reset() {
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.expired.Store(0)
+	c.evicted.Store(0)
+}
+
+// Counters returns the hit, miss, expiry and eviction counts.
+func (s *Storage) Counters() Counters {
+	var total Counters
+	for _, d := range s.databases {
+		total.Hits += d.counters.hits.Load()
+		total.Misses += d.counters.misses.Load()
+		total.Expired += d.counters.expired.Load()
+		total.Evicted += d.counters.evicted.Load()
+	}
+	return total
+}
+
+// ResetCounters zeroes the counters, for CONFIG RESETSTAT.
+func (s *Storage) ResetCounters() {
+	for _, d := range s.databases {
+		d.counters.reset()
+	}
+}
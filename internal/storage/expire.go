@@ -0,0 +1,116 @@
+package storage
+
+import "time"
+
+// Expire sets key to expire after ttl and reports whether the key exists.
+// A ttl of zero or less deletes the key right away, as Redis does.
+func (d *Database) Expire(key string, ttl time.Duration) bool {
+	return d.SetExpiryAt(key, time.Now().Add(ttl))
+}
+
+// SetExpiryAt sets key to expire at the wall clock time at and reports
+// whether the key exists. A time that is not in the future deletes the key
+// right away.
+func (d *Database) SetExpiryAt(key string, at time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.lookup(key)
+	if !ok {
+		return false
+	}
+	if !at.After(time.Now()) {
+		d.remove(key)
+		return true
+	}
+	entry.Value.Expiry = at
+	d.put(key, entry)
+	return true
+}
+
+// ExpiryAt returns when key expires. ok is false when the key does not
+// exist, and at is the zero time when it exists but never expires.
+func (d *Database) ExpiryAt(key string) (at time.Time, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.peek(key)
+	d.read(key, entry, ok)
+	if !ok {
+		return time.Time{}, false
+	}
+	return entry.Value.Expiry, true
+}
+
+// TTL returns how long key has left to live. ok is false when the key does
+// not exist, and ttl is negative when it exists but never expires.
+func (d *Database) TTL(key string) (ttl time.Duration, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok := d.peek(key)
+	d.read(key, entry, ok)
+	if !ok {
+		return 0, false
+	}
+	if entry.Value.Expiry.IsZero() {
+		return -1, true
+	}
+	return max(time.Until(entry.Value.Expiry), 0), true
+}
+
+// Persist removes the expiry of key and reports whether it had one.
+func (d *Database) Persist(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.lookup(key)
+	if !ok || entry.Value.Expiry.IsZero() {
+		return false
+	}
+	entry.Value.Expiry = time.Time{}
+	d.put(key, entry)
+	return true
+}
+
+func (s *Storage) Expire(key string, ttl time.Duration, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.Expire(key, ttl), nil
+}
+
+func (s *Storage) SetExpiryAt(key string, at time.Time, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.SetExpiryAt(key, at), nil
+}
+
+func (s *Storage) ExpiryAt(key string, db int) (time.Time, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	at, ok := d.ExpiryAt(key)
+	return at, ok, nil
+}
+
+func (s *Storage) TTL(key string, db int) (time.Duration, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, false, err
+	}
+	ttl, ok := d.TTL(key)
+	return ttl, ok, nil
+}
+
+func (s *Storage) Persist(key string, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.Persist(key), nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+// MatchPattern reports whether s matches the glob pattern the way Redis
+// matches KEYS, SCAN MATCH and PSUBSCRIBE patterns: * and ? match any run
+// and any single byte, [abc], [^abc] and [a-z] match byte classes, and a
+// backslash matches the next byte literally.
+//
+// Every token but * matches exactly one byte, so on a mismatch it is enough
+// to retry from the most recent * with one more byte swallowed by it: an
+// earlier * could only swallow what the later one can. That keeps the work
+// to O(len(pattern)*len(s)); recursing into every *, as Redis once did
+// (CVE-2022-36021), is exponential in the number of stars.
+func MatchPattern(pattern, s string) bool {
+	p, i := 0, 0
+	star, mark := -1, 0 // just past the last *, and where s resumes after it
+	for i < len(s) {
+		if p < len(pattern) && pattern[p] == '*' {
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			if p == len(pattern) {
+				return true
+			}
+			star, mark = p, i
+			continue
+		}
+		if p < len(pattern) {
+			if next, ok := matchByte(pattern, p, s[i]); ok {
+				p, i = next, i+1
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		mark++
+		p, i = star, mark
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchByte matches c against the single-byte token at pattern[p], which
+// is not a *, and returns the index of the token after it.
+func matchByte(pattern string, p int, c byte) (int, bool) {
+	switch pattern[p] {
+	case '?':
+		return p + 1, true
+	case '[':
+		ok, rest := matchClass(pattern[p+1:], c)
+		return len(pattern) - len(rest), ok
+	case '\\':
+		if p+1 < len(pattern) {
+			p++
+		}
+	}
+	return p + 1, pattern[p] == c
+}
+
+// matchClass matches c against the class that starts at pattern, just
+// past the '[', and returns the pattern after the closing ']'.
+func matchClass(pattern string, c byte) (bool, string) {
+	negate := len(pattern) > 0 && pattern[0] == '^'
+	if negate {
+		pattern = pattern[1:]
+	}
+	match := false
+	for len(pattern) > 0 && pattern[0] != ']' {
+		switch {
+		case pattern[0] == '\\' && len(pattern) > 1:
+			match = match || pattern[1] == c
+			pattern = pattern[2:]
+		case len(pattern) > 2 && pattern[1] == '-' && pattern[2] != ']':
+			lo, hi := pattern[0], pattern[2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			match = match || (c >= lo && c <= hi)
+			pattern = pattern[3:]
+		default:
+			match = match || pattern[0] == c
+			pattern = pattern[1:]
+		}
+	}
+	if len(pattern) > 0 {
+		pattern = pattern[1:] // the ']'
+	}
+	return match != negate, pattern
+}
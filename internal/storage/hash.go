@@ -0,0 +1,292 @@
+package storage
+
+import "time"
+
+// HSet sets each field of the hash at key to its value, creating the hash
+// if needed, and returns how many of the fields are new. Fields that are
+// set lose their TTL.
+func (d *Database) HSet(key string, pairs [][2]string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupHash(key)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		entry = Entry{Value: Value{Type: TypeHash, Hash: make(map[string]string, len(pairs))}}
+	} else {
+		d.preserve(key)
+	}
+	added := 0
+	for _, p := range pairs {
+		if _, exists := entry.Value.Hash[p[0]]; !exists {
+			added++
+		}
+		entry.Value.Hash[p[0]] = p[1]
+		delete(entry.Value.FieldExpiry, p[0])
+	}
+	d.put(key, entry)
+	return added, nil
+}
+
+// HSetNX sets field in the hash at key only if it does not exist yet,
+// creating the hash if needed, and reports whether it did.
+func (d *Database) HSetNX(key, field, value string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupHash(key)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		entry = Entry{Value: Value{Type: TypeHash, Hash: make(map[string]string, 1)}}
+	} else if _, exists := entry.Value.Hash[field]; exists {
+		return false, nil
+	} else {
+		d.preserve(key)
+	}
+	entry.Value.Hash[field] = value
+	d.put(key, entry)
+	return true, nil
+}
+
+// HGet returns the value of field in the hash at key. ok is false when the
+// key or the field does not exist.
+func (d *Database) HGet(key, field string) (string, bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	val, ok := entry.Value.Hash[field]
+	if ok && entry.Value.fieldExpired(field, time.Now()) {
+		return "", false, nil
+	}
+	return val, ok, nil
+}
+
+// HDel removes fields from the hash at key and returns how many existed.
+// The key is deleted once the hash is empty.
+func (d *Database) HDel(key string, fields []string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupHash(key)
+	if !ok || err != nil {
+		return 0, err
+	}
+	d.preserve(key)
+	removed := 0
+	for _, field := range fields {
+		if _, exists := entry.Value.Hash[field]; exists {
+			delete(entry.Value.Hash, field)
+			delete(entry.Value.FieldExpiry, field)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	if len(entry.Value.Hash) == 0 {
+		d.remove(key)
+		return removed, nil
+	}
+	d.put(key, entry)
+	return removed, nil
+}
+
+// HGetAll returns every field of the hash at key with its value, ordered by
+// field so repeated calls agree; a missing key has none.
+func (d *Database) HGetAll(key string) ([][2]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return nil, err
+	}
+	fields := entry.Value.liveFields(time.Now())
+	pairs := make([][2]string, 0, len(fields))
+	for _, field := range fields {
+		pairs = append(pairs, [2]string{field, entry.Value.Hash[field]})
+	}
+	return pairs, nil
+}
+
+// HStrLen returns the length of the value of field in the hash at key, or
+// 0 when the key or the field does not exist.
+func (d *Database) HStrLen(key, field string) (int, error) {
+	val, _, err := d.HGet(key, field)
+	return len(val), err
+}
+
+// HMGet returns the value of each field in the hash at key, nil for fields
+// that do not exist; a missing key has none of them.
+func (d *Database) HMGet(key string, fields []string) ([]*string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	vals := make([]*string, len(fields))
+	for i, field := range fields {
+		if v, ok := entry.Value.Hash[field]; ok && !entry.Value.fieldExpired(field, now) {
+			vals[i] = &v
+		}
+	}
+	return vals, nil
+}
+
+// HExists reports whether field exists in the hash at key.
+func (d *Database) HExists(key, field string) (bool, error) {
+	_, ok, err := d.HGet(key, field)
+	return ok, err
+}
+
+// HLen returns the number of fields in the hash at key.
+func (d *Database) HLen(key string) (int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return 0, err
+	}
+	n, now := len(entry.Value.Hash), time.Now()
+	for field := range entry.Value.FieldExpiry {
+		if entry.Value.fieldExpired(field, now) {
+			n--
+		}
+	}
+	return n, nil
+}
+
+// HKeys returns the fields of the hash at key, in the order of HGetAll.
+func (d *Database) HKeys(key string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return nil, err
+	}
+	return entry.Value.liveFields(time.Now()), nil
+}
+
+// HVals returns the values of the hash at key, in the order of HGetAll.
+func (d *Database) HVals(key string) ([]string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return nil, err
+	}
+	fields := entry.Value.liveFields(time.Now())
+	vals := make([]string, 0, len(fields))
+	for _, field := range fields {
+		vals = append(vals, entry.Value.Hash[field])
+	}
+	return vals, nil
+}
+
+func (s *Storage) HSet(key string, pairs [][2]string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.HSet(key, pairs)
+}
+
+func (s *Storage) HGet(key, field string, db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	return d.HGet(key, field)
+}
+
+func (s *Storage) HDel(key string, fields []string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.HDel(key, fields)
+}
+
+func (s *Storage) HGetAll(key string, db int) ([][2]string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HGetAll(key)
+}
+
+func (s *Storage) HMGet(key string, fields []string, db int) ([]*string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HMGet(key, fields)
+}
+
+func (s *Storage) HExists(key, field string, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.HExists(key, field)
+}
+
+func (s *Storage) HLen(key string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.HLen(key)
+}
+
+func (s *Storage) HKeys(key string, db int) ([]string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HKeys(key)
+}
+
+func (s *Storage) HVals(key string, db int) ([]string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HVals(key)
+}
+
+func (s *Storage) HSetNX(key, field, value string, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.HSetNX(key, field, value)
+}
+
+func (s *Storage) HStrLen(key, field string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.HStrLen(key, field)
+}
@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// ExpireCondition restricts when HExpireAt replaces a field's expiry, as the
+// NX, XX, GT and LT options of HEXPIRE do. A field without an expiry counts
+// as expiring never, so GT never applies to it and LT always does.
+type ExpireCondition int8
+
+const (
+	ExpireAlways ExpireCondition = iota
+	ExpireNX                     // only fields without an expiry
+	ExpireXX                     // only fields with an expiry
+	ExpireGT                     // only when the new expiry is later
+	ExpireLT                     // only when the new expiry is earlier
+)
+
+// Per-field results of HExpireAt and HPersist, as Redis replies them.
+const (
+	FieldMissing    = -2 // no such field, or no such key
+	FieldNoExpiry   = -1 // HPersist: the field had no expiry
+	FieldNotSet     = 0  // HExpireAt: the condition did not hold
+	FieldUpdated    = 1  // the expiry was set or removed
+	FieldExpiredNow = 2  // HExpireAt: the time had passed, so the field was deleted
+)
+
+func (c ExpireCondition) allows(cur, at time.Time) bool {
+	switch c {
+	case ExpireNX:
+		return cur.IsZero()
+	case ExpireXX:
+		return !cur.IsZero()
+	case ExpireGT:
+		return !cur.IsZero() && at.After(cur)
+	case ExpireLT:
+		return cur.IsZero() || at.Before(cur)
+	}
+	return true
+}
+
+// fieldExpired reports whether field of the hash v has a TTL that has
+// passed. Readers use it to hide such fields until a writer or the active
+// cycle drops them.
+func (v Value) fieldExpired(field string, now time.Time) bool {
+	at, ok := v.FieldExpiry[field]
+	return ok && !now.Before(at)
+}
+
+// liveFields returns the fields of the hash v whose TTL has not passed,
+// sorted, which is the order every command listing a hash uses.
+func (v Value) liveFields(now time.Time) []string {
+	fields := make([]string, 0, len(v.Hash))
+	for field := range v.Hash {
+		if !v.fieldExpired(field, now) {
+			fields = append(fields, field)
+		}
+	}
+	slices.Sort(fields)
+	return fields
+}
+
+// lookupHash is lookupType for hash writers: it drops the fields whose TTL
+// has passed first, and a hash left with none reads as missing. The caller
+// holds d.mu for writing.
+func (d *Database) lookupHash(key string) (Entry, bool, error) {
+	entry, ok, err := d.lookupType(key, TypeHash)
+	if !ok || err != nil {
+		return entry, ok, err
+	}
+	entry, ok = d.expireFields(key, entry, time.Now())
+	return entry, ok, nil
+}
+
+// expireFields drops the fields of the hash entry at key whose TTL has
+// passed, deleting the key once no field is left, and reports whether the
+// key still exists; the caller holds d.mu for writing.
+func (d *Database) expireFields(key string, entry Entry, now time.Time) (Entry, bool) {
+	var expired []string
+	for field := range entry.Value.FieldExpiry {
+		if entry.Value.fieldExpired(field, now) {
+			expired = append(expired, field)
+		}
+	}
+	if len(expired) == 0 {
+		return entry, true
+	}
+	d.preserve(key)
+	for _, field := range expired {
+		delete(entry.Value.Hash, field)
+		delete(entry.Value.FieldExpiry, field)
+	}
+	if len(entry.Value.Hash) == 0 {
+		d.expire(key)
+		return Entry{}, false
+	}
+	if len(entry.Value.FieldExpiry) == 0 {
+		entry.Value.FieldExpiry = nil
+	}
+	d.put(key, entry)
+	return entry, true
+}
+
+// HExpireAt sets each of fields in the hash at key to expire at the wall
+// clock time at, where cond allows it, and returns a Field* result per
+// field. A time that is not in the future deletes the field right away,
+// and the key with its last field.
+func (d *Database) HExpireAt(key string, fields []string, at time.Time, cond ExpireCondition) ([]int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	results := make([]int, len(fields))
+	entry, ok, err := d.lookupHash(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		for i := range results {
+			results[i] = FieldMissing
+		}
+		return results, nil
+	}
+
+	d.preserve(key)
+	v := &entry.Value
+	now := time.Now()
+	changed := false
+	for i, field := range fields {
+		if _, exists := v.Hash[field]; !exists {
+			results[i] = FieldMissing
+			continue
+		}
+		if !cond.allows(v.FieldExpiry[field], at) {
+			results[i] = FieldNotSet
+			continue
+		}
+		changed = true
+		if !at.After(now) {
+			delete(v.Hash, field)
+			delete(v.FieldExpiry, field)
+			results[i] = FieldExpiredNow
+			continue
+		}
+		if v.FieldExpiry == nil {
+			v.FieldExpiry = make(map[string]time.Time)
+		}
+		v.FieldExpiry[field] = at
+		results[i] = FieldUpdated
+	}
+	switch {
+	case !changed:
+	case len(v.Hash) == 0:
+		d.remove(key)
+	default:
+		if len(v.FieldExpiry) == 0 {
+			v.FieldExpiry = nil
+		}
+		d.put(key, entry)
+	}
+	return results, nil
+}
+
+// HExpiryAt returns when each of fields in the hash at key expires: nil for
+// a field that does not exist, and the zero time for one that never
+// expires.
+func (d *Database) HExpiryAt(key string, fields []string) ([]*time.Time, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeHash)
+	d.read(key, entry, ok)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ats := make([]*time.Time, len(fields))
+	for i, field := range fields {
+		if _, exists := entry.Value.Hash[field]; exists && !entry.Value.fieldExpired(field, now) {
+			at := entry.Value.FieldExpiry[field]
+			ats[i] = &at
+		}
+	}
+	return ats, nil
+}
+
+// HPersist removes the expiry of each of fields in the hash at key and
+// returns a Field* result per field.
+func (d *Database) HPersist(key string, fields []string) ([]int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	results := make([]int, len(fields))
+	entry, ok, err := d.lookupHash(key)
+	if err != nil {
+		return nil, err
+	}
+	changed := false
+	for i, field := range fields {
+		_, exists := entry.Value.Hash[field]
+		_, expires := entry.Value.FieldExpiry[field]
+		switch {
+		case !ok || !exists:
+			results[i] = FieldMissing
+		case !expires:
+			results[i] = FieldNoExpiry
+		default:
+			d.preserve(key)
+			delete(entry.Value.FieldExpiry, field)
+			results[i] = FieldUpdated
+			changed = true
+		}
+	}
+	if changed {
+		if len(entry.Value.FieldExpiry) == 0 {
+			entry.Value.FieldExpiry = nil
+		}
+		d.put(key, entry)
+	}
+	return results, nil
+}
+
+// expireFieldsCycle is the active half of field expiry: it checks up to
+// limit of the hashes that carry field TTLs and drops their expired fields,
+// so fields nobody touches again do not linger.
+func (d *Database) expireFieldsCycle(limit int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	for key := range d.fieldExpires {
+		if limit--; limit < 0 {
+			return
+		}
+		if entry, ok := d.lookup(key); ok {
+			d.expireFields(key, entry, now)
+		}
+	}
+}
+
+func (s *Storage) HExpireAt(key string, fields []string, at time.Time, cond ExpireCondition, db int) ([]int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HExpireAt(key, fields, at, cond)
+}
+
+func (s *Storage) HExpiryAt(key string, fields []string, db int) ([]*time.Time, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HExpiryAt(key, fields)
+}
+
+func (s *Storage) HPersist(key string, fields []string, db int) ([]int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.HPersist(key, fields)
+}
+
+// ExpireFields runs one active field expiry cycle on every database,
+// checking up to limit hashes in each. The server cron calls it.
+func (s *Storage) ExpireFields(limit int) {
+	s.mu.RLock()
+	dbs := make([]*Database, 0, len(s.databases))
+	for _, d := range s.databases {
+		dbs = append(dbs, d)
+	}
+	s.mu.RUnlock()
+
+	for _, d := range dbs {
+		d.expireFieldsCycle(limit)
+	}
+}
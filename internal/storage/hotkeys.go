@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"cmp"
+	"container/heap"
+	"hash/maphash"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	sketchDepth = 4
+	sketchWidth = 2048
+	// sketchDecay is how many accesses pass between halvings of every
+	// count, so keys that stop being used drop out of the top-K.
+	sketchDecay = 10 * sketchWidth
+)
+
+// HotKey is one entry of the hot-key list. Count is an estimate of the
+// recent accesses to the key; it may overcount but never undercounts.
+type HotKey struct {
+	DB    int
+	Key   string
+	Count uint32
+}
+
+// hotKeys estimates access counts with a count-min sketch and keeps the
+// k keys with the highest estimates in a min-heap, so tracking costs a
+// fixed amount of memory however many keys there are.
+type hotKeys struct {
+	mu       sync.Mutex
+	k        int
+	seed     maphash.Seed
+	sketch   [sketchDepth][sketchWidth]uint32
+	accesses int
+	top      hotHeap
+}
+
+type hotKeyID struct {
+	db  int
+	key string
+}
+
+func newHotKeys(k int) *hotKeys {
+	return &hotKeys{
+		k:    k,
+		seed: maphash.MakeSeed(),
+		top:  hotHeap{pos: make(map[hotKeyID]int, k)},
+	}
+}
+
+func (h *hotKeys) record(db int, key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := hotKeyID{db, key}
+	est := ^uint32(0)
+	for i, col := range h.columns(db, key) {
+		h.sketch[i][col]++
+		est = min(est, h.sketch[i][col])
+	}
+
+	if i, ok := h.top.pos[id]; ok {
+		h.top.entries[i].Count = est
+		heap.Fix(&h.top, i)
+	} else if h.top.Len() < h.k {
+		heap.Push(&h.top, HotKey{DB: db, Key: key, Count: est})
+	} else if est > h.top.entries[0].Count {
+		h.top.replaceMin(HotKey{DB: db, Key: key, Count: est})
+	}
+
+	if h.accesses++; h.accesses >= sketchDecay {
+		h.decay()
+	}
+}
+
+// columns picks a key's counter in each sketch row by double hashing the
+// two halves of one hash, so keys that share a counter in one row are
+// unlikely to share it in the others.
+func (h *hotKeys) columns(db int, key string) (cols [sketchDepth]uint64) {
+	sum := maphash.String(h.seed, key) + uint64(db)
+	h1, h2 := sum&0xffffffff, sum>>32|1
+	for i := range cols {
+		cols[i] = (h1 + uint64(i)*h2) % sketchWidth
+	}
+	return cols
+}
+
+func (h *hotKeys) decay() {
+	h.accesses = 0
+	for i := range h.sketch {
+		for j := range h.sketch[i] {
+			h.sketch[i][j] /= 2
+		}
+	}
+	for i := range h.top.entries {
+		h.top.entries[i].Count /= 2
+	}
+}
+
+func (h *hotKeys) list() []HotKey {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := slices.Clone(h.top.entries)
+	slices.SortFunc(out, func(a, b HotKey) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.DB, b.DB), cmp.Compare(a.Key, b.Key))
+	})
+	return out
+}
+
+// hotHeap is a min-heap of HotKeys by Count that knows where each key
+// sits, so a key already in it can be updated in place.
+type hotHeap struct {
+	entries []HotKey
+	pos     map[hotKeyID]int
+}
+
+func (h *hotHeap) Len() int           { return len(h.entries) }
+func (h *hotHeap) Less(i, j int) bool { return h.entries[i].Count < h.entries[j].Count }
+
+func (h *hotHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.pos[hotKeyID{h.entries[i].DB, h.entries[i].Key}] = i
+	h.pos[hotKeyID{h.entries[j].DB, h.entries[j].Key}] = j
+}
+
+func (h *hotHeap) Push(x any) {
+	e := x.(HotKey)
+	h.pos[hotKeyID{e.DB, e.Key}] = len(h.entries)
+	h.entries = append(h.entries, e)
+}
+
+func (h *hotHeap) Pop() any {
+	e := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	delete(h.pos, hotKeyID{e.DB, e.Key})
+	return e
+}
+
+// replaceMin evicts the least accessed key in favour of e.
+func (h *hotHeap) replaceMin(e HotKey) {
+	delete(h.pos, hotKeyID{h.entries[0].DB, h.entries[0].Key})
+	h.entries[0] = e
+	h.pos[hotKeyID{e.DB, e.Key}] = 0
+	heap.Fix(h, 0)
+}
+
+// hotTracker is the tracker shared by every database of a Storage; it
+// holds nil while tracking is off.
+type hotTracker struct {
+	atomic.Pointer[hotKeys]
+}
+
+func (t *hotTracker) record(db int, key string) {
+	if h := t.Load(); h != nil {
+		h.record(db, key)
+	}
+}
+
+// TrackHotKeys starts estimating the k most accessed keys across all
+// databases, discarding what was tracked so far; k of 0 stops tracking.
+func (s *Storage) TrackHotKeys(k int) {
+	if k <= 0 {
+		s.hot.Store(nil)
+		return
+	}
+	s.hot.Store(newHotKeys(k))
+}
+
+// HotKeys returns the tracked keys, most accessed first, or nil when
+// tracking is off.
+func (s *Storage) HotKeys() []HotKey {
+	h := s.hot.Load()
+	if h == nil {
+		return nil
+	}
+	return h.list()
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// ErrNotInteger is returned when a counter operation hits a value that is
+// not an integer, or when the result would overflow int64.
+var ErrNotInteger = errors.New("value is not an integer or out of range")
+
+// parseIntString reports whether s is the canonical decimal form of an
+// int64, i.e. converting the result back yields exactly s. Values such as
+// "007" or "+1" stay plain strings so they round-trip byte for byte.
+func parseIntString(s string) (int64, bool) {
+	if len(s) == 0 || len(s) > 20 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || strconv.FormatInt(n, 10) != s {
+		return 0, false
+	}
+	return n, true
+}
+
+// stringValue builds a string Value, switching to the int encoding when possible.
+func stringValue(s string) Value {
+	if n, ok := parseIntString(s); ok {
+		return Value{Type: TypeInt, Num: n}
+	}
+	return Value{Type: TypeString, String: s}
+}
+
+// Str returns the string form of a string-typed value regardless of its encoding.
+func (v Value) Str() string {
+	if v.Type == TypeInt {
+		return strconv.FormatInt(v.Num, 10)
+	}
+	return v.String
+}
+
+// IsString reports whether v is a string, in either the raw or int encoding.
+func (v Value) IsString() bool {
+	return v.Type == TypeString || v.Type == TypeInt
+}
+
+// asInt returns the integer held by a string value.
+func (v Value) asInt() (int64, error) {
+	switch v.Type {
+	case TypeInt:
+		return v.Num, nil
+	case TypeString:
+		if n, ok := parseIntString(v.String); ok {
+			return n, nil
+		}
+	}
+	return 0, ErrNotInteger
+}
+
+func addInt64(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrNotInteger
+	}
+	return a + b, nil
+}
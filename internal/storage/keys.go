@@ -0,0 +1,140 @@
+package storage
+
+import "errors"
+
+// Exists counts how many of keys exist; a key named twice counts twice.
+// It does not count as an access to the keys.
+func (d *Database) Exists(keys []string) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	n := 0
+	for _, key := range keys {
+		_, ok := d.peek(key)
+		d.counters.read(ok)
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// Touch records an access to each of keys that exists and returns how
+// many did, through a NoTouch handle too.
+func (d *Database) Touch(keys []string) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	n := 0
+	for _, key := range keys {
+		entry, ok := d.peek(key)
+		d.read(key, entry, ok)
+		if ok {
+			if d.noTouch && entry.access != nil {
+				entry.access.touch()
+			}
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Storage) Exists(keys []string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.Exists(keys), nil
+}
+
+func (s *Storage) Touch(keys []string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.Touch(keys), nil
+}
+
+// ErrSameObject is returned when COPY or MOVE would write a key onto
+// itself.
+var ErrSameObject = errors.New("source and destination objects are the same")
+
+// lockPair write-locks two databases, always in index order so that two
+// calls locking the same pair the opposite way round cannot deadlock.
+func lockPair(a, b *Database) (unlock func()) {
+	if a.dbState == b.dbState {
+		a.mu.Lock()
+		return a.mu.Unlock
+	}
+	first, second := a, b
+	if b.index < a.index {
+		first, second = b, a
+	}
+	first.mu.Lock()
+	second.mu.Lock()
+	return func() {
+		second.mu.Unlock()
+		first.mu.Unlock()
+	}
+}
+
+// Copy copies the value and TTL of key in database srcDB to dst in dstDB
+// and reports whether it did. It does nothing when key does not exist, or
+// when dst does and replace is false.
+func (s *Storage) Copy(key, dst string, srcDB, dstDB int, replace bool) (bool, error) {
+	src, err := s.database(srcDB)
+	if err != nil {
+		return false, err
+	}
+	dest, err := s.database(dstDB)
+	if err != nil {
+		return false, err
+	}
+	if src.dbState == dest.dbState && key == dst {
+		return false, ErrSameObject
+	}
+	defer lockPair(src, dest)()
+
+	entry, ok := src.lookup(key)
+	if !ok {
+		return false, nil
+	}
+	if _, exists := dest.lookup(dst); exists && !replace {
+		return false, nil
+	}
+	value := entry.Value.clone()
+	if value.Type == TypeInt {
+		value.String = ""
+	}
+	dest.put(dst, Entry{Value: value})
+	return true, nil
+}
+
+// Move moves key, with its TTL, from database srcDB to dstDB and reports
+// whether it did. It does nothing when key does not exist in srcDB or
+// already exists in dstDB.
+func (s *Storage) Move(key string, srcDB, dstDB int) (bool, error) {
+	src, err := s.database(srcDB)
+	if err != nil {
+		return false, err
+	}
+	dest, err := s.database(dstDB)
+	if err != nil {
+		return false, err
+	}
+	if src.dbState == dest.dbState {
+		return false, ErrSameObject
+	}
+	defer lockPair(src, dest)()
+
+	entry, ok := src.lookup(key)
+	if !ok {
+		return false, nil
+	}
+	if _, exists := dest.lookup(key); exists {
+		return false, nil
+	}
+	src.remove(key)
+	dest.put(key, Entry{Value: entry.Value})
+	return true, nil
+}
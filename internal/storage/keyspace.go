@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrWrongType is returned when a command meets a key holding a value of
+// a different type than it works on.
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// ErrNoSuchKey is returned by operations that need key to exist.
+var ErrNoSuchKey = errors.New("no such key")
+
+// KeyspaceStats summarises one database for INFO keyspace. Keys includes
+// expired keys that have not been removed yet.
+type KeyspaceStats struct {
+	Keys    int
+	Expires int
+	AvgTTL  time.Duration
+}
+
+// lookup returns the live entry for key, lazily expiring it when its TTL
+// has passed; the caller holds d.mu for writing.
+func (d *Database) lookup(key string) (Entry, bool) {
+	entry, ok := d.data[key]
+	if !ok {
+		return Entry{}, false
+	}
+	if !entry.Value.Expiry.IsZero() && time.Now().After(entry.Value.Expiry) {
+		d.expire(key)
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// peek is lookup for callers holding d.mu only for reading: an expired
+// key reads as missing but is left for the next writer to remove.
+func (d *Database) peek(key string) (Entry, bool) {
+	entry, ok := d.data[key]
+	if !ok || (!entry.Value.Expiry.IsZero() && time.Now().After(entry.Value.Expiry)) {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+// lookupType is lookup for commands that only work on values of type
+// typ, failing with ErrWrongType when key holds anything else. Strings
+// match either string encoding. The caller holds d.mu for writing.
+func (d *Database) lookupType(key string, typ ValueType) (Entry, bool, error) {
+	entry, ok := d.lookup(key)
+	return entry, ok, checkType(entry, ok, typ)
+}
+
+// peekType is lookupType for callers holding d.mu only for reading.
+func (d *Database) peekType(key string, typ ValueType) (Entry, bool, error) {
+	entry, ok := d.peek(key)
+	return entry, ok, checkType(entry, ok, typ)
+}
+
+func checkType(e Entry, ok bool, typ ValueType) error {
+	if !ok {
+		return nil
+	}
+	if typ == TypeString && e.Value.IsString() || e.Value.Type == typ {
+		return nil
+	}
+	return ErrWrongType
+}
+
+// put stores e under key with a fresh version and keeps the keyspace
+// counters in sync. Every write to d.data must go through put or remove;
+// the caller holds d.mu.
+func (d *Database) put(key string, e Entry) {
+	d.preserve(key)
+	if old, ok := d.data[key]; ok {
+		d.untrackExpiry(old.Value.Expiry)
+		// Overwriting a key keeps its access history, as in Redis.
+		e.access = old.access
+	} else {
+		d.keys.add(key)
+		d.random.add(key)
+	}
+	if e.access == nil {
+		e.access = newAccess()
+	} else {
+		e.access.touch()
+	}
+	d.version++
+	e.Version = d.version
+	d.trackExpiry(e.Value.Expiry)
+	d.trackFieldExpiry(key, e.Value)
+	d.data[key] = e
+	d.hot.record(d.index, key)
+	d.emit(EventModified, key, e.Value.Type)
+	if e.Value.Type == TypeList {
+		d.blocked.signal(key)
+	}
+}
+
+// remove deletes key and reports whether it existed; the caller holds d.mu.
+func (d *Database) remove(key string) bool {
+	return d.drop(key, EventDeleted)
+}
+
+// expire deletes a key whose TTL has passed; the caller holds d.mu.
+func (d *Database) expire(key string) bool {
+	return d.drop(key, EventExpired)
+}
+
+func (d *Database) drop(key string, kind EventKind) bool {
+	old, ok := d.data[key]
+	if !ok {
+		return false
+	}
+	d.preserve(key)
+	d.untrackExpiry(old.Value.Expiry)
+	delete(d.data, key)
+	delete(d.fieldExpires, key)
+	d.keys.remove(key)
+	d.random.remove(key)
+	if kind == EventExpired {
+		d.counters.expired.Add(1)
+	}
+	d.emit(kind, key, old.Value.Type)
+	return true
+}
+
+func (d *Database) trackExpiry(expiry time.Time) {
+	if expiry.IsZero() {
+		return
+	}
+	d.expires++
+	d.expirySum += expiry.UnixMilli()
+}
+
+// trackFieldExpiry keeps key in d.fieldExpires exactly while v is a hash
+// with field TTLs.
+func (d *Database) trackFieldExpiry(key string, v Value) {
+	if len(v.FieldExpiry) == 0 {
+		delete(d.fieldExpires, key)
+		return
+	}
+	if d.fieldExpires == nil {
+		d.fieldExpires = make(map[string]struct{})
+	}
+	d.fieldExpires[key] = struct{}{}
+}
+
+func (d *Database) untrackExpiry(expiry time.Time) {
+	if expiry.IsZero() {
+		return
+	}
+	d.expires--
+	d.expirySum -= expiry.UnixMilli()
+}
+
+// reset drops every key along with the counters; the caller holds d.mu.
+func (d *Database) reset() {
+	d.preserveAll()
+	d.data = make(map[string]Entry)
+	d.keys.clear()
+	d.random.clear()
+	d.expires = 0
+	d.expirySum = 0
+	d.fieldExpires = nil
+	d.emit(EventFlushed, "", 0)
+}
+
+// Stats returns the keyspace counters without walking the keys.
+func (d *Database) Stats() KeyspaceStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	stats := KeyspaceStats{Keys: len(d.data), Expires: d.expires}
+	if d.expires > 0 {
+		avg := time.UnixMilli(d.expirySum / int64(d.expires))
+		if ttl := time.Until(avg); ttl > 0 {
+			stats.AvgTTL = ttl
+		}
+	}
+	return stats
+}
+
+func (s *Storage) Stats(db int) (KeyspaceStats, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return KeyspaceStats{}, err
+	}
+	return d.Stats(), nil
+}
+
+// DBSize returns the number of live keys. Expired keys that have not been
+// removed yet are left out, which takes a walk over the keys whenever any
+// of them has a TTL.
+func (d *Database) DBSize() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	n := len(d.data)
+	if d.expires == 0 {
+		return n
+	}
+	now := time.Now()
+	for _, e := range d.data {
+		if !e.Value.Expiry.IsZero() && now.After(e.Value.Expiry) {
+			n--
+		}
+	}
+	return n
+}
+
+func (s *Storage) DBSize(db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.DBSize(), nil
+}
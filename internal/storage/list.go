@@ -0,0 +1,288 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrIndexOutOfRange is returned by LSet for an index past either end of
+// the list.
+var ErrIndexOutOfRange = errors.New("index out of range")
+
+// listIndex resolves a possibly negative index into a list of n elements,
+// reporting whether it is in range.
+func listIndex(n, index int) (int, bool) {
+	if index < 0 {
+		index += n
+	}
+	return index, index >= 0 && index < n
+}
+
+// LPushX is LPush for a list that already exists; it returns 0 otherwise.
+func (d *Database) LPushX(key string, items []string) (int, error) {
+	return d.push(key, items, true, true)
+}
+
+// RPushX is RPush for a list that already exists; it returns 0 otherwise.
+func (d *Database) RPushX(key string, items []string) (int, error) {
+	return d.push(key, items, false, true)
+}
+
+// LIndex returns the element at index in the list at key; negative indexes
+// count from the tail. ok is false when the key or the index does not exist.
+func (d *Database) LIndex(key string, index int) (string, bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeList)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	i, ok := listIndex(entry.Value.List.Len(), index)
+	if !ok {
+		return "", false, nil
+	}
+	return entry.Value.List.index(i), true, nil
+}
+
+// LSet replaces the element at index in the list at key. It returns
+// ErrNoSuchKey for a missing key and ErrIndexOutOfRange for a bad index.
+func (d *Database) LSet(key string, index int, element string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeList)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNoSuchKey
+	}
+	i, ok := listIndex(entry.Value.List.Len(), index)
+	if !ok {
+		return ErrIndexOutOfRange
+	}
+	d.preserve(key)
+	entry.Value.List.set(i, element)
+	d.put(key, entry)
+	return nil
+}
+
+// LInsert inserts element before or after the first occurrence of pivot in
+// the list at key. It returns the new length, 0 when the key does not exist
+// and -1 when pivot is not in the list.
+func (d *Database) LInsert(key string, before bool, pivot, element string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeList)
+	if !ok || err != nil {
+		return 0, err
+	}
+	i := entry.Value.List.indexOf(pivot)
+	if i < 0 {
+		return -1, nil
+	}
+	if !before {
+		i++
+	}
+	d.preserve(key)
+	entry.Value.List.insert(i, element)
+	d.put(key, entry)
+	return entry.Value.List.Len(), nil
+}
+
+// LRem removes occurrences of element from the list at key: the first count
+// from the head when count is positive, the last -count from the tail when
+// it is negative, and all of them when it is 0. It returns how many were
+// removed and deletes the key once the list is empty.
+func (d *Database) LRem(key string, count int, element string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeList)
+	if !ok || err != nil {
+		return 0, err
+	}
+	list := entry.Value.List
+	limit := list.Len()
+	if count > 0 {
+		limit = min(count, limit)
+	} else if count < 0 && count > -limit {
+		limit = -count
+	}
+	d.preserve(key)
+	removed := list.remove(element, limit, count < 0)
+	if removed == 0 {
+		return 0, nil
+	}
+	if list.Len() == 0 {
+		d.remove(key)
+		return removed, nil
+	}
+	d.put(key, entry)
+	return removed, nil
+}
+
+// LTrim keeps only the elements of the list at key between start and stop,
+// inclusive; negative indexes count from the tail. A range that selects
+// nothing deletes the key.
+func (d *Database) LTrim(key string, start, stop int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeList)
+	if !ok || err != nil {
+		return err
+	}
+	list := entry.Value.List
+	n := list.Len()
+	if start < 0 {
+		start = max(start+n, 0)
+	}
+	if stop < 0 {
+		stop += n
+	}
+	stop = min(stop, n-1)
+	if start > stop {
+		d.remove(key)
+		return nil
+	}
+	if start == 0 && stop == n-1 {
+		return nil
+	}
+	d.preserve(key)
+	list.trim(start, stop)
+	d.put(key, entry)
+	return nil
+}
+
+// LMove pops an element from one end of the list at src and pushes it onto
+// one end of the list at dst, as a single step even when src and dst are the
+// same key. fromLeft and toLeft pick the head for each side. ok is false
+// when src does not exist; a dst holding another type fails with
+// ErrWrongType before anything is popped.
+func (d *Database) LMove(src, dst string, fromLeft, toLeft bool) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	from, ok, err := d.lookupType(src, TypeList)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	to, exists, err := d.lookupType(dst, TypeList)
+	if err != nil {
+		return "", false, err
+	}
+
+	d.preserve(src)
+	var element string
+	if fromLeft {
+		element = from.Value.List.popHead()
+	} else {
+		element = from.Value.List.popTail()
+	}
+	if src == dst {
+		to, exists = from, true
+	} else if from.Value.List.Len() == 0 {
+		d.remove(src)
+	} else {
+		d.put(src, from)
+	}
+	if !exists {
+		to = Entry{Value: Value{Type: TypeList, List: newQuicklist()}}
+	} else if src != dst {
+		d.preserve(dst)
+	}
+	if toLeft {
+		to.Value.List.pushHead(element)
+	} else {
+		to.Value.List.pushTail(element)
+	}
+	d.put(dst, to)
+	return element, true, nil
+}
+
+// BLMove is LMove waiting for src to have an element, with the timeout and
+// cancellation of BLPOP.
+func (d *Database) BLMove(ctx context.Context, src, dst string, fromLeft, toLeft bool, timeout time.Duration) (element string, ok bool, err error) {
+	err = d.block(ctx, []string{src}, timeout, func() (bool, error) {
+		element, ok, err = d.LMove(src, dst, fromLeft, toLeft)
+		return ok, err
+	})
+	return element, ok, err
+}
+
+func (s *Storage) LPushX(key string, items []string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.LPushX(key, items)
+}
+
+func (s *Storage) RPushX(key string, items []string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.RPushX(key, items)
+}
+
+func (s *Storage) LIndex(key string, index, db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	return d.LIndex(key, index)
+}
+
+func (s *Storage) LSet(key string, index int, element string, db int) error {
+	d, err := s.database(db)
+	if err != nil {
+		return err
+	}
+	return d.LSet(key, index, element)
+}
+
+func (s *Storage) LInsert(key string, before bool, pivot, element string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.LInsert(key, before, pivot, element)
+}
+
+func (s *Storage) LRem(key string, count int, element string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.LRem(key, count, element)
+}
+
+func (s *Storage) LTrim(key string, start, stop, db int) error {
+	d, err := s.database(db)
+	if err != nil {
+		return err
+	}
+	return d.LTrim(key, start, stop)
+}
+
+func (s *Storage) LMove(src, dst string, fromLeft, toLeft bool, db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	return d.LMove(src, dst, fromLeft, toLeft)
+}
+
+func (s *Storage) BLMove(ctx context.Context, src, dst string, fromLeft, toLeft bool, timeout time.Duration, db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	return d.BLMove(ctx, src, dst, fromLeft, toLeft, timeout)
+}
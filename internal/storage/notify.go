@@ -0,0 +1,104 @@
+package storage
+
+import "sync"
+
+type EventKind int8
+
+const (
+	EventModified EventKind = iota // key was created or its value changed
+	EventDeleted                   // key was removed by a command
+	EventExpired                   // key was removed because its TTL passed
+	EventFlushed                   // every key in the database was dropped; Key is empty
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventModified:
+		return "modified"
+	case EventDeleted:
+		return "deleted"
+	case EventExpired:
+		return "expired"
+	case EventFlushed:
+		return "flushed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a single change to a Database.
+type Event struct {
+	Kind EventKind
+	DB   int
+	Key  string
+	Type ValueType // type of the value after a modification, or of the removed value
+}
+
+// Subscriber receives change events from a Database. Notify runs
+// synchronously while the database write lock is held, so it must be quick
+// and must never call back into the Database; hand the event off (e.g. over
+// a channel) when real work is needed.
+type Subscriber interface {
+	Notify(ev Event)
+}
+
+// SubscriberFunc adapts a plain function to the Subscriber interface.
+type SubscriberFunc func(ev Event)
+
+func (f SubscriberFunc) Notify(ev Event) { f(ev) }
+
+type subscribers struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]Subscriber
+}
+
+func (s *subscribers) add(sub Subscriber) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs == nil {
+		s.subs = make(map[int]Subscriber)
+	}
+	id := s.nextID
+	s.nextID++
+	s.subs[id] = sub
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			delete(s.subs, id)
+			s.mu.Unlock()
+		})
+	}
+}
+
+func (s *subscribers) notify(ev Event) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, sub := range s.subs {
+		sub.Notify(ev)
+	}
+}
+
+// Subscribe registers sub for every change to d and returns a function that removes it.
+func (d *Database) Subscribe(sub Subscriber) (unsubscribe func()) {
+	return d.subs.add(sub)
+}
+
+// Subscribe registers sub on every database and returns a function that removes it everywhere.
+func (s *Storage) Subscribe(sub Subscriber) (unsubscribe func()) {
+	unsubs := make([]func(), 0, len(s.databases))
+	for _, d := range s.databases {
+		unsubs = append(unsubs, d.Subscribe(sub))
+	}
+	return func() {
+		for _, unsub := range unsubs {
+			unsub()
+		}
+	}
+}
+
+func (d *Database) emit(kind EventKind, key string, typ ValueType) {
+	d.subs.notify(Event{Kind: kind, DB: d.index, Key: key, Type: typ})
+}
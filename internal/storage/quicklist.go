@@ -0,0 +1,279 @@
+package storage
+
+// quicklistFill is the most elements a quicklist node holds.
+const quicklistFill = 128
+
+// Quicklist is how list values are stored: a doubly linked list of nodes
+// holding up to quicklistFill elements each, as in Redis. Pushes and pops
+// at either end touch one small node, and a node is released as soon as
+// its last element is popped, so a long-lived queue does not pin the
+// memory of everything that ever went through it.
+type Quicklist struct {
+	head, tail *qlNode
+	len        int
+}
+
+type qlNode struct {
+	prev, next *qlNode
+	items      []string
+}
+
+func newQuicklist(items ...string) *Quicklist {
+	l := &Quicklist{}
+	for _, item := range items {
+		l.pushTail(item)
+	}
+	return l
+}
+
+// Len returns the number of elements in l.
+func (l *Quicklist) Len() int {
+	if l == nil {
+		return 0
+	}
+	return l.len
+}
+
+// Values returns a copy of every element of l, head first.
+func (l *Quicklist) Values() []string {
+	return l.rangeOf(0, l.Len()-1)
+}
+
+func (l *Quicklist) clone() *Quicklist {
+	if l == nil {
+		return nil
+	}
+	return newQuicklist(l.Values()...)
+}
+
+func (l *Quicklist) pushHead(item string) {
+	if l.head == nil || len(l.head.items) >= quicklistFill {
+		l.link(nil, &qlNode{items: make([]string, 0, quicklistFill)})
+	}
+	n := l.head
+	n.items = append(n.items, "")
+	copy(n.items[1:], n.items)
+	n.items[0] = item
+	l.len++
+}
+
+func (l *Quicklist) pushTail(item string) {
+	if l.tail == nil || len(l.tail.items) >= quicklistFill {
+		l.link(l.tail, &qlNode{items: make([]string, 0, quicklistFill)})
+	}
+	l.tail.items = append(l.tail.items, item)
+	l.len++
+}
+
+// popHead removes and returns the first element; l must not be empty.
+func (l *Quicklist) popHead() string {
+	n := l.head
+	item := n.items[0]
+	n.items[0] = ""
+	n.items = n.items[1:]
+	l.len--
+	if len(n.items) == 0 {
+		l.unlink(n)
+	}
+	return item
+}
+
+// popTail removes and returns the last element; l must not be empty.
+func (l *Quicklist) popTail() string {
+	n := l.tail
+	last := len(n.items) - 1
+	item := n.items[last]
+	n.items[last] = ""
+	n.items = n.items[:last]
+	l.len--
+	if len(n.items) == 0 {
+		l.unlink(n)
+	}
+	return item
+}
+
+// link inserts n after prev, or at the head when prev is nil.
+func (l *Quicklist) link(prev, n *qlNode) {
+	n.prev = prev
+	if prev == nil {
+		n.next = l.head
+		l.head = n
+	} else {
+		n.next = prev.next
+		prev.next = n
+	}
+	if n.next == nil {
+		l.tail = n
+	} else {
+		n.next.prev = n
+	}
+}
+
+func (l *Quicklist) unlink(n *qlNode) {
+	if n.prev == nil {
+		l.head = n.next
+	} else {
+		n.prev.next = n.next
+	}
+	if n.next == nil {
+		l.tail = n.prev
+	} else {
+		n.next.prev = n.prev
+	}
+	n.prev, n.next = nil, nil
+}
+
+// locate returns the node holding element i and i's offset in it, walking
+// from whichever end is nearer; i must be in range.
+func (l *Quicklist) locate(i int) (*qlNode, int) {
+	if i < l.len/2 {
+		n := l.head
+		for i >= len(n.items) {
+			i -= len(n.items)
+			n = n.next
+		}
+		return n, i
+	}
+	n, back := l.tail, l.len-1-i
+	for back >= len(n.items) {
+		back -= len(n.items)
+		n = n.prev
+	}
+	return n, len(n.items) - 1 - back
+}
+
+func (l *Quicklist) index(i int) string {
+	n, off := l.locate(i)
+	return n.items[off]
+}
+
+func (l *Quicklist) set(i int, item string) {
+	n, off := l.locate(i)
+	n.items[off] = item
+}
+
+// insert puts item at position i, shifting the elements from i on; i may
+// be l.Len() to append. A node that overflows is split in two.
+func (l *Quicklist) insert(i int, item string) {
+	if i == l.len {
+		l.pushTail(item)
+		return
+	}
+	n, off := l.locate(i)
+	n.items = append(n.items, "")
+	copy(n.items[off+1:], n.items[off:])
+	n.items[off] = item
+	l.len++
+	if len(n.items) > quicklistFill {
+		half := len(n.items) / 2
+		rest := make([]string, len(n.items)-half, quicklistFill)
+		copy(rest, n.items[half:])
+		clear(n.items[half:])
+		n.items = n.items[:half]
+		l.link(n, &qlNode{items: rest})
+	}
+}
+
+// indexOf returns the position of the first element equal to item, or -1.
+func (l *Quicklist) indexOf(item string) int {
+	i := 0
+	for n := l.head; n != nil; n = n.next {
+		for _, v := range n.items {
+			if v == item {
+				return i
+			}
+			i++
+		}
+	}
+	return -1
+}
+
+// rangeOf copies out the elements from through to, inclusive; both must
+// be in range, or from > to for none.
+func (l *Quicklist) rangeOf(from, to int) []string {
+	if from > to {
+		return nil
+	}
+	out := make([]string, 0, to-from+1)
+	n, off := l.locate(from)
+	for len(out) < cap(out) {
+		end := min(len(n.items), off+cap(out)-len(out))
+		out = append(out, n.items[off:end]...)
+		n, off = n.next, 0
+	}
+	return out
+}
+
+// trim keeps only the elements from start through stop, which must be in
+// range, dropping whole nodes where it can.
+func (l *Quicklist) trim(start, stop int) {
+	for drop := start; drop > 0; {
+		n := l.head
+		if len(n.items) <= drop {
+			drop -= len(n.items)
+			l.len -= len(n.items)
+			l.unlink(n)
+			continue
+		}
+		clear(n.items[:drop])
+		n.items = n.items[drop:]
+		l.len -= drop
+		drop = 0
+	}
+	for drop := l.len - (stop - start + 1); drop > 0; {
+		n := l.tail
+		if len(n.items) <= drop {
+			drop -= len(n.items)
+			l.len -= len(n.items)
+			l.unlink(n)
+			continue
+		}
+		keep := len(n.items) - drop
+		clear(n.items[keep:])
+		n.items = n.items[:keep]
+		l.len -= drop
+		drop = 0
+	}
+}
+
+// remove deletes up to limit elements equal to item, walking from the
+// tail when fromTail is set, and returns how many it deleted.
+func (l *Quicklist) remove(item string, limit int, fromTail bool) int {
+	removed := 0
+	n := l.head
+	if fromTail {
+		n = l.tail
+	}
+	for n != nil && removed < limit {
+		next := n.next
+		if fromTail {
+			next = n.prev
+		}
+		// Nodes never hold more than quicklistFill elements.
+		var drop [quicklistFill]bool
+		for j := range n.items {
+			i := j
+			if fromTail {
+				i = len(n.items) - 1 - j
+			}
+			if removed < limit && n.items[i] == item {
+				drop[i] = true
+				removed++
+			}
+		}
+		kept := n.items[:0]
+		for i, v := range n.items {
+			if !drop[i] {
+				kept = append(kept, v)
+			}
+		}
+		clear(n.items[len(kept):])
+		l.len -= len(n.items) - len(kept)
+		n.items = kept
+		if len(n.items) == 0 {
+			l.unlink(n)
+		}
+		n = next
+	}
+	return removed
+}
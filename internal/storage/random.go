@@ -0,0 +1,64 @@
+package storage
+
+import "math/rand/v2"
+
+// randomIndex keeps the key names of a Database in a slice so RANDOMKEY
+// can pick one uniformly in constant time. Removal swaps the last key into
+// the hole.
+type randomIndex struct {
+	keys []string
+	pos  map[string]int
+}
+
+func (ix *randomIndex) add(key string) {
+	if ix.pos == nil {
+		ix.pos = make(map[string]int)
+	}
+	if _, ok := ix.pos[key]; ok {
+		return
+	}
+	ix.pos[key] = len(ix.keys)
+	ix.keys = append(ix.keys, key)
+}
+
+func (ix *randomIndex) remove(key string) {
+	i, ok := ix.pos[key]
+	if !ok {
+		return
+	}
+	last := len(ix.keys) - 1
+	ix.keys[i] = ix.keys[last]
+	ix.pos[ix.keys[i]] = i
+	ix.keys[last] = ""
+	ix.keys = ix.keys[:last]
+	delete(ix.pos, key)
+}
+
+func (ix *randomIndex) clear() {
+	ix.keys, ix.pos = nil, nil
+}
+
+// RandomKey returns a key chosen uniformly among the live keys, or false
+// when the database is empty. Expired keys it draws are removed and it
+// draws again.
+func (d *Database) RandomKey() (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for len(d.random.keys) > 0 {
+		key := d.random.keys[rand.IntN(len(d.random.keys))]
+		if _, ok := d.lookup(key); ok {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func (s *Storage) RandomKey(db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	key, ok := d.RandomKey()
+	return key, ok, nil
+}
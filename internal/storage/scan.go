@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"time"
+)
+
+// minScanBuckets is the smallest number of buckets in a keyIndex.
+const minScanBuckets = 16
+
+var scanSeed = maphash.MakeSeed()
+
+// keyIndex groups the key names of a Database into a power-of-two number
+// of buckets by hash, so SCAN can resume from a bucket number. The table
+// doubles when it averages four keys a bucket and halves below one key
+// per four buckets.
+//
+// Cursors walk the buckets in reverse-binary order, counting from the
+// high bit of the mask down. A bucket splits into buckets that share its
+// low bits when the table grows and merges with them when it shrinks, so
+// with this order every bucket visited before a resize stays visited
+// after it: a key present for the whole scan is always returned, though
+// a shrink may return some keys twice.
+type keyIndex struct {
+	buckets []map[string]struct{}
+	count   int
+}
+
+func (ix *keyIndex) bucket(key string) uint64 {
+	return maphash.String(scanSeed, key) & uint64(len(ix.buckets)-1)
+}
+
+func (ix *keyIndex) add(key string) {
+	if ix.buckets == nil {
+		ix.resize(minScanBuckets)
+	}
+	b := ix.bucket(key)
+	if ix.buckets[b] == nil {
+		ix.buckets[b] = make(map[string]struct{})
+	}
+	if _, ok := ix.buckets[b][key]; ok {
+		return
+	}
+	ix.buckets[b][key] = struct{}{}
+	ix.count++
+	if ix.count > 4*len(ix.buckets) {
+		ix.resize(2 * len(ix.buckets))
+	}
+}
+
+func (ix *keyIndex) remove(key string) {
+	if ix.buckets == nil {
+		return
+	}
+	b := ix.bucket(key)
+	if _, ok := ix.buckets[b][key]; !ok {
+		return
+	}
+	delete(ix.buckets[b], key)
+	ix.count--
+	if len(ix.buckets) > minScanBuckets && 4*ix.count < len(ix.buckets) {
+		ix.resize(len(ix.buckets) / 2)
+	}
+}
+
+func (ix *keyIndex) clear() {
+	ix.buckets = nil
+	ix.count = 0
+}
+
+func (ix *keyIndex) resize(n int) {
+	old := ix.buckets
+	ix.buckets = make([]map[string]struct{}, n)
+	for _, keys := range old {
+		for key := range keys {
+			b := ix.bucket(key)
+			if ix.buckets[b] == nil {
+				ix.buckets[b] = make(map[string]struct{})
+			}
+			ix.buckets[b][key] = struct{}{}
+		}
+	}
+}
+
+// nextCursor advances a cursor to the next bucket in reverse-binary order;
+// it returns 0 once every bucket has been visited.
+func nextCursor(cursor, mask uint64) uint64 {
+	cursor |= ^mask
+	cursor = bits.Reverse64(cursor)
+	cursor++
+	return bits.Reverse64(cursor)
+}
+
+// ScanOptions filter what Scan returns. Count is a hint for how many keys
+// to look at per call; a zero Count means 10.
+type ScanOptions struct {
+	Match string // glob pattern; empty matches every key
+	Type  string // as reported by ValueType.String; empty for any type
+	Count int
+}
+
+// Scan returns some of the keys in d, starting from cursor, and the cursor
+// to pass next; a returned cursor of 0 means the iteration is over. Start
+// with cursor 0. A key that exists for the whole iteration is returned at
+// least once, however the keyspace changes in between.
+func (d *Database) Scan(cursor uint64, opts ScanOptions) (uint64, []string) {
+	if opts.Count <= 0 {
+		opts.Count = 10
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.keys.buckets == nil {
+		return 0, nil
+	}
+	mask := uint64(len(d.keys.buckets) - 1)
+	now := time.Now()
+	var keys []string
+	visited := 0
+	for {
+		for key := range d.keys.buckets[cursor&mask] {
+			visited++
+			if opts.Match != "" && !MatchPattern(opts.Match, key) {
+				continue
+			}
+			e := d.data[key]
+			if !e.Value.Expiry.IsZero() && now.After(e.Value.Expiry) {
+				continue
+			}
+			if opts.Type != "" && e.Value.Type.String() != opts.Type {
+				continue
+			}
+			keys = append(keys, key)
+		}
+		if cursor = nextCursor(cursor, mask); cursor == 0 || visited >= opts.Count {
+			return cursor, keys
+		}
+		// Empty buckets are cheap but not free; count them so a sparse
+		// table still returns promptly.
+		visited++
+	}
+}
+
+func (s *Storage) Scan(cursor uint64, opts ScanOptions, db int) (uint64, []string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, nil, err
+	}
+	next, keys := d.Scan(cursor, opts)
+	return next, keys, nil
+}
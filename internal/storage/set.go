@@ -0,0 +1,73 @@
+package storage
+
+import "time"
+
+// SetCondition restricts when SetWithOptions writes.
+type SetCondition int8
+
+const (
+	SetAlways   SetCondition = iota
+	SetIfAbsent              // NX: only when the key does not exist
+	SetIfExists              // XX: only when it does
+)
+
+// SetOptions are the modifiers of SET.
+type SetOptions struct {
+	// Expiry is when the new value expires; zero means never, unless
+	// KeepTTL keeps the expiry the old value had.
+	Expiry  time.Time
+	KeepTTL bool
+	Cond    SetCondition
+	// Get asks for the old value, which must then be a string.
+	Get bool
+}
+
+// SetResult is what SetWithOptions did. Old and Existed describe the
+// previous value and are only filled in when SetOptions.Get is set.
+type SetResult struct {
+	Set     bool
+	Old     string
+	Existed bool
+}
+
+// SetWithOptions stores val under key as SET does, checking the condition,
+// reading the old value and writing the new one under a single lock. With
+// Get, an old value that is not a string fails with ErrWrongType and
+// nothing is written.
+func (d *Database) SetWithOptions(key, val string, opts SetOptions) (SetResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var res SetResult
+	old, exists := d.lookup(key)
+	if opts.Get {
+		if exists && !old.Value.IsString() {
+			return res, ErrWrongType
+		}
+		d.read(key, old, exists)
+		res.Existed = exists
+		if exists {
+			res.Old = old.Value.Str()
+		}
+	}
+	if opts.Cond == SetIfAbsent && exists || opts.Cond == SetIfExists && !exists {
+		return res, nil
+	}
+
+	value := stringValue(val)
+	value.Expiry = opts.Expiry
+	if opts.KeepTTL && exists {
+		value.Expiry = old.Value.Expiry
+	}
+	d.put(key, Entry{Value: value})
+	res.Set = true
+	return res, nil
+}
+
+func (s *Storage) SetWithOptions(key, val string, opts SetOptions, db int) (SetResult, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return SetResult{}, err
+	}
+	return d.SetWithOptions(key, val, opts)
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// snapshotBatch is how many keys a snapshot reads per read-lock acquisition.
+const snapshotBatch = 512
+
+// clone returns a deep copy of v so it can outlive later writes to the
+// database. Int-encoded strings get their String form filled in, as on Get.
+func (v Value) clone() Value {
+	c := v
+	if c.Type == TypeInt {
+		c.String = c.Str()
+	}
+	c.List = v.List.clone()
+	c.Hash = maps.Clone(v.Hash)
+	c.FieldExpiry = maps.Clone(v.FieldExpiry)
+	if v.Streams != nil {
+		c.Streams = make([]Stream, len(v.Streams))
+		for i, s := range v.Streams {
+			c.Streams[i] = Stream{Key: s.Key, ID: s.ID, Entries: slices.Clone(s.Entries)}
+		}
+	}
+	return c
+}
+
+// view is a copy-on-write point-in-time view of a Database. Only the key
+// names are copied when it starts; values are read lazily from the live map,
+// and writers preserve the original value of any key they touch before the
+// view has read it.
+type view struct {
+	at    time.Time
+	keys  []string
+	saved map[string]savedEntry
+}
+
+type savedEntry struct {
+	entry   Entry
+	existed bool
+}
+
+// beginView registers a new view; the caller holds d.mu for writing.
+func (d *Database) beginView(at time.Time) *view {
+	v := &view{
+		at:    at,
+		keys:  make([]string, 0, len(d.data)),
+		saved: make(map[string]savedEntry),
+	}
+	for key := range d.data {
+		v.keys = append(v.keys, key)
+	}
+	if d.views == nil {
+		d.views = make(map[*view]struct{})
+	}
+	d.views[v] = struct{}{}
+	return v
+}
+
+func (d *Database) endView(v *view) {
+	d.mu.Lock()
+	delete(d.views, v)
+	d.mu.Unlock()
+}
+
+// preserve records the current state of key in every open view that has not
+// seen it yet. put and drop call it automatically; code that mutates a value
+// in place (e.g. a list element or a hash field) must call it first. The
+// caller holds d.mu for writing.
+func (d *Database) preserve(key string) {
+	for v := range d.views {
+		if _, done := v.saved[key]; done {
+			continue
+		}
+		old, ok := d.data[key]
+		if ok {
+			old.Value = old.Value.clone()
+		}
+		v.saved[key] = savedEntry{entry: old, existed: ok}
+	}
+}
+
+// preserveAll is preserve for every key, used before the map is replaced
+// wholesale. The old entries are handed over as-is since nothing writes to
+// them afterwards.
+func (d *Database) preserveAll() {
+	for v := range d.views {
+		for key, entry := range d.data {
+			if _, done := v.saved[key]; !done {
+				v.saved[key] = savedEntry{entry: entry, existed: true}
+			}
+		}
+	}
+}
+
+// walk reads the view in batches, holding the read lock only while a batch
+// is copied out, and calls fn for every key that was live when it began.
+func (d *Database) walk(v *view, fn func(key string, e Entry) error) error {
+	batch := make([]snapshotItem, 0, snapshotBatch)
+	for start := 0; start < len(v.keys); start += snapshotBatch {
+		end := min(start+snapshotBatch, len(v.keys))
+
+		batch = batch[:0]
+		d.mu.RLock()
+		for _, key := range v.keys[start:end] {
+			entry, ok := d.data[key]
+			if saved, touched := v.saved[key]; touched {
+				entry, ok = saved.entry, saved.existed
+			}
+			if !ok || (!entry.Value.Expiry.IsZero() && v.at.After(entry.Value.Expiry)) {
+				continue
+			}
+			batch = append(batch, snapshotItem{key: key, entry: Entry{Value: entry.Value.clone(), Version: entry.Version}})
+		}
+		d.mu.RUnlock()
+
+		for _, item := range batch {
+			if err := fn(item.key, item.entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type snapshotItem struct {
+	key   string
+	entry Entry
+}
+
+// Snapshot calls fn for every entry that was live in d when the call began.
+// Writes keep flowing while it runs: they only pay for preserving the
+// original of each key they touch, and fn never runs under the lock.
+// Returning an error from fn stops the walk.
+func (d *Database) Snapshot(fn func(key string, e Entry) error) error {
+	d.mu.Lock()
+	v := d.beginView(time.Now())
+	d.mu.Unlock()
+	defer d.endView(v)
+
+	return d.walk(v, fn)
+}
+
+// Snapshot walks every database in index order as of a single point in
+// time: all views are opened while every database lock is held, so the
+// result is consistent across databases as well as within each one.
+func (s *Storage) Snapshot(fn func(db int, key string, e Entry) error) error {
+	now := time.Now()
+	views := make([]*view, len(s.databases))
+	for db := 0; db < len(s.databases); db++ {
+		s.databases[db].mu.Lock()
+	}
+	for db := 0; db < len(s.databases); db++ {
+		views[db] = s.databases[db].beginView(now)
+	}
+	for db := len(s.databases) - 1; db >= 0; db-- {
+		s.databases[db].mu.Unlock()
+	}
+	defer func() {
+		for db, v := range views {
+			s.databases[db].endView(v)
+		}
+	}()
+
+	for db, v := range views {
+		err := s.databases[db].walk(v, func(key string, e Entry) error {
+			return fn(db, key, e)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -17,15 +18,35 @@ const (
 	TypeStream
 	TypeTransaction
 	TypeInt
+	TypeHash
 )
 
+// String is the type name TYPE reports; both string encodings are "string".
+func (t ValueType) String() string {
+	switch t {
+	case TypeString, TypeInt:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeStream:
+		return "stream"
+	case TypeHash:
+		return "hash"
+	default:
+		return "none"
+	}
+}
+
 type Value struct {
 	Type    ValueType
 	String  string
-	List    []string
+	List    *Quicklist
 	Streams []Stream
-	Expiry  time.Time
-	Num     int
+	Hash    map[string]string
+	// FieldExpiry holds the expiry of the hash fields that have one.
+	FieldExpiry map[string]time.Time
+	Expiry      time.Time
+	Num         int64 // integer encoding of a string, used when Type is TypeInt
 }
 type Stream struct {
 	Key     string
@@ -35,35 +56,114 @@ type Stream struct {
 
 type Entry struct {
 	Value Value
+	// Version is bumped on every write to the key; see Database.Version.
+	Version uint64
+
+	access *access
 }
 
+// Database is a handle on one logical database. Every handle on a
+// database shares its dbState; see NoTouch.
 type Database struct {
+	*dbState
+	// noTouch keeps reads through this handle from updating the keys'
+	// access metadata.
+	noTouch bool
+}
+
+type dbState struct {
 	data map[string]Entry
 	mu   sync.RWMutex
+
+	index   int
+	subs    subscribers
+	version uint64 // last version handed out by put
+	views   map[*view]struct{}
+
+	expires   int   // keys carrying a TTL
+	expirySum int64 // sum of their expiry times in unix ms, for avg_ttl
+	// fieldExpires is the set of hashes with field TTLs, which the active
+	// field expiry cycle walks.
+	fieldExpires map[string]struct{}
+	keys         keyIndex
+	random       randomIndex
+
+	counters counters
+	hot      *hotTracker
+	blocked  blockedClients
 }
 
 type Storage struct {
 	databases map[int]*Database
 	mu        sync.RWMutex
+	hot       hotTracker
+}
+
+// DefaultDatabases is the number of logical databases created by NewStorage.
+const DefaultDatabases = 10
+
+// Config controls how a Storage is laid out.
+type Config struct {
+	// Databases is the number of logical databases; values below 1 fall back to DefaultDatabases.
+	Databases int
 }
 
 func NewStorage() *Storage {
-	databases := make(map[int]*Database, 10)
-	for i := 0; i < 10; i++ {
-		databases[i] = &Database{
-			data: make(map[string]Entry),
-		}
+	return NewStorageWithConfig(Config{Databases: DefaultDatabases})
+}
+
+func NewStorageWithConfig(cfg Config) *Storage {
+	if cfg.Databases < 1 {
+		cfg.Databases = DefaultDatabases
+	}
+	s := &Storage{databases: make(map[int]*Database, cfg.Databases)}
+	for i := 0; i < cfg.Databases; i++ {
+		s.databases[i] = &Database{dbState: &dbState{
+			index: i,
+			data:  make(map[string]Entry),
+			hot:   &s.hot,
+		}}
+	}
+	return s
+}
+
+// Databases returns the number of logical databases.
+func (s *Storage) Databases() int {
+	return len(s.databases)
+}
+
+// noTouchDB is the bit NoTouch sets on a database index.
+const noTouchDB = 1 << 24
+
+// NoTouch marks the database index db so that reads through it, other
+// than TOUCH, leave the keys' idle time and LFU counter alone, for
+// clients in CLIENT NO-TOUCH mode.
+func NoTouch(db int) int {
+	return db | noTouchDB
+}
+
+// database resolves a database index, rejecting anything outside [0, Databases()).
+func (s *Storage) database(db int) (*Database, error) {
+	noTouch := db >= 0 && db&noTouchDB != 0
+	if noTouch {
+		db &^= noTouchDB
+	}
+	d, ok := s.databases[db]
+	if !ok {
+		return nil, fmt.Errorf("invalid database %d", db)
 	}
-	return &Storage{
-		databases: databases,
+	if noTouch {
+		return &Database{dbState: d.dbState, noTouch: true}, nil
 	}
+	return d, nil
 }
 
 func (s *Storage) Set(key, val string, exp time.Duration, db int) error {
-	if db >= 10 {
-		return fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return err
 	}
-	return s.databases[db].Set(key, val, exp)
+	return d.Set(key, val, exp)
 }
 
 func (d *Database) Set(key, val string, exp time.Duration) error {
@@ -75,21 +175,18 @@ func (d *Database) Set(key, val string, exp time.Duration) error {
 		expiry = time.Now().Add(exp)
 	}
 
-	d.data[key] = Entry{
-		Value: Value{
-			Type:   TypeString,
-			String: val,
-			Expiry: expiry,
-		},
-	}
+	value := stringValue(val)
+	value.Expiry = expiry
+	d.put(key, Entry{Value: value})
 	return nil
 }
 
 func (s *Storage) Get(key string, db int) (*Entry, error) {
-	if db >= 10 {
-		return nil, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
 	}
-	return s.databases[db].Get(key), nil
+	return d.Get(key), nil
 }
 
 func (d *Database) Get(key string) *Entry {
@@ -97,37 +194,47 @@ func (d *Database) Get(key string) *Entry {
 	entry, ok := d.data[key]
 	d.mu.RUnlock()
 	if !ok {
+		d.counters.read(false)
 		return nil
 	}
 
 	if !entry.Value.Expiry.IsZero() && time.Now().After(entry.Value.Expiry) {
 		d.mu.Lock()
-		delete(d.data, key)
+		if cur, ok := d.data[key]; ok && cur.Value.Expiry.Equal(entry.Value.Expiry) {
+			d.expire(key)
+		}
 		d.mu.Unlock()
+		d.counters.read(false)
 		return nil
 	}
+	d.read(key, entry, true)
 
+	if entry.Value.Type == TypeInt {
+		entry.Value.String = entry.Value.Str()
+	}
 	return &entry
 }
 
-func (s *Storage) Del(key string, db int) int {
-	if db >= 10 {
+func (s *Storage) Del(keys []string, db int) int {
+	d, err := s.database(db)
+	if err != nil {
 		return 0
 	}
-	return s.databases[db].Del(key)
+	return d.Del(keys)
 }
 
-func (d *Database) Del(key string) int {
-	d.mu.RLock()
-	_, ok := d.data[key]
-	d.mu.RUnlock()
-	if !ok {
-		return 0
-	}
+// Del removes keys under a single lock and returns how many existed; a
+// key named twice is only counted once.
+func (d *Database) Del(keys []string) int {
 	d.mu.Lock()
-	delete(d.data, key)
-	d.mu.Unlock()
-	return 1
+	defer d.mu.Unlock()
+	n := 0
+	for _, key := range keys {
+		if _, ok := d.lookup(key); ok && d.remove(key) {
+			n++
+		}
+	}
+	return n
 }
 
 func (s *Storage) Flush() error {
@@ -140,83 +247,109 @@ func (s *Storage) Flush() error {
 
 	for _, db := range dbs {
 		db.mu.Lock()
-		db.data = make(map[string]Entry)
+		db.reset()
 		db.mu.Unlock()
 	}
 	return nil
 }
 
 func (s *Storage) RPush(key string, items []string, db int) (int, error) {
-	if db >= 10 {
-		return 0, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
 	}
-	return s.databases[db].RPush(key, items)
+	return d.RPush(key, items)
 }
 
 func (d *Database) RPush(key string, items []string) (int, error) {
+	return d.push(key, items, false, false)
+}
+
+// push adds items to the head or the tail of the list at key. With
+// existing set it only does so when the list is already there, returning
+// 0 otherwise.
+func (d *Database) push(key string, items []string, left, existing bool) (int, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	entry, exists := d.data[key]
-	if !exists || entry.Value.Type != TypeList {
-		d.data[key] = Entry{
+	entry, exists, err := d.lookupType(key, TypeList)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		if existing {
+			return 0, nil
+		}
+		entry = Entry{
 			Value: Value{
 				Type: TypeList,
-				List: make([]string, 0),
+				List: newQuicklist(),
 			},
 		}
-		entry = d.data[key]
+	} else {
+		d.preserve(key)
 	}
 
-	entry.Value.List = append(entry.Value.List, items...)
-	d.data[key] = entry
-	return len(entry.Value.List), nil
+	for _, item := range items {
+		if left {
+			// Each item goes to the head in turn, so they end up reversed.
+			entry.Value.List.pushHead(item)
+		} else {
+			entry.Value.List.pushTail(item)
+		}
+	}
+	d.put(key, entry)
+	return entry.Value.List.Len(), nil
 }
 
 func (s *Storage) RLen(key string, db int) (int, error) {
-	if db >= 10 {
-		return 0, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
 	}
-	return s.databases[db].RLen(key)
+	return d.RLen(key)
 }
 
 func (d *Database) RLen(key string) (int, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	entry, ok := d.data[key]
-	if !ok || entry.Value.Type != TypeList {
-		return 0, nil
+	entry, ok, err := d.peekType(key, TypeList)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return 0, err
 	}
-	return len(entry.Value.List), nil
+	return entry.Value.List.Len(), nil
 }
 
-func (s *Storage) RRange(key string, from, to string, db int) (string, error) {
-	if db >= 10 {
-		return "", fmt.Errorf("invalid database %d", db)
+func (s *Storage) RRange(key string, from, to string, db int) ([]string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
 	}
 	fromInt, err := strconv.Atoi(from)
 	if err != nil {
-		return "", fmt.Errorf("invalid %d as from range", db)
+		return nil, fmt.Errorf("invalid %d as from range", db)
 	}
 	toInt, err := strconv.Atoi(to)
 	if err != nil {
-		return "", fmt.Errorf("invalid %d as to range", db)
+		return nil, fmt.Errorf("invalid %d as to range", db)
 	}
-	return s.databases[db].RRange(key, fromInt, toInt)
+	return d.RRange(key, fromInt, toInt)
 }
 
-func (d *Database) RRange(key string, from, to int) (string, error) {
+func (d *Database) RRange(key string, from, to int) ([]string, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	entry, ok := d.data[key]
-	if !ok || entry.Value.Type != TypeList {
-		return "", nil
+	entry, ok, err := d.peekType(key, TypeList)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return nil, err
 	}
 
 	list := entry.Value.List
-	n := len(list)
+	n := list.Len()
 
 	if from < 0 {
 		from += n
@@ -231,67 +364,50 @@ func (d *Database) RRange(key string, from, to int) (string, error) {
 	if to >= n {
 		to = n - 1
 	}
-	if from > to {
-		return "", nil
-	}
-
-	return strings.Join(list[from:to+1], ","), nil
+	return list.rangeOf(from, to), nil
 }
 
 func (s *Storage) LPush(key string, items []string, db int) (int, error) {
-	if db >= 10 {
-		return 0, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
 	}
-	return s.databases[db].LPush(key, items)
+	return d.LPush(key, items)
 }
 func (d *Database) LPush(key string, items []string) (int, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	entry, exists := d.data[key]
-	if !exists || entry.Value.Type != TypeList {
-		entry = Entry{
-			Value: Value{
-				Type: TypeList,
-				List: []string{},
-			},
-		}
-	}
-
-	entry.Value.List = append(items, entry.Value.List...)
-
-	d.data[key] = entry
-	return len(entry.Value.List), nil
+	return d.push(key, items, true, false)
 }
 
-func (s *Storage) LRange(key string, from, to string, db int) (string, error) {
-	if db >= 10 {
-		return "", fmt.Errorf("invalid database %d", db)
+func (s *Storage) LRange(key string, from, to string, db int) ([]string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
 	}
 	fromInt, err := strconv.Atoi(from)
 	if err != nil {
-		return "", fmt.Errorf("invalid %d as from range", db)
+		return nil, fmt.Errorf("invalid %d as from range", db)
 	}
 	toInt, err := strconv.Atoi(to)
 	if err != nil {
-		return "", fmt.Errorf("invalid %d as to range", db)
+		return nil, fmt.Errorf("invalid %d as to range", db)
 	}
-	return s.databases[db].LRange(key, fromInt, toInt)
+	return d.LRange(key, fromInt, toInt)
 }
 
-func (d *Database) LRange(key string, from, to int) (string, error) {
+func (d *Database) LRange(key string, from, to int) ([]string, error) {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
-	entry, ok := d.data[key]
-	if !ok || entry.Value.Type != TypeList {
-		return "", nil
+	entry, ok, err := d.peekType(key, TypeList)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return nil, err
 	}
 
 	list := entry.Value.List
-	n := len(list)
+	n := list.Len()
 	if n == 0 {
-		return "", nil
+		return nil, nil
 	}
 
 	if from < 0 {
@@ -307,183 +423,148 @@ func (d *Database) LRange(key string, from, to int) (string, error) {
 	if to >= n {
 		to = n - 1
 	}
-	if from > to {
-		return "", nil
-	}
-
-	return strings.Join(list[from:to+1], ","), nil
+	return list.rangeOf(from, to), nil
 }
 
 // TODO: add lpop and rpop
 func (s *Storage) LPOP(key string, count, db int) ([]string, error) {
-	if db >= 10 {
-		return nil, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
 	}
-	return s.databases[db].LPOP(key, count)
+	return d.LPOP(key, count)
 }
 
 func (d *Database) LPOP(key string, count int) ([]string, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	entry, exists := d.data[key]
-	if !exists || entry.Value.Type != TypeList {
-		return nil, nil
-	}
-
-	list := entry.Value.List
-	n := len(list)
-	if n == 0 {
-		return nil, nil
-	}
-
-	if count < 0 || count > n {
-		count = n
-	}
-
-	result := make([]string, count)
-
-	if count == 0 {
-		result = []string{list[0]}
-	} else {
-		copy(result, list[:count])
-	}
-
-	entry.Value.List = list[count:]
-	d.data[key] = entry
-
-	if len(entry.Value.List) == 0 {
-		delete(d.data, key)
-	}
-
-	return result, nil
+	return d.pop(key, count, true)
 }
 
 func (s *Storage) RPOP(key string, count, db int) ([]string, error) {
-	if db >= 10 {
-		return nil, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
 	}
-	return s.databases[db].RPOP(key, count)
+	return d.RPOP(key, count)
 }
 
 func (d *Database) RPOP(key string, count int) ([]string, error) {
+	return d.pop(key, count, false)
+}
+
+// pop removes up to count elements from the head or the tail of the list
+// at key, in the order they come off; a count of 0 pops one, and a
+// negative one the whole list. The key is deleted once the list is empty.
+func (d *Database) pop(key string, count int, head bool) ([]string, error) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	entry, exists := d.data[key]
-	if !exists || entry.Value.Type != TypeList {
-		return nil, nil
+	entry, exists, err := d.lookupType(key, TypeList)
+	if !exists || err != nil {
+		return nil, err
 	}
 
 	list := entry.Value.List
-	n := len(list)
-	if n == 0 {
-		return nil, nil
+	n := list.Len()
+	if count == 0 {
+		count = 1
 	}
-
 	if count < 0 || count > n {
 		count = n
 	}
 
-	start := n - count
+	d.preserve(key)
 	result := make([]string, count)
-	copy(result, list[start:])
-
-	entry.Value.List = list[:start]
-	d.data[key] = entry
+	for i := range result {
+		if head {
+			result[i] = list.popHead()
+		} else {
+			result[i] = list.popTail()
+		}
+	}
 
-	if len(entry.Value.List) == 0 {
-		delete(d.data, key)
+	if list.Len() == 0 {
+		d.remove(key)
+	} else {
+		d.put(key, entry)
 	}
 
 	return result, nil
 }
 
-func (s *Storage) BLPOP(key string, count, timeoutSec, db int) ([]string, error) {
-	if db >= 10 {
-		return nil, fmt.Errorf("invalid database %d", db)
+// BLPOP pops the head of the first non-empty list among keys, checked in
+// order, waiting for one of them to have an element. It returns which key
+// was served; ok is false once timeout passes, with 0 meaning no timeout,
+// and err is ctx.Err() as soon as ctx is done, so a closed connection or a
+// shutdown never leaves it waiting.
+func (s *Storage) BLPOP(ctx context.Context, keys []string, timeout time.Duration, db int) (key, value string, ok bool, err error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", "", false, err
 	}
-	return s.databases[db].BLPOP(key, count, timeoutSec)
+	return d.BLPOP(ctx, keys, timeout)
 }
 
-func (d *Database) BLPOP(key string, count, timeoutSec int) ([]string, error) {
-	if count <= 0 {
-		count = 1
-	}
-
-	deadline := time.Now().Add(time.Duration(timeoutSec) * time.Second)
-	if timeoutSec == 0 {
-		deadline = time.Time{}
-	}
-
-	for {
-		d.mu.RLock()
-		entry, exists := d.data[key]
-		hasItems := exists && entry.Value.Type == TypeList && len(entry.Value.List) >= count
-		d.mu.RUnlock()
-
-		if hasItems {
-			return d.LPOP(key, count)
-		}
-
-		if !deadline.IsZero() && time.Now().After(deadline) {
-			return nil, nil
-		}
-
-		time.Sleep(50 * time.Millisecond)
-	}
-}
-func (s *Storage) BRPOP(key string, count, timeoutSec, db int) ([]string, error) {
-	if db >= 10 {
-		return nil, fmt.Errorf("invalid database %d", db)
-	}
-	return s.databases[db].BRPOP(key, count, timeoutSec)
+func (d *Database) BLPOP(ctx context.Context, keys []string, timeout time.Duration) (key, value string, ok bool, err error) {
+	return d.blockingPop(ctx, keys, timeout, d.LPOP)
 }
 
-func (d *Database) BRPOP(key string, count, timeoutSec int) ([]string, error) {
-	if count <= 0 {
-		count = 1
-	}
-
-	deadline := time.Now().Add(time.Duration(timeoutSec) * time.Second)
-	if timeoutSec == 0 {
-		deadline = time.Time{}
+// BRPOP is BLPOP popping from the tail.
+func (s *Storage) BRPOP(ctx context.Context, keys []string, timeout time.Duration, db int) (key, value string, ok bool, err error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", "", false, err
 	}
+	return d.BRPOP(ctx, keys, timeout)
+}
 
-	for {
-		d.mu.RLock()
-		entry, exists := d.data[key]
-		hasItems := exists && entry.Value.Type == TypeList && len(entry.Value.List) >= count
-		d.mu.RUnlock()
-
-		if hasItems {
-			return d.RPOP(key, count)
-		}
+func (d *Database) BRPOP(ctx context.Context, keys []string, timeout time.Duration) (key, value string, ok bool, err error) {
+	return d.blockingPop(ctx, keys, timeout, d.RPOP)
+}
 
-		if !deadline.IsZero() && time.Now().After(deadline) {
-			return nil, nil
+func (d *Database) blockingPop(ctx context.Context, keys []string, timeout time.Duration, pop func(string, int) ([]string, error)) (key, value string, ok bool, err error) {
+	err = d.block(ctx, keys, timeout, func() (bool, error) {
+		for _, k := range keys {
+			items, err := pop(k, 1)
+			if err != nil {
+				return false, err
+			}
+			if len(items) > 0 {
+				key, value, ok = k, items[0], true
+				return true, nil
+			}
 		}
-
-		time.Sleep(50 * time.Millisecond)
-	}
+		return false, nil
+	})
+	return key, value, ok, err
 }
 
 func (s *Storage) TypeCmd(key string, db int) (*ValueType, error) {
-	return s.databases[db].TypeCmd(key)
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.TypeCmd(key)
 }
 
+// TypeCmd returns the type of the value at key, or ErrNoSuchKey. Like
+// EXISTS it does not count as an access to the key.
 func (d *Database) TypeCmd(key string) (*ValueType, error) {
 	d.mu.RLock()
-	item, ok := d.data[key]
+	item, ok := d.peek(key)
 	d.mu.RUnlock()
+	d.counters.read(ok)
 	if !ok {
-		return nil, errors.New("key does not exists")
+		return nil, ErrNoSuchKey
 	}
 	return &item.Value.Type, nil
 }
 
 func (s *Storage) XAdd(key, ID string, pairs [][2]string, db int) error {
-	return s.databases[db].XAdd(key, ID, pairs)
+	d, err := s.database(db)
+	if err != nil {
+		return err
+	}
+	return d.XAdd(key, ID, pairs)
 }
 
 func (d *Database) XAdd(key, ID string, pairs [][2]string) error {
@@ -492,7 +573,13 @@ func (d *Database) XAdd(key, ID string, pairs [][2]string) error {
 		The millisecondsTime portion of the new ID must be greater than or equal to the last entry's millisecondsTime.
 		If the millisecondsTime values are equal, the sequenceNumber of the new ID must be greater than the last entry's sequenceNumber.
 	*/
-	item, ok := d.data[key]
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, ok, err := d.lookupType(key, TypeStream)
+	if err != nil {
+		return err
+	}
 	if ID == "" {
 		// id is created by milisecond time stamp + - + sequence number
 		// first find last sequence
@@ -534,8 +621,8 @@ func (d *Database) XAdd(key, ID string, pairs [][2]string) error {
 	}
 
 	if !ok || len(item.Value.Streams) == 0 {
-		d.data[key] = Entry{
-			Value{
+		item = Entry{
+			Value: Value{
 				Type:    TypeStream,
 				Streams: make([]Stream, 0, len(pairs)),
 			},
@@ -546,9 +633,8 @@ func (d *Database) XAdd(key, ID string, pairs [][2]string) error {
 		ID:      ID,
 		Entries: pairs,
 	}
-	item = d.data[key]
 	item.Value.Streams = append(item.Value.Streams, stream)
-	d.data[key] = item
+	d.put(key, item)
 
 	return nil
 }
@@ -559,23 +645,25 @@ type XRangeResp struct {
 }
 
 func (s *Storage) XRange(key, start, end string, db int) ([]XRangeResp, error) {
-	if db >= 10 {
-		return nil, fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.databases[db].XRange(key, start, end)
+	return d.XRange(key, start, end)
 }
 
 func (d *Database) XRange(key, start, end string) ([]XRangeResp, error) {
 	d.mu.RLock()
-	item, ok := d.data[key]
+	item, ok, err := d.peekType(key, TypeStream)
 	d.mu.RUnlock()
+	d.read(key, item, ok)
+	if err != nil {
+		return nil, err
+	}
 	if !ok {
 		return nil, fmt.Errorf("%s not exists", key)
 	}
-	if len(item.Value.Streams) == 0 {
-		return nil, fmt.Errorf("%s is not stream", key)
-	}
 	found := make([]Stream, 0)
 	startInt, _ := strconv.Atoi(start)
 	endInt, _ := strconv.Atoi(end)
@@ -594,26 +682,50 @@ func (d *Database) XRange(key, start, end string) ([]XRangeResp, error) {
 }
 
 func (s *Storage) Incr(key string, db int) error {
-	if db >= 10 {
-		return fmt.Errorf("invalid database %d", db)
+	d, err := s.database(db)
+	if err != nil {
+		return err
 	}
 
-	return s.databases[db].Incr(key)
+	return d.Incr(key)
 }
 
 func (d *Database) Incr(key string) error {
-	d.mu.RLock()
-	item, ok := d.data[key]
-	d.mu.RUnlock()
+	_, err := d.IncrBy(key, 1)
+	return err
+}
 
-	if !ok {
-		d.mu.Lock()
-		d.data[key] = Entry{Value: Value{Type: TypeInt, Num: 1}}
-		d.mu.Unlock()
-	} else {
-		d.mu.Lock()
-		item.Value.Num++
-		d.mu.Unlock()
+func (s *Storage) IncrBy(key string, delta int64, db int) (int64, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
 	}
-	return nil
+	return d.IncrBy(key, delta)
+}
+
+// IncrBy adds delta to the integer stored at key, treating a missing key
+// as 0, and returns the result. The key keeps its TTL. A value that is not
+// an integer, or a result that overflows int64, fails with ErrNotInteger.
+func (d *Database) IncrBy(key string, delta int64) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	item, ok, err := d.lookupType(key, TypeString)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	if ok {
+		if n, err = item.Value.asInt(); err != nil {
+			return 0, err
+		}
+	}
+	if n, err = addInt64(n, delta); err != nil {
+		return 0, err
+	}
+	item.Value.Type = TypeInt
+	item.Value.Num = n
+	item.Value.String = ""
+	d.put(key, item)
+	return n, nil
 }
@@ -1,6 +1,14 @@
 package storage
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -103,22 +111,22 @@ func TestStorage_Del(t *testing.T) {
 	s.Set("key2", "val2", 100*time.Second, 0)
 	s.Set("key3", "val3", 100*time.Second, 1)
 
-	if s.Del("key1", 0) != 1 {
+	if s.Del([]string{"key1"}, 0) != 1 {
 		t.Fatal("Del should return 1")
 	}
-	if s.Del("key1", 0) != 0 {
+	if s.Del([]string{"key1"}, 0) != 0 {
 		t.Fatal("Del on missing key should return 0")
 	}
-	if s.Del("key2", 0) != 1 {
+	if s.Del([]string{"key2"}, 0) != 1 {
 		t.Fatal("Del should return 1")
 	}
-	if s.Del("key3", 0) != 0 {
+	if s.Del([]string{"key3"}, 0) != 0 {
 		t.Fatal("Del on wrong db should return 0")
 	}
-	if s.Del("key3", 1) != 1 {
+	if s.Del([]string{"key3"}, 1) != 1 {
 		t.Fatal("Del should return 1 in correct db")
 	}
-	if s.Del("key3", 1) != 0 {
+	if s.Del([]string{"key3"}, 1) != 0 {
 		t.Fatal("second Del should return 0")
 	}
 
@@ -131,13 +139,21 @@ func TestStorage_Del(t *testing.T) {
 	if entry, err := s.Get("key3", 0); entry != nil || err != nil {
 		t.Fatal("key3 should be deleted")
 	}
+
+	s.Set("a", "v", 0, 0)
+	s.Set("b", "v", 0, 0)
+	s.Set("gone", "v", time.Millisecond, 0)
+	time.Sleep(5 * time.Millisecond)
+	if n := s.Del([]string{"a", "missing", "b", "a", "gone"}, 0); n != 2 {
+		t.Fatalf("Del of several keys = %d, want 2", n)
+	}
 }
 
 func TestStorage_Del_InvalidDB(t *testing.T) {
 	s := NewStorage()
 	s.Set("key", "value", 100*time.Second, 0)
 
-	if s.Del("key", 999) != 0 {
+	if s.Del([]string{"key"}, 999) != 0 {
 		t.Fatal("Del on invalid db should return 0")
 	}
 	if entry, err := s.Get("key", 0); entry == nil || err != nil {
@@ -152,8 +168,8 @@ func TestStorage_Del_Concurrent(t *testing.T) {
 	done := make(chan bool, 100)
 	for i := 0; i < 100; i++ {
 		go func() {
-			s.Del("key", 0)
-			s.Del("missing", 0)
+			s.Del([]string{"key"}, 0)
+			s.Del([]string{"missing"}, 0)
 			done <- true
 		}()
 	}
@@ -242,21 +258,21 @@ func TestRRange_NegativeIndices(t *testing.T) {
 	tests := []struct {
 		from string
 		to   string
-		want string
+		want []string
 	}{
 
-		{"0", "-1", "a,b,c,d,e"},
-		{"0", "4", "a,b,c,d,e"},
-		{"1", "3", "b,c,d"},
-		{"-3", "-1", "c,d,e"},
-		{"-5", "-1", "a,b,c,d,e"},
-		{"-1", "-1", "e"},
-		{"-2", "-2", "d"},
-		{"0", "0", "a"},
-		{"-10", "-1", "a,b,c,d,e"},
-		{"0", "100", "a,b,c,d,e"},
-		{"5", "10", ""},
-		{"-1", "-5", ""},
+		{"0", "-1", []string{"a", "b", "c", "d", "e"}},
+		{"0", "4", []string{"a", "b", "c", "d", "e"}},
+		{"1", "3", []string{"b", "c", "d"}},
+		{"-3", "-1", []string{"c", "d", "e"}},
+		{"-5", "-1", []string{"a", "b", "c", "d", "e"}},
+		{"-1", "-1", []string{"e"}},
+		{"-2", "-2", []string{"d"}},
+		{"0", "0", []string{"a"}},
+		{"-10", "-1", []string{"a", "b", "c", "d", "e"}},
+		{"0", "100", []string{"a", "b", "c", "d", "e"}},
+		{"5", "10", nil},
+		{"-1", "-5", nil},
 	}
 
 	for _, tt := range tests {
@@ -265,18 +281,18 @@ func TestRRange_NegativeIndices(t *testing.T) {
 			t.Errorf("RRange(%q, %q) error: %v", tt.from, tt.to, err)
 			continue
 		}
-		if got != tt.want {
+		if !slices.Equal(got, tt.want) {
 			t.Errorf("RRange(%q, %q) = %q, want %q", tt.from, tt.to, got, tt.want)
 		}
 	}
 
 	s.RPush("empty", []string{}, 0)
-	if got, _ := s.RRange("empty", "0", "-1", 0); got != "" {
-		t.Errorf("empty list should return empty string, got %q", got)
+	if got, _ := s.RRange("empty", "0", "-1", 0); len(got) != 0 {
+		t.Errorf("empty list should return no elements, got %q", got)
 	}
 
-	if got, _ := s.RRange("missing", "0", "-1", 0); got != "" {
-		t.Errorf("missing key should return empty string, got %q", got)
+	if got, _ := s.RRange("missing", "0", "-1", 0); len(got) != 0 {
+		t.Errorf("missing key should return no elements, got %q", got)
 	}
 }
 func TestLRange(t *testing.T) {
@@ -287,25 +303,50 @@ func TestLRange(t *testing.T) {
 	tests := []struct {
 		from string
 		to   string
-		want string
+		want []string
 	}{
-		{"0", "-1", "a,b,c,d,e"},
-		{"1", "3", "b,c,d"},
-		{"-3", "-1", "c,d,e"},
-		{"-1", "-1", "e"},
-		{"0", "0", "a"},
-		{"-5", "-3", "a,b,c"},
-		{"-10", "10", "a,b,c,d,e"},
-		{"5", "10", ""},
-		{"-1", "-5", ""},
+		{"0", "-1", []string{"a", "b", "c", "d", "e"}},
+		{"1", "3", []string{"b", "c", "d"}},
+		{"-3", "-1", []string{"c", "d", "e"}},
+		{"-1", "-1", []string{"e"}},
+		{"0", "0", []string{"a"}},
+		{"-5", "-3", []string{"a", "b", "c"}},
+		{"-10", "10", []string{"a", "b", "c", "d", "e"}},
+		{"5", "10", nil},
+		{"-1", "-5", nil},
 	}
 
 	for _, tt := range tests {
 		got, _ := s.LRange("mylist", tt.from, tt.to, 0)
-		if got != tt.want {
+		if !slices.Equal(got, tt.want) {
 			t.Errorf("LRange(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
 		}
 	}
+
+	s.RPush("commas", []string{"a,b", "", "c"}, 0)
+	if got, _ := s.LRange("commas", "0", "-1", 0); !slices.Equal(got, []string{"a,b", "", "c"}) {
+		t.Errorf("LRange = %q, want the elements unchanged", got)
+	}
+}
+
+func TestLPush(t *testing.T) {
+	s := NewStorage()
+	s.RPush("l", []string{"c"}, 0)
+	if n, err := s.LPush("l", []string{"b", "a"}, 0); n != 3 || err != nil {
+		t.Fatalf("LPush = %d %v, want 3", n, err)
+	}
+	if got, _ := s.LRange("l", "0", "-1", 0); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("list = %q, want [a b c]", got)
+	}
+	if n, _ := s.RPushX("l", []string{"d"}, 0); n != 4 {
+		t.Fatalf("RPushX = %d, want 4", n)
+	}
+	if n, _ := s.LPushX("missing", []string{"a"}, 0); n != 0 {
+		t.Fatalf("LPushX on a missing key = %d, want 0", n)
+	}
+	if e, _ := s.Get("missing", 0); e != nil {
+		t.Fatal("LPushX created a missing key")
+	}
 }
 
 func TestLPOP(t *testing.T) {
@@ -336,3 +377,1536 @@ func TestLPOP(t *testing.T) {
 	}
 
 }
+
+func TestStorage_ConfigurableDatabases(t *testing.T) {
+	s := NewStorageWithConfig(Config{Databases: 16})
+	if s.Databases() != 16 {
+		t.Fatalf("expected 16 databases, got %d", s.Databases())
+	}
+	if err := s.Set("k", "v", 0, 15); err != nil {
+		t.Fatalf("db 15 should be valid: %v", err)
+	}
+	if err := s.Set("k", "v", 0, 16); err == nil {
+		t.Fatal("expected error for db 16")
+	}
+	if err := s.Set("k", "v", 0, -1); err == nil {
+		t.Fatal("expected error for negative db")
+	}
+
+	if got := NewStorageWithConfig(Config{}).Databases(); got != DefaultDatabases {
+		t.Fatalf("zero config should fall back to %d databases, got %d", DefaultDatabases, got)
+	}
+}
+
+func TestStorage_KeyspaceStats(t *testing.T) {
+	s := NewStorage()
+
+	s.Set("plain", "v", 0, 0)
+	s.Set("ttl1", "v", 10*time.Second, 0)
+	s.Set("ttl2", "v", 20*time.Second, 0)
+	s.RPush("list", []string{"a"}, 0)
+
+	stats, err := s.Stats(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Keys != 4 || stats.Expires != 2 {
+		t.Fatalf("got keys=%d expires=%d, want 4 and 2", stats.Keys, stats.Expires)
+	}
+	if stats.AvgTTL < 14*time.Second || stats.AvgTTL > 15*time.Second {
+		t.Fatalf("avg ttl %v out of range", stats.AvgTTL)
+	}
+
+	s.Set("ttl1", "v", 0, 0)
+	s.Del([]string{"ttl2"}, 0)
+	s.LPOP("list", 1, 0)
+	stats, _ = s.Stats(0)
+	if stats.Keys != 2 || stats.Expires != 0 || stats.AvgTTL != 0 {
+		t.Fatalf("counters not updated: %+v", stats)
+	}
+
+	s.Flush()
+	if size, _ := s.DBSize(0); size != 0 {
+		t.Fatalf("expected empty db after flush, got %d", size)
+	}
+}
+
+func TestStorage_Counters(t *testing.T) {
+	s := NewStorage()
+
+	s.Set("k", "v", 0, 0)
+	s.Set("temp", "v", 10*time.Millisecond, 1)
+	s.RPush("list", []string{"a"}, 0)
+
+	s.Get("k", 0)
+	s.Get("missing", 0)
+	s.RLen("list", 0)
+	s.LRange("nolist", "0", "-1", 0)
+	time.Sleep(20 * time.Millisecond)
+	s.Get("temp", 1)
+
+	want := Counters{Hits: 2, Misses: 3, Expired: 1}
+	if got := s.Counters(); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	s.ResetCounters()
+	if got := s.Counters(); got != (Counters{}) {
+		t.Fatalf("got %+v after reset, want zero", got)
+	}
+}
+
+func TestStorage_WrongType(t *testing.T) {
+	s := NewStorage()
+	s.Set("str", "v", 0, 0)
+	s.RPush("list", []string{"a"}, 0)
+	s.XAdd("stream", "1-0", [][2]string{{"f", "v"}}, 0)
+
+	checks := map[string]error{}
+	_, checks["RPUSH"] = s.RPush("str", []string{"x"}, 0)
+	_, checks["LPUSH"] = s.LPush("str", []string{"x"}, 0)
+	_, checks["LPUSHX"] = s.LPushX("str", []string{"x"}, 0)
+	_, checks["RPUSHX"] = s.RPushX("stream", []string{"x"}, 0)
+	_, checks["RLEN"] = s.RLen("str", 0)
+	_, checks["RRANGE"] = s.RRange("str", "0", "-1", 0)
+	_, checks["LRANGE"] = s.LRange("stream", "0", "-1", 0)
+	_, checks["LPOP"] = s.LPOP("str", 1, 0)
+	_, checks["RPOP"] = s.RPOP("str", 1, 0)
+	_, _, _, checks["BLPOP"] = s.BLPOP(context.Background(), []string{"missing", "str"}, time.Second, 0)
+	_, checks["XRANGE"] = s.XRange("list", "0", "+", 0)
+	checks["XADD"] = s.XAdd("str", "", nil, 0)
+	checks["INCR"] = s.Incr("list", 0)
+	for name, err := range checks {
+		if !errors.Is(err, ErrWrongType) {
+			t.Errorf("%s: expected ErrWrongType, got %v", name, err)
+		}
+	}
+
+	if e, _ := s.Get("str", 0); e == nil || e.Value.String != "v" {
+		t.Fatalf("a failed RPUSH must leave the string alone, got %v", e)
+	}
+	if err := s.Incr("str", 0); !errors.Is(err, ErrNotInteger) {
+		t.Fatalf("expected ErrNotInteger for a non-numeric string, got %v", err)
+	}
+}
+
+func TestStorage_Subscribe(t *testing.T) {
+	s := NewStorage()
+
+	var events []Event
+	unsubscribe := s.Subscribe(SubscriberFunc(func(ev Event) {
+		events = append(events, ev)
+	}))
+
+	s.Set("str", "v", 0, 1)
+	s.RPush("list", []string{"a"}, 0)
+	s.LPOP("list", 1, 0)
+	s.Set("temp", "v", 10*time.Millisecond, 0)
+	time.Sleep(20 * time.Millisecond)
+	s.Get("temp", 0)
+	s.Del([]string{"str"}, 1)
+
+	want := []Event{
+		{Kind: EventModified, DB: 1, Key: "str", Type: TypeString},
+		{Kind: EventModified, DB: 0, Key: "list", Type: TypeList},
+		{Kind: EventDeleted, DB: 0, Key: "list", Type: TypeList},
+		{Kind: EventModified, DB: 0, Key: "temp", Type: TypeString},
+		{Kind: EventExpired, DB: 0, Key: "temp", Type: TypeString},
+		{Kind: EventDeleted, DB: 1, Key: "str", Type: TypeString},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+
+	unsubscribe()
+	s.Set("after", "v", 0, 0)
+	if len(events) != len(want) {
+		t.Fatal("subscriber notified after unsubscribe")
+	}
+}
+
+func TestStorage_Snapshot(t *testing.T) {
+	s := NewStorage()
+
+	s.Set("a", "1", 0, 0)
+	s.Set("gone", "1", time.Millisecond, 0)
+	s.RPush("list", []string{"x", "y"}, 3)
+	time.Sleep(5 * time.Millisecond)
+
+	seen := make(map[string]Entry)
+	err := s.Snapshot(func(db int, key string, e Entry) error {
+		seen[fmt.Sprintf("%d/%s", db, key)] = e
+		// writes during the walk must neither deadlock nor leak into the copy
+		if db == 3 {
+			s.RPush("list", []string{"z"}, 3)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 2 {
+		t.Fatalf("expected 2 live entries, got %v", seen)
+	}
+	if seen["0/a"].Value.String != "1" {
+		t.Fatalf("unexpected value for a: %+v", seen["0/a"])
+	}
+	if got := seen["3/list"].Value.List.Values(); len(got) != 2 {
+		t.Fatalf("snapshot list should not see later pushes, got %v", got)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = s.Snapshot(func(int, string, Entry) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Fatalf("expected walk to stop after first error, got err=%v calls=%d", err, calls)
+	}
+}
+
+func TestStorage_IntEncoding(t *testing.T) {
+	s := NewStorage()
+	db := s.databases[0]
+
+	s.Set("counter", "41", 0, 0)
+	if v := db.data["counter"].Value; v.Type != TypeInt || v.Num != 41 || v.String != "" {
+		t.Fatalf("expected int encoding, got %+v", v)
+	}
+	if err := s.Incr("counter", 0); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := s.Get("counter", 0); e.Value.String != "42" {
+		t.Fatalf("got %q, want 42", e.Value.String)
+	}
+
+	for _, raw := range []string{"007", "+1", "-0", " 1", "99999999999999999999", "abc"} {
+		s.Set("raw", raw, 0, 0)
+		if v := db.data["raw"].Value; v.Type != TypeString {
+			t.Errorf("%q should stay a raw string, got %+v", raw, v)
+		}
+		if e, _ := s.Get("raw", 0); e.Value.String != raw {
+			t.Errorf("%q did not round-trip, got %q", raw, e.Value.String)
+		}
+	}
+
+	if err := s.Incr("raw", 0); err != ErrNotInteger {
+		t.Fatalf("expected ErrNotInteger, got %v", err)
+	}
+	s.Set("max", "9223372036854775807", 0, 0)
+	if err := s.Incr("max", 0); err != ErrNotInteger {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+}
+
+func TestStorage_IncrBy(t *testing.T) {
+	s := NewStorage()
+
+	if n, err := s.IncrBy("n", -5, 0); err != nil || n != -5 {
+		t.Fatalf("IncrBy on a missing key = %d %v, want -5", n, err)
+	}
+	s.Expire("n", time.Minute, 0)
+	if n, _ := s.IncrBy("n", 15, 0); n != 10 {
+		t.Fatalf("got %d, want 10", n)
+	}
+	if ttl, _, _ := s.TTL("n", 0); ttl <= 0 {
+		t.Fatal("IncrBy dropped the TTL")
+	}
+	s.Set("min", "-9223372036854775808", 0, 0)
+	if _, err := s.IncrBy("min", -1, 0); err != ErrNotInteger {
+		t.Fatalf("expected overflow error, got %v", err)
+	}
+}
+
+func TestStorage_CompareAndSet(t *testing.T) {
+	s := NewStorage()
+
+	if ok, _ := s.SetIfAbsent("lock", "owner-1", 0, 0); !ok {
+		t.Fatal("first SetIfAbsent should succeed")
+	}
+	if ok, _ := s.SetIfAbsent("lock", "owner-2", 0, 0); ok {
+		t.Fatal("second SetIfAbsent should fail")
+	}
+
+	v1, _ := s.Version("lock", 0)
+	if v1 == 0 {
+		t.Fatal("existing key must have a non-zero version")
+	}
+
+	ok, v2, err := s.CompareAndSet("lock", "owner-3", 0, v1, 0)
+	if err != nil || !ok || v2 <= v1 {
+		t.Fatalf("CAS at current version failed: ok=%v v2=%d err=%v", ok, v2, err)
+	}
+	if ok, current, _ := s.CompareAndSet("lock", "owner-4", 0, v1, 0); ok || current != v2 {
+		t.Fatalf("CAS at stale version should fail and report %d, got ok=%v current=%d", v2, ok, current)
+	}
+	if e, _ := s.Get("lock", 0); e.Value.String != "owner-3" {
+		t.Fatalf("got %q, want owner-3", e.Value.String)
+	}
+
+	s.Del([]string{"lock"}, 0)
+	if v, _ := s.Version("lock", 0); v != 0 {
+		t.Fatalf("deleted key should report version 0, got %d", v)
+	}
+
+	s.Set("temp", "v", time.Millisecond, 0)
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := s.SetIfAbsent("temp", "fresh", 0, 0); !ok {
+		t.Fatal("expired key should count as absent")
+	}
+}
+
+func TestStorage_SnapshotPointInTime(t *testing.T) {
+	s := NewStorage()
+	for i := 0; i < 3*snapshotBatch; i++ {
+		s.Set(fmt.Sprintf("key:%d", i), "old", 0, 0)
+	}
+	s.RPush("list", []string{"a", "b"}, 1)
+
+	seen := make(map[string]string)
+	first := true
+	err := s.Snapshot(func(db int, key string, e Entry) error {
+		if first {
+			first = false
+			// rewrite everything while the walk is in progress
+			for i := 0; i < 3*snapshotBatch; i++ {
+				k := fmt.Sprintf("key:%d", i)
+				if i%2 == 0 {
+					s.Set(k, "new", 0, 0)
+				} else {
+					s.Del([]string{k}, 0)
+				}
+			}
+			s.Set("created", "later", 0, 0)
+			s.RPush("list", []string{"c"}, 1)
+			s.Flush()
+		}
+		if e.Value.Type == TypeList {
+			seen[key] = fmt.Sprint(e.Value.List.Values())
+		} else {
+			seen[key] = e.Value.String
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 3*snapshotBatch+1 {
+		t.Fatalf("expected %d entries, got %d", 3*snapshotBatch+1, len(seen))
+	}
+	for key, val := range seen {
+		if key == "list" {
+			if val != "[a b]" {
+				t.Errorf("list = %s, want [a b]", val)
+			}
+			continue
+		}
+		if val != "old" {
+			t.Errorf("%s = %q, want old", key, val)
+		}
+	}
+	if len(s.databases[0].views) != 0 {
+		t.Fatal("views should be released after the walk")
+	}
+}
+
+func TestStorage_JSONRoundTrip(t *testing.T) {
+	s := NewStorage()
+	s.Set("b", "text", 0, 0)
+	s.Set("a", "42", time.Hour, 0)
+	s.RPush("list", []string{"x", "y"}, 2)
+	s.XAdd("events", "1-0", [][2]string{{"f", "v"}}, 1)
+	s.HSet("hash", [][2]string{{"f", "v"}, {"g", "w"}}, 0)
+	s.HExpireAt("hash", []string{"g"}, time.Now().Add(time.Hour), ExpireAlways, 0)
+
+	var first bytes.Buffer
+	if err := s.ExportJSON(&first); err != nil {
+		t.Fatal(err)
+	}
+	if i, j := strings.Index(first.String(), `"a"`), strings.Index(first.String(), `"b"`); i < 0 || i > j {
+		t.Fatalf("keys are not sorted:\n%s", first.String())
+	}
+
+	loaded := NewStorage()
+	if err := loaded.ImportJSON(bytes.NewReader(first.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	var second bytes.Buffer
+	if err := loaded.ExportJSON(&second); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Fatalf("round trip changed the dump:\n%s\nvs\n%s", first.String(), second.String())
+	}
+	if e, _ := loaded.Get("a", 0); e == nil || e.Value.Type != TypeInt || e.Value.Expiry.IsZero() {
+		t.Fatalf("a lost its encoding or TTL: %+v", e)
+	}
+	if ats, _ := loaded.HExpiryAt("hash", []string{"f", "g"}, 0); ats[0] == nil || !ats[0].IsZero() || ats[1] == nil || ats[1].IsZero() {
+		t.Fatalf("hash fields lost their TTLs: %v", ats)
+	}
+
+	bad := `{"keys":[{"db":0,"key":"ok","type":"string","value":"v"},{"db":99,"key":"k","type":"string","value":"v"}]}`
+	if err := loaded.ImportJSON(strings.NewReader(bad)); err == nil {
+		t.Fatal("expected an error for an out of range database")
+	}
+	if e, _ := loaded.Get("ok", 0); e != nil {
+		t.Fatal("a failed import must not write anything")
+	}
+
+	expired := `{"keys":[{"db":0,"key":"old","type":"string","expire_at":1,"value":"v"}]}`
+	if err := loaded.ImportJSON(strings.NewReader(expired)); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := loaded.Get("old", 0); e != nil {
+		t.Fatal("expired keys should be skipped")
+	}
+
+	empty := `{"keys":[{"db":0,"key":"nolist","type":"list","value":[]}]}`
+	if err := loaded.ImportJSON(strings.NewReader(empty)); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := loaded.Get("nolist", 0); e != nil {
+		t.Fatal("empty lists should be skipped")
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	cases := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "", true},
+		{"user:*", "user:42", true},
+		{"user:*", "users:42", false},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-c]llo", "hbllo", true},
+		{"h[a-c]llo", "hdllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"*:*:end", "a:b:end", true},
+		{"a**b", "axxb", true},
+		{"*a*b", "aab", true},
+		{"*a*b", "aba", false},
+		{"a*b*c", "abxbc", true},
+		{"*[0-9]", "key1", true},
+		{"*[0-9]", "key", false},
+		{`*\*`, "a*", true},
+		{"?*", "", false},
+		{"*?", "x", true},
+		{"**", "", true},
+	}
+	for _, c := range cases {
+		if got := MatchPattern(c.pattern, c.s); got != c.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", c.pattern, c.s, got, c.want)
+		}
+	}
+}
+
+// TestMatchPattern_ManyStars guards against backtracking into every *,
+// which takes exponential time and would let one SCAN MATCH stall a
+// database behind its read lock.
+func TestMatchPattern_ManyStars(t *testing.T) {
+	pattern := strings.Repeat("*a", 20) + "*b"
+	s := strings.Repeat("a", 1000)
+	start := time.Now()
+	if MatchPattern(pattern, s) {
+		t.Fatalf("MatchPattern(%q, %q) = true, want false", pattern, s)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("MatchPattern took %v", elapsed)
+	}
+}
+
+func TestStorage_Scan(t *testing.T) {
+	s := NewStorage()
+	for i := range 1000 {
+		s.Set(fmt.Sprintf("key:%d", i), "v", 0, 0)
+	}
+	s.RPush("list:1", []string{"a"}, 0)
+	s.Set("gone", "v", time.Millisecond, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	scanAll := func(opts ScanOptions) map[string]int {
+		seen := map[string]int{}
+		var cursor uint64
+		for {
+			next, keys, err := s.Scan(cursor, opts, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range keys {
+				seen[k]++
+			}
+			if cursor = next; cursor == 0 {
+				return seen
+			}
+		}
+	}
+	if seen := scanAll(ScanOptions{Count: 7}); len(seen) != 1001 || seen["gone"] != 0 {
+		t.Fatalf("scan saw %d keys, want 1001 live ones", len(seen))
+	}
+	if seen := scanAll(ScanOptions{Match: "key:99*"}); len(seen) != 11 {
+		t.Fatalf("MATCH key:99* saw %d keys, want 11", len(seen))
+	}
+	if seen := scanAll(ScanOptions{Type: "list"}); len(seen) != 1 || seen["list:1"] != 1 {
+		t.Fatalf("TYPE list saw %v", seen)
+	}
+}
+
+// TestStorage_ScanWhileResizing checks the SCAN guarantee: keys present for
+// the whole iteration are returned even as the index grows and shrinks.
+func TestStorage_ScanWhileResizing(t *testing.T) {
+	s := NewStorage()
+	for i := range 100 {
+		s.Set(fmt.Sprintf("stable:%d", i), "v", 0, 0)
+	}
+
+	seen := map[string]bool{}
+	sizes := map[int]bool{}
+	var cursor uint64
+	for step := 0; ; step++ {
+		// alternate bursts of inserts and deletes so the table resizes
+		// in both directions between calls
+		for i := range 3000 {
+			key := fmt.Sprintf("churn:%d", i)
+			if step%4 < 2 {
+				s.Set(key, "v", 0, 0)
+			} else {
+				s.Del([]string{key}, 0)
+			}
+		}
+		sizes[len(s.databases[0].keys.buckets)] = true
+		next, keys, _ := s.Scan(cursor, ScanOptions{Count: 20}, 0)
+		for _, k := range keys {
+			seen[k] = true
+		}
+		if cursor = next; cursor == 0 {
+			break
+		}
+	}
+	if len(sizes) < 2 {
+		t.Fatalf("the index never resized: bucket counts %v", sizes)
+	}
+	for i := range 100 {
+		if key := fmt.Sprintf("stable:%d", i); !seen[key] {
+			t.Fatalf("scan missed %s", key)
+		}
+	}
+}
+
+func TestStorage_BlockingPop(t *testing.T) {
+	s := NewStorage()
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.RPush("jobs", []string{"a", "b"}, 0)
+	}()
+	key, v, ok, err := s.BLPOP(context.Background(), []string{"other", "jobs"}, time.Second, 0)
+	if err != nil || !ok || key != "jobs" || v != "a" {
+		t.Fatalf("BLPOP = %q %q %v %v, want jobs a", key, v, ok, err)
+	}
+	if _, v, _, _ := s.BRPOP(context.Background(), []string{"jobs"}, 0, 0); v != "b" {
+		t.Fatalf("BRPOP = %q, want b", v)
+	}
+
+	// The first non-empty key in argument order is served.
+	s.RPush("k2", []string{"two"}, 0)
+	s.RPush("k1", []string{"one"}, 0)
+	if key, v, _, _ := s.BLPOP(context.Background(), []string{"k0", "k1", "k2"}, 0, 0); key != "k1" || v != "one" {
+		t.Fatalf("BLPOP = %q %q, want k1 one", key, v)
+	}
+
+	start := time.Now()
+	if _, _, ok, err := s.BLPOP(context.Background(), []string{"jobs"}, 30*time.Millisecond, 0); ok || err != nil {
+		t.Fatalf("expected a timeout, got %v %v", ok, err)
+	}
+	if time.Since(start) < 30*time.Millisecond {
+		t.Fatal("BLPOP returned before its timeout")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start = time.Now()
+	if _, _, _, err := s.BRPOP(ctx, []string{"jobs"}, 0, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("cancellation did not unblock BRPOP promptly")
+	}
+}
+
+func TestStorage_HotKeys(t *testing.T) {
+	s := NewStorage()
+	if s.HotKeys() != nil {
+		t.Fatal("expected no hot keys while tracking is off")
+	}
+
+	s.TrackHotKeys(3)
+	for i := range 500 {
+		s.Set(fmt.Sprintf("cold:%d", i), "v", 0, 0)
+	}
+	for range 50 {
+		s.Get("hot", 0)
+		s.Set("hot", "v", 0, 0)
+		s.Get("warm", 1)
+		s.Set("warm", "v", 0, 1)
+	}
+	for range 20 {
+		s.Get("warm", 1)
+	}
+
+	got := s.HotKeys()
+	if len(got) != 3 {
+		t.Fatalf("got %d hot keys, want 3: %+v", len(got), got)
+	}
+	if got[0].Key != "warm" || got[0].DB != 1 || got[1].Key != "hot" || got[1].DB != 0 {
+		t.Fatalf("got %+v, want warm then hot", got)
+	}
+	if got[0].Count < 119 || got[1].Count < 99 { // the first GET of each misses
+		t.Fatalf("counts %d and %d are below the true access counts", got[0].Count, got[1].Count)
+	}
+
+	s.TrackHotKeys(0)
+	if s.HotKeys() != nil {
+		t.Fatal("expected tracking to stop")
+	}
+}
+
+func TestHotKeysRowCollision(t *testing.T) {
+	h := newHotKeys(2)
+	// Find two keys that share a counter in the first row only.
+	first := make(map[uint64]string)
+	var a, b string
+	for i := 0; b == "" && i < 100000; i++ {
+		key := fmt.Sprintf("k%d", i)
+		cols := h.columns(0, key)
+		other, ok := first[cols[0]]
+		if !ok {
+			first[cols[0]] = key
+			continue
+		}
+		oc := h.columns(0, other)
+		if oc[1] != cols[1] && oc[2] != cols[2] && oc[3] != cols[3] {
+			a, b = other, key
+		}
+	}
+	if b == "" {
+		t.Fatal("keys that collide in one row collide in every row")
+	}
+
+	for range 100 {
+		h.record(0, a)
+	}
+	h.record(0, b)
+	got := h.list()
+	if len(got) != 2 || got[0].Key != a || got[0].Count != 100 || got[1].Key != b || got[1].Count != 1 {
+		t.Fatalf("got %+v, want %s counted 100 times and %s once", got, a, b)
+	}
+}
+
+func TestStorage_Expire(t *testing.T) {
+	s := NewStorage()
+	s.Set("k", "v", 0, 0)
+
+	if ttl, ok, _ := s.TTL("k", 0); !ok || ttl >= 0 {
+		t.Fatalf("TTL = %v %v, want a key without expiry", ttl, ok)
+	}
+	if ok, _ := s.Expire("k", time.Minute, 0); !ok {
+		t.Fatal("Expire on an existing key returned false")
+	}
+	if ttl, _, _ := s.TTL("k", 0); ttl <= 59*time.Second || ttl > time.Minute {
+		t.Fatalf("TTL = %v, want about a minute", ttl)
+	}
+	if stats, _ := s.Stats(0); stats.Expires != 1 {
+		t.Fatalf("expires = %d, want 1", stats.Expires)
+	}
+	if ok, _ := s.Persist("k", 0); !ok {
+		t.Fatal("Persist on a volatile key returned false")
+	}
+	if ok, _ := s.Persist("k", 0); ok {
+		t.Fatal("Persist on a persistent key returned true")
+	}
+	if stats, _ := s.Stats(0); stats.Expires != 0 {
+		t.Fatalf("expires = %d after Persist, want 0", stats.Expires)
+	}
+
+	if ok, _ := s.Expire("missing", time.Minute, 0); ok {
+		t.Fatal("Expire on a missing key returned true")
+	}
+	if _, ok, _ := s.TTL("missing", 0); ok {
+		t.Fatal("TTL found a missing key")
+	}
+
+	s.Expire("k", 10*time.Millisecond, 0)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := s.TTL("k", 0); ok {
+		t.Fatal("key outlived its TTL")
+	}
+
+	s.Set("k", "v", 0, 0)
+	if ok, _ := s.Expire("k", -time.Second, 0); !ok {
+		t.Fatal("Expire with a negative TTL returned false")
+	}
+	if e, _ := s.Get("k", 0); e != nil {
+		t.Fatal("a negative TTL did not delete the key")
+	}
+}
+
+func TestStorage_SetExpiryAt(t *testing.T) {
+	s := NewStorage()
+	s.Set("k", "v", 0, 0)
+
+	if at, ok, _ := s.ExpiryAt("k", 0); !ok || !at.IsZero() {
+		t.Fatalf("ExpiryAt = %v %v, want a key without expiry", at, ok)
+	}
+	want := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	if ok, _ := s.SetExpiryAt("k", want, 0); !ok {
+		t.Fatal("SetExpiryAt on an existing key returned false")
+	}
+	if at, _, _ := s.ExpiryAt("k", 0); !at.Equal(want) {
+		t.Fatalf("ExpiryAt = %v, want %v", at, want)
+	}
+	if ok, _ := s.SetExpiryAt("missing", want, 0); ok {
+		t.Fatal("SetExpiryAt on a missing key returned true")
+	}
+	if _, ok, _ := s.ExpiryAt("missing", 0); ok {
+		t.Fatal("ExpiryAt found a missing key")
+	}
+	if ok, _ := s.SetExpiryAt("k", time.Now().Add(-time.Second), 0); !ok {
+		t.Fatal("SetExpiryAt in the past returned false")
+	}
+	if e, _ := s.Get("k", 0); e != nil {
+		t.Fatal("an expiry in the past did not delete the key")
+	}
+}
+
+func TestStorage_SetWithOptions(t *testing.T) {
+	s := NewStorage()
+
+	res, err := s.SetWithOptions("k", "v1", SetOptions{Cond: SetIfExists}, 0)
+	if err != nil || res.Set {
+		t.Fatalf("XX on a missing key: %+v %v", res, err)
+	}
+	res, _ = s.SetWithOptions("k", "v1", SetOptions{Cond: SetIfAbsent, Expiry: time.Now().Add(time.Minute)}, 0)
+	if !res.Set {
+		t.Fatal("NX on a missing key did not set it")
+	}
+	res, _ = s.SetWithOptions("k", "v2", SetOptions{Cond: SetIfAbsent, Get: true}, 0)
+	if res.Set || !res.Existed || res.Old != "v1" {
+		t.Fatalf("NX GET on an existing key: %+v", res)
+	}
+
+	res, _ = s.SetWithOptions("k", "v3", SetOptions{KeepTTL: true, Get: true}, 0)
+	if !res.Set || res.Old != "v1" {
+		t.Fatalf("KEEPTTL GET: %+v", res)
+	}
+	if ttl, _, _ := s.TTL("k", 0); ttl <= 0 {
+		t.Fatalf("KEEPTTL dropped the expiry, TTL = %v", ttl)
+	}
+	s.SetWithOptions("k", "v4", SetOptions{}, 0)
+	if ttl, _, _ := s.TTL("k", 0); ttl >= 0 {
+		t.Fatalf("a plain SET kept the expiry, TTL = %v", ttl)
+	}
+
+	s.RPush("list", []string{"a"}, 0)
+	if _, err := s.SetWithOptions("list", "v", SetOptions{Get: true}, 0); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("GET on a list: err = %v, want ErrWrongType", err)
+	}
+	if typ, _ := s.TypeCmd("list", 0); *typ != TypeList {
+		t.Fatal("a failed SET GET overwrote the list")
+	}
+	if res, _ := s.SetWithOptions("list", "v", SetOptions{}, 0); !res.Set {
+		t.Fatal("SET without GET must overwrite a key of any type")
+	}
+}
+
+func TestStorage_Ranges(t *testing.T) {
+	s := NewStorage()
+	s.Set("k", "Hello World", 0, 0)
+
+	for _, tc := range []struct {
+		start, end int64
+		want       string
+	}{
+		{0, 4, "Hello"},
+		{-5, -1, "World"},
+		{-100, 2, "Hel"},
+		{6, 100, "World"},
+		{5, 3, ""},
+		{-1, -5, ""},
+	} {
+		if got, _ := s.GetRange("k", tc.start, tc.end, 0); got != tc.want {
+			t.Errorf("GetRange(%d, %d) = %q, want %q", tc.start, tc.end, got, tc.want)
+		}
+	}
+	if got, err := s.GetRange("missing", 0, -1, 0); got != "" || err != nil {
+		t.Errorf("GetRange on a missing key = %q %v", got, err)
+	}
+
+	if n, _ := s.SetRange("k", 6, "Redis", 0); n != 11 {
+		t.Fatalf("SetRange returned %d, want 11", n)
+	}
+	if e, _ := s.Get("k", 0); e.Value.String != "Hello Redis" {
+		t.Fatalf("got %q", e.Value.String)
+	}
+	if n, _ := s.SetRange("pad", 2, "x", 0); n != 3 {
+		t.Fatalf("SetRange past the end returned %d, want 3", n)
+	}
+	if e, _ := s.Get("pad", 0); e.Value.String != "\x00\x00x" {
+		t.Fatalf("got %q, want zero padding", e.Value.String)
+	}
+	if n, _ := s.SetRange("none", 5, "", 0); n != 0 {
+		t.Fatalf("SetRange with an empty value returned %d", n)
+	}
+	if e, _ := s.Get("none", 0); e != nil {
+		t.Fatal("SetRange with an empty value created the key")
+	}
+	for _, offset := range []int64{MaxStringLen, math.MaxInt64, math.MaxInt64 - 1} {
+		if _, err := s.SetRange("k", offset, "xy", 0); err != ErrStringTooLong {
+			t.Fatalf("SetRange at %d: expected ErrStringTooLong, got %v", offset, err)
+		}
+	}
+}
+
+func TestStorage_MSet(t *testing.T) {
+	s := NewStorage()
+	s.Set("a", "old", time.Minute, 0)
+	s.RPush("list", []string{"x"}, 0)
+
+	s.MSet([][2]string{{"a", "1"}, {"b", "2"}}, 0)
+	if ttl, _, _ := s.TTL("a", 0); ttl >= 0 {
+		t.Fatal("MSet kept the old TTL")
+	}
+	vals, _ := s.MGet([]string{"a", "missing", "list", "b"}, 0)
+	if len(vals) != 4 || *vals[0] != "1" || vals[1] != nil || vals[2] != nil || *vals[3] != "2" {
+		t.Fatalf("MGet = %v", vals)
+	}
+
+	if ok, _ := s.MSetNX([][2]string{{"c", "3"}, {"a", "x"}}, 0); ok {
+		t.Fatal("MSetNX wrote although a key existed")
+	}
+	if e, _ := s.Get("c", 0); e != nil {
+		t.Fatal("MSetNX set some keys of a failed batch")
+	}
+	if ok, _ := s.MSetNX([][2]string{{"c", "3"}, {"d", "4"}}, 0); !ok {
+		t.Fatal("MSetNX on new keys failed")
+	}
+}
+
+func TestStorage_GetDelGetEx(t *testing.T) {
+	s := NewStorage()
+	s.Set("k", "v", 0, 0)
+
+	if v, ok, err := s.GetEx("k", GetExOptions{Expiry: time.Now().Add(time.Minute)}, 0); v != "v" || !ok || err != nil {
+		t.Fatalf("GetEx = %q %v %v", v, ok, err)
+	}
+	if ttl, _, _ := s.TTL("k", 0); ttl <= 0 {
+		t.Fatal("GetEx did not set the expiry")
+	}
+	s.GetEx("k", GetExOptions{Persist: true}, 0)
+	if ttl, _, _ := s.TTL("k", 0); ttl >= 0 {
+		t.Fatal("GetEx PERSIST kept the expiry")
+	}
+	if v, ok, _ := s.GetEx("k", GetExOptions{Expiry: time.Now().Add(-time.Second)}, 0); v != "v" || !ok {
+		t.Fatal("GetEx with a past expiry did not return the value")
+	}
+	if e, _ := s.Get("k", 0); e != nil {
+		t.Fatal("GetEx with a past expiry kept the key")
+	}
+
+	s.Set("k", "v", 0, 0)
+	if v, ok, _ := s.GetDel("k", 0); v != "v" || !ok {
+		t.Fatalf("GetDel = %q %v", v, ok)
+	}
+	if _, ok, _ := s.GetDel("k", 0); ok {
+		t.Fatal("GetDel found a deleted key")
+	}
+	s.RPush("list", []string{"a"}, 0)
+	if _, _, err := s.GetDel("list", 0); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("GetDel on a list: err = %v", err)
+	}
+	if n, _ := s.RLen("list", 0); n != 1 {
+		t.Fatal("a failed GetDel removed the list")
+	}
+}
+
+func TestStorage_Exists(t *testing.T) {
+	s := NewStorage()
+	s.Set("a", "1", 0, 0)
+	s.Set("gone", "1", time.Millisecond, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if n, _ := s.Exists([]string{"a", "a", "gone", "missing"}, 0); n != 2 {
+		t.Fatalf("Exists = %d, want 2", n)
+	}
+	s.TrackHotKeys(4)
+	s.Exists([]string{"a"}, 0)
+	if hot := s.HotKeys(); len(hot) != 0 {
+		t.Fatalf("Exists counted as an access: %+v", hot)
+	}
+	if n, _ := s.Touch([]string{"a", "missing"}, 0); n != 1 {
+		t.Fatalf("Touch = %d, want 1", n)
+	}
+	if hot := s.HotKeys(); len(hot) != 1 || hot[0].Key != "a" {
+		t.Fatalf("Touch did not count as an access: %+v", hot)
+	}
+}
+
+func TestStorage_CopyMove(t *testing.T) {
+	s := NewStorage()
+	s.Set("k", "v", time.Minute, 0)
+	s.RPush("list", []string{"a", "b"}, 0)
+
+	if ok, err := s.Copy("list", "copy", 0, 1, false); !ok || err != nil {
+		t.Fatalf("Copy = %v %v", ok, err)
+	}
+	s.RPush("copy", []string{"c"}, 1)
+	if n, _ := s.RLen("list", 0); n != 2 {
+		t.Fatal("writing to the copy changed the original")
+	}
+	if ok, _ := s.Copy("k", "copy", 0, 1, false); ok {
+		t.Fatal("Copy overwrote a key without replace")
+	}
+	if ok, _ := s.Copy("k", "copy", 0, 1, true); !ok {
+		t.Fatal("Copy with replace failed")
+	}
+	if ttl, _, _ := s.TTL("copy", 1); ttl <= 0 {
+		t.Fatal("Copy dropped the TTL")
+	}
+	if _, err := s.Copy("k", "k", 0, 0, true); err != ErrSameObject {
+		t.Fatalf("Copy onto itself: err = %v", err)
+	}
+
+	s.Set("k", "other", 0, 1)
+	if ok, _ := s.Move("k", 0, 1); ok {
+		t.Fatal("Move overwrote an existing key")
+	}
+	if ok, _ := s.Move("k", 0, 2); !ok {
+		t.Fatal("Move failed")
+	}
+	if e, _ := s.Get("k", 0); e != nil {
+		t.Fatal("Move left the key in the source database")
+	}
+	if ttl, _, _ := s.TTL("k", 2); ttl <= 0 {
+		t.Fatal("Move dropped the TTL")
+	}
+
+	// Opposite-order moves between the same pair must not deadlock.
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(2)
+		key := fmt.Sprint(i)
+		s.Set(key, "v", 0, 3)
+		go func() { defer wg.Done(); s.Move(key, 3, 4) }()
+		go func() { defer wg.Done(); s.Move(key, 4, 3) }()
+	}
+	wg.Wait()
+}
+
+func TestStorage_RandomKeyDBSize(t *testing.T) {
+	s := NewStorage()
+	if _, ok, _ := s.RandomKey(0); ok {
+		t.Fatal("RandomKey found a key in an empty database")
+	}
+
+	for i := range 4 {
+		s.Set(fmt.Sprint(i), "v", 0, 0)
+	}
+	s.Set("gone", "v", time.Millisecond, 0)
+	time.Sleep(5 * time.Millisecond)
+	if n, _ := s.DBSize(0); n != 4 {
+		t.Fatalf("DBSize = %d, want 4 live keys", n)
+	}
+
+	seen := map[string]int{}
+	for range 4000 {
+		key, ok, _ := s.RandomKey(0)
+		if !ok || key == "gone" {
+			t.Fatalf("RandomKey = %q %v", key, ok)
+		}
+		seen[key]++
+	}
+	for key, n := range seen {
+		if n < 800 || n > 1200 {
+			t.Errorf("key %s drawn %d times out of 4000, want about 1000", key, n)
+		}
+	}
+
+	s.Del([]string{"0"}, 0)
+	s.Del([]string{"1"}, 0)
+	for range 100 {
+		if key, _, _ := s.RandomKey(0); key == "0" || key == "1" {
+			t.Fatalf("RandomKey returned deleted key %s", key)
+		}
+	}
+	s.Flush()
+	if _, ok, _ := s.RandomKey(0); ok {
+		t.Fatal("RandomKey found a key after Flush")
+	}
+}
+
+func TestStorage_TypeCmd(t *testing.T) {
+	s := NewStorage()
+	s.Set("n", "42", 0, 0)
+	s.Set("gone", "v", time.Millisecond, 0)
+	time.Sleep(5 * time.Millisecond)
+
+	if typ, err := s.TypeCmd("n", 0); err != nil || typ.String() != "string" {
+		t.Fatalf("TypeCmd = %v %v, want string", typ, err)
+	}
+	for _, key := range []string{"gone", "missing"} {
+		if _, err := s.TypeCmd(key, 0); err != ErrNoSuchKey {
+			t.Errorf("TypeCmd(%q): err = %v, want ErrNoSuchKey", key, err)
+		}
+	}
+}
+
+func TestStorage_Object(t *testing.T) {
+	s := NewStorage()
+	s.Set("n", "42", 0, 0)
+	s.Set("s", "hello", 0, 0)
+	s.RPush("l", []string{"a"}, 0)
+
+	for key, want := range map[string]string{"n": "int", "s": "raw", "l": "quicklist"} {
+		if info, ok, _ := s.Object(key, 0); !ok || info.Encoding != want {
+			t.Errorf("encoding of %s = %q, want %q", key, info.Encoding, want)
+		}
+	}
+	if info, _, _ := s.Object("s", 0); info.Freq < lfuInitVal {
+		t.Fatalf("freq of a new key = %d, want at least %d", info.Freq, lfuInitVal)
+	}
+	for range 1000 {
+		s.Get("s", 0)
+	}
+	info, _, _ := s.Object("s", 0)
+	if info.Freq <= lfuInitVal+1 {
+		t.Fatalf("freq after 1000 reads = %d, want it to grow", info.Freq)
+	}
+	s.Set("s", "again", 0, 0)
+	if again, _, _ := s.Object("s", 0); again.Freq < info.Freq {
+		t.Fatalf("overwriting reset the freq from %d to %d", info.Freq, again.Freq)
+	}
+
+	db := s.databases[0]
+	db.data["s"].access.last.Store(time.Now().Add(-3 * time.Minute).UnixNano())
+	info, _, _ = s.Object("s", 0)
+	if info.Idle < 3*time.Minute {
+		t.Fatalf("idle = %v, want at least 3m", info.Idle)
+	}
+	s.Get("s", 0)
+	if info, _, _ := s.Object("s", 0); info.Idle > time.Second {
+		t.Fatalf("idle = %v after a read, want about 0", info.Idle)
+	}
+	if _, ok, _ := s.Object("missing", 0); ok {
+		t.Fatal("Object found a missing key")
+	}
+}
+
+func TestStorage_NoTouch(t *testing.T) {
+	s := NewStorage()
+	s.Set("k", "v", 0, 1)
+	s.databases[1].data["k"].access.last.Store(time.Now().Add(-time.Minute).UnixNano())
+
+	before, _, _ := s.Object("k", 1)
+	nt := NoTouch(1)
+	if e, _ := s.Get("k", nt); e == nil || e.Value.Str() != "v" {
+		t.Fatalf("Get through NoTouch = %v", e)
+	}
+	s.MGet([]string{"k"}, nt)
+	s.GetRange("k", 0, -1, nt)
+	s.TTL("k", nt)
+	info, _, _ := s.Object("k", 1)
+	if info.Idle < time.Minute || info.Freq != before.Freq {
+		t.Fatalf("after NoTouch reads idle = %v, freq = %d; want them unchanged", info.Idle, info.Freq)
+	}
+	if s.Counters().Hits != 4 {
+		t.Fatalf("hits = %d, NoTouch reads should still count", s.Counters().Hits)
+	}
+
+	if n, _ := s.Touch([]string{"k"}, nt); n != 1 {
+		t.Fatalf("Touch = %d", n)
+	}
+	if info, _, _ := s.Object("k", 1); info.Idle > time.Second {
+		t.Fatalf("idle = %v after TOUCH, want about 0", info.Idle)
+	}
+
+	// A NoTouch handle is the same database for MOVE and COPY.
+	if _, err := s.Move("k", nt, 1); !errors.Is(err, ErrSameObject) {
+		t.Fatalf("Move to the same database: %v", err)
+	}
+	if ok, err := s.Copy("k", "k2", nt, 1, false); !ok || err != nil {
+		t.Fatalf("Copy = %v, %v", ok, err)
+	}
+	if _, err := s.Get("k", NoTouch(DefaultDatabases)); err == nil {
+		t.Fatal("NoTouch of a missing database was accepted")
+	}
+}
+
+func TestStorage_ListPositional(t *testing.T) {
+	s := NewStorage()
+	s.RPush("l", []string{"a", "b", "c"}, 0)
+
+	if v, ok, _ := s.LIndex("l", -1, 0); !ok || v != "c" {
+		t.Fatalf("LIndex(-1) = %q %v, want c", v, ok)
+	}
+	if _, ok, _ := s.LIndex("l", 3, 0); ok {
+		t.Fatal("LIndex found an element past the tail")
+	}
+	if err := s.LSet("l", -3, "A", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LSet("l", 3, "x", 0); !errors.Is(err, ErrIndexOutOfRange) {
+		t.Fatalf("LSet past the tail: %v, want ErrIndexOutOfRange", err)
+	}
+	if err := s.LSet("missing", 0, "x", 0); !errors.Is(err, ErrNoSuchKey) {
+		t.Fatalf("LSet on a missing key: %v, want ErrNoSuchKey", err)
+	}
+	if n, _ := s.LInsert("l", true, "b", "ab", 0); n != 4 {
+		t.Fatalf("LInsert BEFORE = %d, want 4", n)
+	}
+	if n, _ := s.LInsert("l", false, "c", "d", 0); n != 5 {
+		t.Fatalf("LInsert AFTER = %d, want 5", n)
+	}
+	if n, _ := s.LInsert("l", false, "nope", "x", 0); n != -1 {
+		t.Fatalf("LInsert without the pivot = %d, want -1", n)
+	}
+	if n, _ := s.LInsert("missing", false, "a", "x", 0); n != 0 {
+		t.Fatalf("LInsert on a missing key = %d, want 0", n)
+	}
+	if got, _ := s.LRange("l", "0", "-1", 0); !slices.Equal(got, []string{"A", "ab", "b", "c", "d"}) {
+		t.Fatalf("list = %q", got)
+	}
+
+	// LSet and LInsert change the list in place, which a view opened
+	// before them must not see.
+	d := s.databases[0]
+	d.mu.Lock()
+	v := d.beginView(time.Now())
+	d.mu.Unlock()
+	s.LSet("l", 0, "changed", 0)
+	s.LInsert("l", true, "b", "new", 0)
+	var seen []string
+	d.walk(v, func(key string, e Entry) error {
+		seen = e.Value.List.Values()
+		return nil
+	})
+	d.endView(v)
+	if !slices.Equal(seen, []string{"A", "ab", "b", "c", "d"}) {
+		t.Fatalf("snapshot saw %q", seen)
+	}
+}
+
+func TestStorage_LRemLTrim(t *testing.T) {
+	s := NewStorage()
+	tests := []struct {
+		count   int
+		removed int
+		want    []string
+	}{
+		{2, 2, []string{"b", "c", "a", "a"}},
+		{-2, 2, []string{"a", "b", "a", "c"}},
+		{0, 4, []string{"b", "c"}},
+		{-100, 4, []string{"b", "c"}},
+		{math.MinInt, 4, []string{"b", "c"}},
+	}
+	for _, tt := range tests {
+		s.Del([]string{"l"}, 0)
+		s.RPush("l", []string{"a", "b", "a", "c", "a", "a"}, 0)
+		n, err := s.LRem("l", tt.count, "a", 0)
+		got, _ := s.LRange("l", "0", "-1", 0)
+		if err != nil || n != tt.removed || !slices.Equal(got, tt.want) {
+			t.Errorf("LRem(%d) = %d %v, list %q; want %d, %q", tt.count, n, err, got, tt.removed, tt.want)
+		}
+	}
+	s.LRem("l", 0, "b", 0)
+	s.LRem("l", 0, "c", 0)
+	if e, _ := s.Get("l", 0); e != nil {
+		t.Fatal("LRem left an empty list behind")
+	}
+
+	s.RPush("q", []string{"a", "b", "c", "d", "e"}, 0)
+	if err := s.LTrim("q", 1, -2, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.LRange("q", "0", "-1", 0); !slices.Equal(got, []string{"b", "c", "d"}) {
+		t.Fatalf("after LTrim(1, -2) list = %q", got)
+	}
+	s.LTrim("q", -100, 100, 0)
+	if n, _ := s.RLen("q", 0); n != 3 {
+		t.Fatalf("LTrim over the whole list changed its length to %d", n)
+	}
+	s.LTrim("q", 2, 1, 0)
+	if e, _ := s.Get("q", 0); e != nil {
+		t.Fatal("LTrim to an empty range left the key behind")
+	}
+}
+
+func TestStorage_LMove(t *testing.T) {
+	s := NewStorage()
+	s.RPush("src", []string{"a", "b", "c"}, 0)
+
+	if v, ok, err := s.LMove("src", "dst", true, false, 0); !ok || err != nil || v != "a" {
+		t.Fatalf("LMove LEFT RIGHT = %q %v %v, want a", v, ok, err)
+	}
+	if v, _, _ := s.LMove("src", "src", false, true, 0); v != "c" {
+		t.Fatalf("rotating LMove = %q, want c", v)
+	}
+	if got, _ := s.LRange("src", "0", "-1", 0); !slices.Equal(got, []string{"c", "b"}) {
+		t.Fatalf("src after rotating = %q, want [c b]", got)
+	}
+	s.Set("str", "x", 0, 0)
+	if _, _, err := s.LMove("src", "str", true, true, 0); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("LMove onto a string: %v, want ErrWrongType", err)
+	}
+	if n, _ := s.RLen("src", 0); n != 2 {
+		t.Fatalf("a failed LMove popped from src, length %d", n)
+	}
+	s.LMove("src", "dst", true, true, 0)
+	s.LMove("src", "dst", true, true, 0)
+	if e, _ := s.Get("src", 0); e != nil {
+		t.Fatal("LMove left an empty src behind")
+	}
+	if got, _ := s.LRange("dst", "0", "-1", 0); !slices.Equal(got, []string{"b", "c", "a"}) {
+		t.Fatalf("dst = %q, want [b c a]", got)
+	}
+	if _, ok, _ := s.LMove("src", "dst", true, true, 0); ok {
+		t.Fatal("LMove from a missing key moved something")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		s.RPush("jobs", []string{"j"}, 0)
+	}()
+	if v, ok, err := s.BLMove(context.Background(), "jobs", "done", true, true, time.Second, 0); !ok || err != nil || v != "j" {
+		t.Fatalf("BLMove = %q %v %v, want j", v, ok, err)
+	}
+	if _, ok, err := s.BLMove(context.Background(), "jobs", "done", true, true, 20*time.Millisecond, 0); ok || err != nil {
+		t.Fatalf("BLMove on an empty list = %v %v, want a timeout", ok, err)
+	}
+}
+
+func TestStorage_BlockedClientsFIFO(t *testing.T) {
+	s := NewStorage()
+	ctx := context.Background()
+
+	type result struct {
+		who, value string
+	}
+	results := make(chan result, 3)
+	for _, who := range []string{"first", "second", "third"} {
+		go func() {
+			_, v, _, err := s.BLPOP(ctx, []string{"q"}, 5*time.Second, 0)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- result{who, v}
+		}()
+		// Give each waiter time to register before the next one.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	start := time.Now()
+	s.RPush("q", []string{"a"}, 0)
+	if r := <-results; r != (result{"first", "a"}) {
+		t.Fatalf("got %+v, want the first waiter served a", r)
+	}
+	if d := time.Since(start); d > 40*time.Millisecond {
+		t.Errorf("waiter woke %v after the push", d)
+	}
+
+	// A push of two elements serves both remaining waiters in order.
+	s.RPush("q", []string{"b", "c"}, 0)
+	got := []result{<-results, <-results}
+	slices.SortFunc(got, func(x, y result) int { return strings.Compare(x.value, y.value) })
+	if got[0] != (result{"second", "b"}) || got[1] != (result{"third", "c"}) {
+		t.Fatalf("got %+v, want second served b and third served c", got)
+	}
+	if n := len(s.databases[0].blocked.byKey); n != 0 {
+		t.Fatalf("%d keys still have waiters", n)
+	}
+}
+
+func TestQuicklist(t *testing.T) {
+	// Mirror every operation on a plain slice, with enough elements that
+	// they span several nodes and reach the middle from either end.
+	l := newQuicklist()
+	var want []string
+	check := func(step string) {
+		t.Helper()
+		if got := l.Values(); l.Len() != len(want) || !slices.Equal(got, want) {
+			t.Fatalf("after %s: len %d, got %v, want %v", step, l.Len(), got, want)
+		}
+		for i := range want {
+			if v := l.index(i); v != want[i] {
+				t.Fatalf("after %s: index(%d) = %q, want %q", step, i, v, want[i])
+			}
+		}
+		for n := l.head; n != nil; n = n.next {
+			if len(n.items) == 0 || len(n.items) > quicklistFill {
+				t.Fatalf("after %s: node holds %d elements", step, len(n.items))
+			}
+		}
+	}
+
+	for i := range 3 * quicklistFill {
+		l.pushTail(fmt.Sprint(i))
+		want = append(want, fmt.Sprint(i))
+	}
+	for i := range quicklistFill / 2 {
+		l.pushHead(fmt.Sprint("h", i))
+		want = append([]string{fmt.Sprint("h", i)}, want...)
+	}
+	check("pushes")
+
+	for _, i := range []int{0, 5, quicklistFill + 3, len(want) - 1} {
+		l.insert(i, "x")
+		want = slices.Insert(want, i, "x")
+	}
+	for range quicklistFill {
+		l.insert(quicklistFill, "y")
+		want = slices.Insert(want, quicklistFill, "y")
+	}
+	check("inserts")
+
+	l.set(len(want)/2, "mid")
+	want[len(want)/2] = "mid"
+	if i := l.indexOf("mid"); i != len(want)/2 {
+		t.Fatalf("indexOf(mid) = %d", i)
+	}
+	check("set")
+
+	if n := l.remove("y", 10, true); n != 10 {
+		t.Fatalf("removed %d from the tail, want 10", n)
+	}
+	removed := 0
+	for i := len(want) - 1; i >= 0 && removed < 10; i-- {
+		if want[i] == "y" {
+			want = slices.Delete(want, i, i+1)
+			removed++
+		}
+	}
+	check("remove from the tail")
+	if n := l.remove("y", len(want), false); n != quicklistFill-10 {
+		t.Fatalf("removed %d, want %d", n, quicklistFill-10)
+	}
+	want = slices.DeleteFunc(want, func(v string) bool { return v == "y" })
+	check("remove")
+
+	l.trim(quicklistFill+7, len(want)-quicklistFill-2)
+	want = want[quicklistFill+7 : len(want)-quicklistFill-1]
+	check("trim")
+
+	for len(want) > 0 {
+		if v := l.popHead(); v != want[0] {
+			t.Fatalf("popHead = %q, want %q", v, want[0])
+		}
+		want = want[1:]
+		if len(want) == 0 {
+			break
+		}
+		if v := l.popTail(); v != want[len(want)-1] {
+			t.Fatalf("popTail = %q, want %q", v, want[len(want)-1])
+		}
+		want = want[:len(want)-1]
+	}
+	check("pops")
+	if l.head != nil || l.tail != nil {
+		t.Fatal("an empty quicklist still holds nodes")
+	}
+}
+
+func TestStorage_PopCount(t *testing.T) {
+	s := NewStorage()
+	s.RPush("l", []string{"a", "b", "c", "d", "e"}, 0)
+
+	if got, _ := s.RPOP("l", 2, 0); !slices.Equal(got, []string{"e", "d"}) {
+		t.Fatalf("RPOP 2 = %v, want tail first", got)
+	}
+	if got, _ := s.LPOP("l", 0, 0); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("LPOP 0 = %v, want one element", got)
+	}
+	if got, _ := s.LPOP("l", 10, 0); !slices.Equal(got, []string{"b", "c"}) {
+		t.Fatalf("LPOP 10 = %v, want the rest", got)
+	}
+	if n, _ := s.Exists([]string{"l"}, 0); n != 0 {
+		t.Fatal("an emptied list should be deleted")
+	}
+}
+
+func TestStorage_Hash(t *testing.T) {
+	s := NewStorage()
+
+	if n, err := s.HSet("h", [][2]string{{"a", "1"}, {"b", "2"}}, 0); n != 2 || err != nil {
+		t.Fatalf("HSet = %d %v, want 2 new fields", n, err)
+	}
+	if n, _ := s.HSet("h", [][2]string{{"a", "3"}, {"c", "4"}}, 0); n != 1 {
+		t.Fatalf("HSet counted %d new fields, want 1", n)
+	}
+	if v, ok, err := s.HGet("h", "a", 0); v != "3" || !ok || err != nil {
+		t.Fatalf("HGet a = %q %v %v, want 3", v, ok, err)
+	}
+	if _, ok, _ := s.HGet("h", "z", 0); ok {
+		t.Fatal("HGet found a missing field")
+	}
+	want := [][2]string{{"a", "3"}, {"b", "2"}, {"c", "4"}}
+	if got, _ := s.HGetAll("h", 0); !slices.Equal(got, want) {
+		t.Fatalf("HGetAll = %v, want %v", got, want)
+	}
+	if got, _ := s.HKeys("h", 0); !slices.Equal(got, []string{"a", "b", "c"}) {
+		t.Fatalf("HKeys = %v", got)
+	}
+	if got, _ := s.HVals("h", 0); !slices.Equal(got, []string{"3", "2", "4"}) {
+		t.Fatalf("HVals = %v", got)
+	}
+	if n, _ := s.HLen("h", 0); n != 3 {
+		t.Fatalf("HLen = %d, want 3", n)
+	}
+	if ok, _ := s.HExists("h", "b", 0); !ok {
+		t.Fatal("HExists missed field b")
+	}
+	vals, _ := s.HMGet("h", []string{"c", "z"}, 0)
+	if len(vals) != 2 || vals[0] == nil || *vals[0] != "4" || vals[1] != nil {
+		t.Fatalf("HMGet = %v, want [4 nil]", vals)
+	}
+	if vals, err := s.HMGet("missing", []string{"a"}, 0); len(vals) != 1 || vals[0] != nil || err != nil {
+		t.Fatalf("HMGet on a missing key = %v %v", vals, err)
+	}
+	if ok, _ := s.HSetNX("h", "a", "x", 0); ok {
+		t.Fatal("HSetNX overwrote field a")
+	}
+	if ok, _ := s.HSetNX("new", "a", "hello", 0); !ok {
+		t.Fatal("HSetNX did not create a new hash")
+	}
+	if n, _ := s.HStrLen("new", "a", 0); n != 5 {
+		t.Fatalf("HStrLen = %d, want 5", n)
+	}
+
+	// HDel changes the hash in place, which a view opened before it must
+	// not see.
+	d := s.databases[0]
+	d.mu.Lock()
+	v := d.beginView(time.Now())
+	d.mu.Unlock()
+	if n, _ := s.HDel("h", []string{"a", "z"}, 0); n != 1 {
+		t.Fatalf("HDel removed %d fields, want 1", n)
+	}
+	var seen map[string]string
+	d.walk(v, func(key string, e Entry) error {
+		if key == "h" {
+			seen = e.Value.Hash
+		}
+		return nil
+	})
+	d.endView(v)
+	if len(seen) != 3 {
+		t.Fatalf("snapshot saw %v", seen)
+	}
+
+	if n, _ := s.HDel("h", []string{"b", "c"}, 0); n != 2 {
+		t.Fatalf("HDel removed %d fields, want 2", n)
+	}
+	if n, _ := s.Exists([]string{"h"}, 0); n != 0 {
+		t.Fatal("an emptied hash should be deleted")
+	}
+
+	s.Set("str", "v", 0, 0)
+	s.RPush("list", []string{"v"}, 0)
+	for _, key := range []string{"str", "list"} {
+		if _, err := s.HSet(key, [][2]string{{"f", "v"}}, 0); !errors.Is(err, ErrWrongType) {
+			t.Fatalf("HSet on %s: err = %v, want ErrWrongType", key, err)
+		}
+		if _, _, err := s.HGet(key, "f", 0); !errors.Is(err, ErrWrongType) {
+			t.Fatalf("HGet on %s: err = %v, want ErrWrongType", key, err)
+		}
+		if _, err := s.HSetNX(key, "f", "v", 0); !errors.Is(err, ErrWrongType) {
+			t.Fatalf("HSetNX on %s: err = %v, want ErrWrongType", key, err)
+		}
+		if _, err := s.HLen(key, 0); !errors.Is(err, ErrWrongType) {
+			t.Fatalf("HLen on %s: err = %v, want ErrWrongType", key, err)
+		}
+	}
+	s.HSet("h", [][2]string{{"f", "v"}}, 0)
+	if _, err := s.RPush("h", []string{"v"}, 0); !errors.Is(err, ErrWrongType) {
+		t.Fatalf("RPush on a hash: err = %v, want ErrWrongType", err)
+	}
+}
+
+func TestStorage_HashFieldExpiry(t *testing.T) {
+	s := NewStorage()
+	s.HSet("h", [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}}, 0)
+	later := time.Now().Add(time.Hour)
+
+	got, err := s.HExpireAt("h", []string{"a", "b", "nope"}, later, ExpireAlways, 0)
+	if want := []int{FieldUpdated, FieldUpdated, FieldMissing}; err != nil || !slices.Equal(got, want) {
+		t.Fatalf("HExpireAt = %v %v, want %v", got, err, want)
+	}
+	if got, _ := s.HExpireAt("h", []string{"a", "c"}, later, ExpireNX, 0); !slices.Equal(got, []int{FieldNotSet, FieldUpdated}) {
+		t.Fatalf("HExpireAt NX = %v", got)
+	}
+	if got, _ := s.HExpireAt("h", []string{"a"}, later.Add(-time.Minute), ExpireGT, 0); !slices.Equal(got, []int{FieldNotSet}) {
+		t.Fatalf("HExpireAt GT with an earlier time = %v", got)
+	}
+	ats, _ := s.HExpiryAt("h", []string{"a", "nope"}, 0)
+	if ats[0] == nil || !ats[0].Equal(later) || ats[1] != nil {
+		t.Fatalf("HExpiryAt = %v", ats)
+	}
+	if got, _ := s.HPersist("h", []string{"a", "a", "nope"}, 0); !slices.Equal(got, []int{FieldUpdated, FieldNoExpiry, FieldMissing}) {
+		t.Fatalf("HPersist = %v", got)
+	}
+	// Setting a field again drops its TTL.
+	s.HSet("h", [][2]string{{"b", "x"}}, 0)
+	if ats, _ := s.HExpiryAt("h", []string{"b"}, 0); ats[0] == nil || !ats[0].IsZero() {
+		t.Fatalf("b kept its TTL after HSet: %v", ats[0])
+	}
+
+	// A time in the past deletes the field at once.
+	if got, _ := s.HExpireAt("h", []string{"b"}, time.Now().Add(-time.Second), ExpireAlways, 0); !slices.Equal(got, []int{FieldExpiredNow}) {
+		t.Fatalf("HExpireAt in the past = %v", got)
+	}
+
+	// Expired fields are hidden from readers before anything removes them.
+	s.HExpireAt("h", []string{"c"}, time.Now().Add(10*time.Millisecond), ExpireAlways, 0)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := s.HGet("h", "c", 0); ok {
+		t.Fatal("HGet returned an expired field")
+	}
+	if n, _ := s.HLen("h", 0); n != 1 {
+		t.Fatalf("HLen = %d, want 1", n)
+	}
+	if got, _ := s.HKeys("h", 0); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("HKeys = %v", got)
+	}
+	if ok, _ := s.HSetNX("h", "c", "new", 0); !ok {
+		t.Fatal("HSetNX saw an expired field as present")
+	}
+
+	// The active cycle drops expired fields nobody reads, and the key with
+	// its last field.
+	s.HSet("gone", [][2]string{{"f", "v"}}, 0)
+	s.HExpireAt("gone", []string{"f"}, time.Now().Add(10*time.Millisecond), ExpireAlways, 0)
+	time.Sleep(20 * time.Millisecond)
+	s.ExpireFields(20)
+	d := s.databases[0]
+	if _, ok := d.data["gone"]; ok {
+		t.Fatal("the active cycle left a hash whose fields all expired")
+	}
+	if len(d.fieldExpires) != 0 {
+		t.Fatalf("%d hashes still tracked for field expiry", len(d.fieldExpires))
+	}
+}
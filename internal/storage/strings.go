@@ -0,0 +1,221 @@
+package storage
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxStringLen is the largest string SetRange will build, matching the
+// default proto-max-bulk-len of Redis.
+const MaxStringLen = 512 << 20
+
+// ErrStringTooLong is returned when a write would grow a string past
+// MaxStringLen.
+var ErrStringTooLong = errors.New("string exceeds maximum allowed size (proto-max-bulk-len)")
+
+// GetRange returns the bytes of the string at key from start to end,
+// inclusive. Negative offsets count from the end, and the range is clamped
+// to the string; a missing key reads as the empty string.
+func (d *Database) GetRange(key string, start, end int64) (string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	entry, ok, err := d.peekType(key, TypeString)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return "", err
+	}
+	s := entry.Value.Str()
+	n := int64(len(s))
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if end < 0 {
+		end = max(n+end, 0)
+	}
+	end = min(end, n-1)
+	if n == 0 || start > end {
+		return "", nil
+	}
+	return s[start : end+1], nil
+}
+
+// SetRange overwrites the string at key with val starting at offset,
+// padding with zero bytes when offset is past the end, and returns the new
+// length. The key keeps its TTL. An empty val leaves the key untouched,
+// and does not create a missing one.
+func (d *Database) SetRange(key string, offset int64, val string) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeString)
+	if err != nil {
+		return 0, err
+	}
+	var s string
+	if ok {
+		s = entry.Value.Str()
+	}
+	if val == "" {
+		return len(s), nil
+	}
+	if offset > MaxStringLen-int64(len(val)) {
+		return 0, ErrStringTooLong
+	}
+
+	buf := make([]byte, max(int64(len(s)), offset+int64(len(val))))
+	copy(buf, s)
+	copy(buf[offset:], val)
+	value := stringValue(string(buf))
+	value.Expiry = entry.Value.Expiry
+	d.put(key, Entry{Value: value})
+	return len(buf), nil
+}
+
+// MGet returns the string at each key under a single lock; keys that are
+// missing or hold another type come back nil.
+func (d *Database) MGet(keys []string) []*string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	vals := make([]*string, len(keys))
+	for i, key := range keys {
+		entry, ok := d.peek(key)
+		d.read(key, entry, ok)
+		if ok && entry.Value.IsString() {
+			s := entry.Value.Str()
+			vals[i] = &s
+		}
+	}
+	return vals
+}
+
+// MSet stores every key/value pair under a single lock, so readers see
+// either none or all of them. Like SET, each key loses its TTL.
+func (d *Database) MSet(pairs [][2]string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, p := range pairs {
+		d.put(p[0], Entry{Value: stringValue(p[1])})
+	}
+}
+
+// MSetNX is MSet that writes nothing unless none of the keys exist, and
+// reports whether it wrote.
+func (d *Database) MSetNX(pairs [][2]string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, p := range pairs {
+		if _, ok := d.lookup(p[0]); ok {
+			return false
+		}
+	}
+	for _, p := range pairs {
+		d.put(p[0], Entry{Value: stringValue(p[1])})
+	}
+	return true
+}
+
+// GetDel returns the string at key and deletes the key under the same
+// lock. ok is false when the key does not exist.
+func (d *Database) GetDel(key string) (val string, ok bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeString)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	d.remove(key)
+	return entry.Value.Str(), true, nil
+}
+
+// GetExOptions say how GetEx changes the expiry of the key it reads. The
+// zero value leaves it alone.
+type GetExOptions struct {
+	Expiry  time.Time // new expiry; a time in the past deletes the key
+	Persist bool      // remove the expiry
+}
+
+// GetEx returns the string at key and updates its expiry under the same
+// lock. ok is false when the key does not exist.
+func (d *Database) GetEx(key string, opts GetExOptions) (val string, ok bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok, err := d.lookupType(key, TypeString)
+	d.read(key, entry, ok)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	val = entry.Value.Str()
+	switch {
+	case opts.Persist && !entry.Value.Expiry.IsZero():
+		entry.Value.Expiry = time.Time{}
+		d.put(key, entry)
+	case !opts.Expiry.IsZero() && !opts.Expiry.After(time.Now()):
+		d.remove(key)
+	case !opts.Expiry.IsZero():
+		entry.Value.Expiry = opts.Expiry
+		d.put(key, entry)
+	}
+	return val, true, nil
+}
+
+func (s *Storage) GetRange(key string, start, end int64, db int) (string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", err
+	}
+	return d.GetRange(key, start, end)
+}
+
+func (s *Storage) SetRange(key string, offset int64, val string, db int) (int, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return 0, err
+	}
+	return d.SetRange(key, offset, val)
+}
+
+func (s *Storage) MGet(keys []string, db int) ([]*string, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return nil, err
+	}
+	return d.MGet(keys), nil
+}
+
+func (s *Storage) MSet(pairs [][2]string, db int) error {
+	d, err := s.database(db)
+	if err != nil {
+		return err
+	}
+	d.MSet(pairs)
+	return nil
+}
+
+func (s *Storage) MSetNX(pairs [][2]string, db int) (bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return false, err
+	}
+	return d.MSetNX(pairs), nil
+}
+
+func (s *Storage) GetDel(key string, db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	return d.GetDel(key)
+}
+
+func (s *Storage) GetEx(key string, opts GetExOptions, db int) (string, bool, error) {
+	d, err := s.database(db)
+	if err != nil {
+		return "", false, err
+	}
+	return d.GetEx(key, opts)
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/tracing"
+)
+
+// ErrNil is returned by the typed helpers when the key does not exist, so
+// callers can tell a missing value from an empty one.
+var ErrNil = resp.ErrNil
+
+// Options configures a Client.
+type Options struct {
+	// Pool configures the connection pool, including dialing and the
+	// per-connection handshake.
+	Pool conn.Options
+	// Retry controls how idempotent commands are retried. The zero value
+	// never retries.
+	Retry RetryPolicy
+	// ReadPreference picks where read-only commands go. Replicas are found
+	// with ROLE (or INFO replication) on the primary and share Pool.
+	ReadPreference ReadPreference
+	// ReplicaRefresh is how often replicas are rediscovered. Defaults to 30s.
+	ReplicaRefresh time.Duration
+	// ReadTimeout and WriteTimeout bound each reply and each write on top of
+	// the context's deadline. A connection that times out is discarded.
+	// Zero leaves only the context.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// Multiplex, when positive, sends commands to the primary over that many
+	// shared connections instead of checking one out per command. Replies
+	// are matched in order, so many goroutines share a connection without
+	// waiting for each other's round trips. Blocking commands, pipelines and
+	// Pub/Sub still use the pool.
+	Multiplex int
+	// Tracer, when set, records a client span for every Do, a child of
+	// the span context in the command's context. See package tracing.
+	Tracer *tracing.Tracer
+}
+
+// Client runs commands on pooled connections. It is safe for concurrent use.
+type Client struct {
+	pool     *conn.Pool
+	retry    RetryPolicy
+	opts     Options
+	replicas *replicaSet
+	mux      *mux
+}
+
+// New connects to addr with a pool configured by opts.
+func New(addr string, opts Options) *Client {
+	c := NewFromPool(conn.NewConnPoolWithOptions(addr, opts.Pool))
+	c.retry = opts.Retry.withDefaults()
+	if opts.ReplicaRefresh <= 0 {
+		opts.ReplicaRefresh = 30 * time.Second
+	}
+	c.opts = opts
+	if opts.ReadPreference != ReadPrimary {
+		c.replicas = &replicaSet{}
+	}
+	if opts.Multiplex > 0 {
+		c.mux = newMux(c.pool, opts.Multiplex, c.timeouts())
+	}
+	return c
+}
+
+// NewFromPool runs commands on an existing pool, without retries. Close
+// closes the pool.
+func NewFromPool(pool *conn.Pool) *Client {
+	return &Client{pool: pool}
+}
+
+func (c *Client) Close() {
+	if c.replicas != nil {
+		c.replicas.close()
+	}
+	if c.mux != nil {
+		c.mux.close()
+	}
+	c.pool.Close()
+}
+
+// Pipeline returns an empty pipeline on the client's pool.
+func (c *Client) Pipeline() *Pipeline {
+	p := NewPipeline(c.pool)
+	p.timeouts = c.timeouts()
+	return p
+}
+
+// Do sends one command and waits for its reply. Check the returned Cmd's
+// Err before using its value. Commands known to be idempotent are retried
+// on transient errors according to the client's RetryPolicy; use DoRetry to
+// decide per call.
+func (c *Client) Do(ctx context.Context, args ...any) *Cmd {
+	return c.DoRetry(ctx, isIdempotent(args), args...)
+}
+
+// do runs a command once, on a replica when the read preference allows it
+// and on the primary otherwise or when the replica fails.
+func (c *Client) do(ctx context.Context, args []any) *Cmd {
+	if pool := c.readPool(ctx, args); pool != nil {
+		cmd := c.doOn(ctx, pool, args)
+		if !isTransient(cmd.err) {
+			return cmd
+		}
+	}
+	if c.mux != nil && !isBlocking(args) {
+		return c.mux.do(ctx, args)
+	}
+	return c.doOn(ctx, c.pool, args)
+}
+
+func (c *Client) timeouts() timeouts {
+	return timeouts{read: c.opts.ReadTimeout, write: c.opts.WriteTimeout}
+}
+
+// doOn runs a command once on a connection from pool.
+func (c *Client) doOn(ctx context.Context, pool *conn.Pool, args []any) *Cmd {
+	cmd := &Cmd{args: args}
+	cn, err := pool.Get(ctx)
+	if err != nil {
+		cmd.err = err
+		return cmd
+	}
+	if err := roundTrip(ctx, cn, []*Cmd{cmd}, c.timeouts()); err != nil {
+		pool.Discard(cn)
+		return cmd
+	}
+	pool.Put(cn)
+	return cmd
+}
+
+// scan runs a command and decodes its reply into dst, turning a nil reply
+// into ErrNil.
+func (c *Client) scan(ctx context.Context, dst any, args ...any) error {
+	cmd := c.Do(ctx, args...)
+	if cmd.err != nil {
+		return cmd.err
+	}
+	if cmd.val.IsNull() {
+		return ErrNil
+	}
+	return resp.Scan(cmd.val, dst)
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	return c.Do(ctx, "PING").Err()
+}
+
+// Get returns the string stored at key, or ErrNil.
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	var s string
+	err := c.scan(ctx, &s, "GET", key)
+	return s, err
+}
+
+// Set stores value at key. A positive expiration sets a millisecond TTL.
+func (c *Client) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	args := []any{"SET", key, value}
+	if expiration > 0 {
+		args = append(args, "PX", strconv.FormatInt(expiration.Milliseconds(), 10))
+	}
+	return c.Do(ctx, args...).Err()
+}
+
+// Del removes keys and returns how many existed.
+func (c *Client) Del(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]any, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, k := range keys {
+		args = append(args, k)
+	}
+	var n int64
+	err := c.scan(ctx, &n, args...)
+	return n, err
+}
+
+// RPush appends values to the list at key and returns its new length.
+func (c *Client) RPush(ctx context.Context, key string, values ...any) (int64, error) {
+	args := make([]any, 0, len(values)+2)
+	args = append(args, "RPUSH", key)
+	args = append(args, values...)
+	var n int64
+	err := c.scan(ctx, &n, args...)
+	return n, err
+}
+
+// LRange returns the elements of the list at key between start and stop,
+// inclusive; negative indexes count from the tail.
+func (c *Client) LRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	var out []string
+	err := c.scan(ctx, &out, "LRANGE", key, start, stop)
+	if err == ErrNil {
+		return nil, nil
+	}
+	return out, err
+}
+
+// Incr increments the integer at key and returns the new value.
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	var n int64
+	err := c.scan(ctx, &n, "INCR", key)
+	return n, err
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/tracing"
+)
+
+func TestClient(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		data   = map[string]string{}
+		lists  = map[string][]string{}
+		lastPX string
+	)
+	addr := fakeServer(t, func(args []string) resp.Value {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "SET":
+			data[args[1]] = args[2]
+			if len(args) == 5 {
+				lastPX = args[4]
+			}
+			return resp.Value{Typ: "string", Str: "OK"}
+		case "GET":
+			v, ok := data[args[1]]
+			if !ok {
+				return resp.Null
+			}
+			return resp.Value{Typ: "bulk", Bulk: v}
+		case "DEL":
+			var n int64
+			for _, k := range args[1:] {
+				if _, ok := data[k]; ok {
+					delete(data, k)
+					n++
+				}
+			}
+			return resp.Value{Typ: "integer", Num: n}
+		case "INCR":
+			n, err := strconv.ParseInt(data[args[1]], 10, 64)
+			if err != nil && data[args[1]] != "" {
+				return resp.ErrorValue(resp.ErrNotInteger)
+			}
+			n++
+			data[args[1]] = strconv.FormatInt(n, 10)
+			return resp.Value{Typ: "integer", Num: n}
+		case "RPUSH":
+			lists[args[1]] = append(lists[args[1]], args[2:]...)
+			return resp.Value{Typ: "integer", Num: int64(len(lists[args[1]]))}
+		case "LRANGE":
+			arr := []resp.Value{}
+			for _, v := range lists[args[1]] {
+				arr = append(arr, resp.Value{Typ: "bulk", Bulk: v})
+			}
+			return resp.Value{Typ: "array", Array: arr}
+		}
+		return resp.ErrorValue(resp.UnknownCommand(args[0]))
+	})
+	c := New(addr, Options{Pool: conn.Options{MaxActive: 2}})
+	defer c.Close()
+	ctx := context.Background()
+
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrNil) {
+		t.Fatalf("expected ErrNil for a missing key, got %v", err)
+	}
+	if err := c.Set(ctx, "k", "", 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "k"); err != nil || v != "" {
+		t.Fatalf("expected an empty string, got %q %v", v, err)
+	}
+	if lastPX != "1500" {
+		t.Fatalf("expected PX 1500, got %q", lastPX)
+	}
+	if n, err := c.Incr(ctx, "n"); err != nil || n != 1 {
+		t.Fatalf("Incr: %d %v", n, err)
+	}
+	if _, err := c.Incr(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	c.Set(ctx, "s", "abc", 0)
+	if _, err := c.Incr(ctx, "s"); !errors.Is(err, resp.ErrNotInteger) {
+		t.Fatalf("expected ErrNotInteger, got %v", err)
+	}
+	if n, err := c.Del(ctx, "k", "n", "missing"); err != nil || n != 2 {
+		t.Fatalf("Del: %d %v", n, err)
+	}
+	if n, err := c.RPush(ctx, "l", "a", 2, "c"); err != nil || n != 3 {
+		t.Fatalf("RPush: %d %v", n, err)
+	}
+	if got, err := c.LRange(ctx, "l", 0, -1); err != nil || strings.Join(got, ",") != "a,2,c" {
+		t.Fatalf("LRange: %q %v", got, err)
+	}
+	if got, err := c.LRange(ctx, "empty", 0, -1); err != nil || len(got) != 0 {
+		t.Fatalf("LRange on a missing key: %q %v", got, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.RPush(ctx, "shared", i); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, _ := c.LRange(ctx, "shared", 0, -1); len(got) != 8 {
+		t.Fatalf("expected 8 concurrent pushes, got %d", len(got))
+	}
+}
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *spanRecorder) ExportSpan(s *tracing.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestClient_Tracing(t *testing.T) {
+	addr := fakeServer(t, func(args []string) resp.Value {
+		return resp.ErrorValue(resp.UnknownCommand(args[0]))
+	})
+	rec := &spanRecorder{}
+	c := New(addr, Options{Tracer: tracing.NewTracer(rec)})
+	defer c.Close()
+
+	parent, _ := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := tracing.ContextWithSpanContext(context.Background(), parent)
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.Do(ctx, "nope")
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(rec.spans))
+	}
+	ping, nope := rec.spans[0], rec.spans[1]
+	if ping.Name != "PING" || ping.Kind != tracing.KindClient || ping.Error != "" {
+		t.Fatalf("unexpected span %+v", ping)
+	}
+	if ping.Context.TraceID != parent.TraceID || ping.Parent != parent.SpanID {
+		t.Fatal("span is not a child of the context's span")
+	}
+	if nope.Name != "NOPE" || nope.Error == "" {
+		t.Fatalf("error reply not recorded: %+v", nope)
+	}
+}
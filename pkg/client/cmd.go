@@ -0,0 +1,78 @@
+// Package client is a Go client for the server, built on the conn pool
+// and the resp codec.
+package client
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// Cmd is one command and, once it has run, its reply.
+type Cmd struct {
+	args []any
+	val  resp.Value
+	err  error
+}
+
+// Args returns the command name and arguments as queued.
+func (c *Cmd) Args() []any { return c.args }
+
+// Val returns the raw reply.
+func (c *Cmd) Val() resp.Value { return c.val }
+
+// Err returns the error reply, or the I/O error that kept the command from
+// getting one.
+func (c *Cmd) Err() error { return c.err }
+
+// Scan decodes the reply into dst; see resp.Scan.
+func (c *Cmd) Scan(dst any) error {
+	if c.err != nil {
+		return c.err
+	}
+	return resp.Scan(c.val, dst)
+}
+
+func (c *Cmd) String() string {
+	return fmt.Sprint(c.args...)
+}
+
+// writeCommand sends args as an array of bulk strings, which is how
+// commands are framed on the wire.
+func writeCommand(w *resp.Writer, args []any) error {
+	if err := w.WriteArrayHeader(len(args)); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		var err error
+		switch a := arg.(type) {
+		case string:
+			err = w.WriteBulkString(a)
+		case []byte:
+			err = w.WriteBulkBytes(a)
+		case int:
+			err = w.WriteBulkString(strconv.Itoa(a))
+		case int64:
+			err = w.WriteBulkString(strconv.FormatInt(a, 10))
+		case uint64:
+			err = w.WriteBulkString(strconv.FormatUint(a, 10))
+		case float64:
+			err = w.WriteBulkString(strconv.FormatFloat(a, 'f', -1, 64))
+		case bool:
+			if a {
+				err = w.WriteBulkString("1")
+			} else {
+				err = w.WriteBulkString("0")
+			}
+		case fmt.Stringer:
+			err = w.WriteBulkString(a.String())
+		default:
+			err = w.WriteBulkString(fmt.Sprint(a))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
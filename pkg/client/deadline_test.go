@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// stallServer never answers STALL and replies OK to everything else.
+func stallServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				for {
+					args, err := r.ReadCommand(nil)
+					if err != nil {
+						return
+					}
+					if strings.EqualFold(string(args[0]), "STALL") {
+						continue
+					}
+					c.Write([]byte("+OK\r\n"))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestDeadlines(t *testing.T) {
+	addr := stallServer(t)
+
+	t.Run("read timeout discards the conn", func(t *testing.T) {
+		pool := conn.NewConnPoolWithOptions(addr, conn.Options{MaxActive: 1, MinIdle: 1})
+		c := NewFromPool(pool)
+		c.opts.ReadTimeout = 50 * time.Millisecond
+		defer c.Close()
+
+		err := c.Do(context.Background(), "STALL").Err()
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() {
+			t.Fatalf("expected a timeout, got %v", err)
+		}
+		if open, _ := pool.Stats(); open != 0 {
+			t.Fatalf("the timed out conn should be discarded, %d still open", open)
+		}
+		// A stale reply must not reach the next command on a reused conn.
+		if v := c.Do(context.Background(), "PING").Val(); v.Str != "OK" {
+			t.Fatalf("unexpected reply %v", v)
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		c := New(addr, Options{Pool: conn.Options{MaxActive: 1}})
+		defer c.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := c.Do(ctx, "STALL").Err(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+		if time.Since(start) > time.Second {
+			t.Fatal("the deadline was not applied to the read")
+		}
+		if err := c.Do(context.Background(), "SET", "k", "v").Err(); err != nil {
+			t.Fatalf("the pool should recover, got %v", err)
+		}
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		c := New(addr, Options{Pool: conn.Options{MaxActive: 1}})
+		defer c.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		if err := c.Do(ctx, "STALL").Err(); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("blocking commands ignore the read timeout", func(t *testing.T) {
+		if !isBlocking([]any{"blpop", "k", 0}) || isBlocking([]any{"GET", "k"}) {
+			t.Fatal("isBlocking misclassified a command")
+		}
+	})
+}
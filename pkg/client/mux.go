@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// mux funnels concurrent commands through a few shared connections. Each
+// connection has a writer that batches whatever is queued into one flush
+// and a reader that hands replies back in the order the commands were
+// written, so callers never wait for a free connection.
+type mux struct {
+	pool     *conn.Pool // dials with the pool's dialer and handshake
+	timeouts timeouts
+	next     atomic.Uint32
+
+	mu     sync.Mutex
+	conns  []*muxConn
+	closed bool
+
+	redial []sync.Mutex // one per connection, held while replacing it
+}
+
+type muxReq struct {
+	args []any
+	val  resp.Value
+	err  error
+	done chan struct{}
+}
+
+type muxConn struct {
+	cn      net.Conn
+	queue   chan *muxReq // waiting to be written
+	pending chan *muxReq // written, waiting for their reply
+
+	once sync.Once
+	err  error // set before done is closed
+	done chan struct{}
+}
+
+func newMux(pool *conn.Pool, n int, t timeouts) *mux {
+	return &mux{pool: pool, timeouts: t, conns: make([]*muxConn, n), redial: make([]sync.Mutex, n)}
+}
+
+// do runs one command on the next connection. If ctx ends first the
+// command is abandoned; its reply is still read and dropped so the
+// connection stays in step.
+func (m *mux) do(ctx context.Context, args []any) *Cmd {
+	cmd := &Cmd{args: args}
+	mc, err := m.conn(ctx)
+	if err != nil {
+		cmd.err = err
+		return cmd
+	}
+
+	req := &muxReq{args: args, done: make(chan struct{})}
+	select {
+	case mc.queue <- req:
+	case <-mc.done:
+		cmd.err = mc.err
+		return cmd
+	case <-ctx.Done():
+		cmd.err = ctx.Err()
+		return cmd
+	}
+
+	select {
+	case <-req.done:
+	case <-mc.done:
+		select {
+		case <-req.done:
+		default:
+			cmd.err = mc.err
+			return cmd
+		}
+	case <-ctx.Done():
+		cmd.err = ctx.Err()
+		return cmd
+	}
+	cmd.val, cmd.err = req.val, req.err
+	return cmd
+}
+
+// conn returns the next connection round-robin, dialing a replacement for
+// one that failed. Dials take only that connection's redial lock, so while
+// one waits out its backoff and circuit, commands bound for the other
+// connections keep flowing.
+func (m *mux) conn(ctx context.Context) (*muxConn, error) {
+	i := int(m.next.Add(1)) % len(m.conns)
+	if mc, err := m.current(i); mc != nil || err != nil {
+		return mc, err
+	}
+
+	m.redial[i].Lock()
+	defer m.redial[i].Unlock()
+	// Whoever held the lock before may have replaced it already.
+	if mc, err := m.current(i); mc != nil || err != nil {
+		return mc, err
+	}
+	cn, err := m.pool.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mc := &muxConn{
+		cn:      cn,
+		queue:   make(chan *muxReq, 128),
+		pending: make(chan *muxReq, 1024),
+		done:    make(chan struct{}),
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		cn.Close()
+		return nil, net.ErrClosed
+	}
+	m.conns[i] = mc
+	m.mu.Unlock()
+	go mc.write(m.timeouts.write)
+	go mc.read(m.timeouts.read)
+	return mc, nil
+}
+
+// current returns connection i if it is usable, nil if it needs dialing.
+func (m *mux) current(i int) (*muxConn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, net.ErrClosed
+	}
+	if mc := m.conns[i]; mc != nil && !mc.failed() {
+		return mc, nil
+	}
+	return nil, nil
+}
+
+func (m *mux) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for _, mc := range m.conns {
+		if mc != nil {
+			mc.fail(net.ErrClosed)
+		}
+	}
+}
+
+func (mc *muxConn) failed() bool {
+	select {
+	case <-mc.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// fail closes the connection; every command without a reply gets err.
+func (mc *muxConn) fail(err error) {
+	mc.once.Do(func() {
+		mc.err = err
+		close(mc.done)
+		mc.cn.Close()
+	})
+}
+
+func (mc *muxConn) write(timeout time.Duration) {
+	w := resp.NewWriter(mc.cn)
+	for {
+		var req *muxReq
+		select {
+		case req = <-mc.queue:
+		case <-mc.done:
+			return
+		}
+		// Batch everything queued so far into one flush.
+		for req != nil {
+			select {
+			case mc.pending <- req:
+			case <-mc.done:
+				return
+			}
+			if err := writeCommand(w, req.args); err != nil {
+				mc.fail(err)
+				return
+			}
+			select {
+			case req = <-mc.queue:
+			default:
+				req = nil
+			}
+		}
+		if timeout > 0 {
+			mc.cn.SetWriteDeadline(time.Now().Add(timeout))
+		}
+		if err := w.Flush(); err != nil {
+			mc.fail(err)
+			return
+		}
+	}
+}
+
+func (mc *muxConn) read(timeout time.Duration) {
+	r := resp.NewReader(mc.cn)
+	for {
+		var req *muxReq
+		select {
+		case req = <-mc.pending:
+		case <-mc.done:
+			return
+		}
+		if timeout > 0 {
+			mc.cn.SetReadDeadline(time.Now().Add(timeout))
+		} else {
+			mc.cn.SetReadDeadline(time.Time{})
+		}
+		v, err := r.ReadValue()
+		if err != nil {
+			mc.fail(err)
+			return
+		}
+		req.val, req.err = v, v.Err()
+		close(req.done)
+	}
+}
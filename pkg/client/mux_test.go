@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+func TestMultiplex(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		data = map[string]string{}
+	)
+	addr := fakeServer(t, func(args []string) resp.Value {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "SET":
+			data[args[1]] = args[2]
+			return resp.Value{Typ: "string", Str: "OK"}
+		case "GET":
+			return resp.Value{Typ: "bulk", Bulk: data[args[1]]}
+		}
+		return resp.ErrorValue(resp.UnknownCommand(args[0]))
+	})
+
+	var dials atomic.Int32
+	c := New(addr, Options{
+		Pool: conn.Options{
+			MaxActive: 1,
+			Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials.Add(1)
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+		Multiplex: 1,
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, val := fmt.Sprintf("k%d", i), fmt.Sprintf("v%d", i)
+			for range 20 {
+				if err := c.Set(ctx, key, val, 0); err != nil {
+					t.Error(err)
+					return
+				}
+				if got, err := c.Get(ctx, key); err != nil || got != val {
+					t.Errorf("GET %s = %q %v, replies out of order", key, got, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := dials.Load(); n != 1 {
+		t.Fatalf("expected all commands on one connection, dialed %d", n)
+	}
+
+	t.Run("error replies stay in step", func(t *testing.T) {
+		if err := c.Do(ctx, "NOPE").Err(); !resp.HasCode(err, "ERR") {
+			t.Fatalf("expected an error reply, got %v", err)
+		}
+		if got, _ := c.Get(ctx, "k1"); got != "v1" {
+			t.Fatalf("GET after an error reply = %q", got)
+		}
+	})
+
+	t.Run("reconnects after a failure", func(t *testing.T) {
+		c.mux.conns[0].fail(net.ErrClosed)
+		if got, err := c.Get(ctx, "k2"); err != nil || got != "v2" {
+			t.Fatalf("GET after reconnect = %q %v", got, err)
+		}
+		if n := dials.Load(); n != 2 {
+			t.Fatalf("expected one redial, dialed %d in total", n)
+		}
+	})
+}
+
+// A redial that hangs must not hold up commands on the other connections.
+func TestMultiplexRedialDoesNotBlock(t *testing.T) {
+	addr := fakeServer(t, func(args []string) resp.Value {
+		return resp.Value{Typ: "string", Str: "OK"}
+	})
+	var dials atomic.Int32
+	blocked := make(chan struct{})
+	release := make(chan struct{})
+	c := New(addr, Options{
+		Pool: conn.Options{
+			MaxActive: 1,
+			Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if dials.Add(1) > 2 {
+					close(blocked)
+					select {
+					case <-release:
+					case <-ctx.Done():
+						return nil, ctx.Err()
+					}
+				}
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+		Multiplex: 2,
+	})
+	defer c.Close()
+	ctx := context.Background()
+	for range 2 {
+		if err := c.Do(ctx, "PING").Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Fail the connection round robin picks next, so the next command
+	// redials and the one after it goes to the healthy connection.
+	c.mux.conns[int(c.mux.next.Load()+1)%2].fail(net.ErrClosed)
+	redialed := make(chan error, 1)
+	go func() { redialed <- c.Do(ctx, "PING").Err() }()
+	<-blocked
+
+	healthy := make(chan error, 1)
+	go func() { healthy <- c.Do(ctx, "PING").Err() }()
+	select {
+	case err := <-healthy:
+		if err != nil {
+			t.Fatalf("command on the healthy connection: %v", err)
+		}
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("command on the healthy connection waited for the redial")
+	}
+	close(release)
+	if err := <-redialed; err != nil {
+		t.Fatalf("command on the redialed connection: %v", err)
+	}
+}
+
+func BenchmarkMultiplex(b *testing.B) {
+	for _, mode := range []struct {
+		name string
+		opts Options
+	}{
+		{"pool", Options{Pool: conn.Options{MaxActive: 8}}},
+		{"mux", Options{Pool: conn.Options{MaxActive: 1}, Multiplex: 1}},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			addr := fakeServer(b, func(args []string) resp.Value {
+				return resp.Value{Typ: "string", Str: "OK"}
+			})
+			c := New(addr, mode.opts)
+			defer c.Close()
+			ctx := context.Background()
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := c.Do(ctx, "SET", "k", "v").Err(); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
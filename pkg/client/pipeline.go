@@ -0,0 +1,163 @@
+package client
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// Pipeline queues commands and sends them in a single write on one pooled
+// connection, then reads the replies back in order. It is not safe for
+// concurrent use.
+type Pipeline struct {
+	pool     *conn.Pool
+	timeouts timeouts
+	cmds     []*Cmd
+}
+
+// NewPipeline returns a pipeline bounded only by the context passed to Exec.
+func NewPipeline(pool *conn.Pool) *Pipeline {
+	return &Pipeline{pool: pool}
+}
+
+// Do queues a command. Its reply is available from the returned Cmd once
+// Exec has run.
+func (p *Pipeline) Do(args ...any) *Cmd {
+	cmd := &Cmd{args: args}
+	p.cmds = append(p.cmds, cmd)
+	return cmd
+}
+
+// Len reports how many commands are queued.
+func (p *Pipeline) Len() int { return len(p.cmds) }
+
+// Discard drops the queued commands without sending them.
+func (p *Pipeline) Discard() { p.cmds = nil }
+
+// Exec sends every queued command and reads their replies, then empties
+// the queue. Error replies are stored on their Cmd and do not stop the
+// rest; the first one is also returned. An I/O error fails every command
+// that did not get a reply and discards the connection.
+func (p *Pipeline) Exec(ctx context.Context) ([]*Cmd, error) {
+	cmds := p.cmds
+	p.cmds = nil
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+
+	c, err := p.pool.Get(ctx)
+	if err != nil {
+		return cmds, fail(cmds, err)
+	}
+	if err := roundTrip(ctx, c, cmds, p.timeouts); err != nil {
+		p.pool.Discard(c)
+		return cmds, err
+	}
+	p.pool.Put(c)
+
+	for _, cmd := range cmds {
+		if cmd.err != nil {
+			return cmds, cmd.err
+		}
+	}
+	return cmds, nil
+}
+
+// timeouts bound each write and each reply on top of the context. Zero
+// leaves only the context.
+type timeouts struct {
+	read, write time.Duration
+}
+
+// deadline is the earlier of ctx's deadline and d from now.
+func deadline(ctx context.Context, d time.Duration) time.Time {
+	dl, ok := ctx.Deadline()
+	if d > 0 {
+		if t := time.Now().Add(d); !ok || t.Before(dl) {
+			return t
+		}
+	}
+	if !ok {
+		return time.Time{}
+	}
+	return dl
+}
+
+// roundTrip writes cmds to c and reads one reply per command, moving c's
+// deadlines to honour ctx and t; cancelling ctx interrupts it. Blocking
+// commands are only bounded by ctx while waiting for their reply. Any
+// error leaves c in an unknown state, and the caller must discard it.
+func roundTrip(ctx context.Context, c net.Conn, cmds []*Cmd, t timeouts) error {
+	fired := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		c.SetDeadline(time.Unix(1, 0))
+		close(fired)
+	})
+	defer func() {
+		if !stop() {
+			<-fired
+		}
+		c.SetDeadline(time.Time{})
+	}()
+
+	c.SetWriteDeadline(deadline(ctx, t.write))
+	w := resp.NewWriter(c)
+	for _, cmd := range cmds {
+		if err := writeCommand(w, cmd.args); err != nil {
+			return fail(cmds, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fail(cmds, ctxErr(ctx, err))
+	}
+
+	r := resp.NewReader(c)
+	for i, cmd := range cmds {
+		read := t.read
+		if isBlocking(cmd.args) {
+			read = 0
+		}
+		c.SetReadDeadline(deadline(ctx, read))
+		v, err := r.ReadValue()
+		if err != nil {
+			return fail(cmds[i:], ctxErr(ctx, err))
+		}
+		cmd.val = v
+		cmd.err = v.Err()
+	}
+	return nil
+}
+
+// blockingCmds wait server-side for data, so a read timeout would cut them
+// short.
+var blockingCmds = map[string]bool{
+	"BLPOP": true, "BRPOP": true, "BLMOVE": true, "BRPOPLPUSH": true, "BLMPOP": true,
+	"BZPOPMIN": true, "BZPOPMAX": true, "XREAD": true, "XREADGROUP": true, "WAIT": true,
+}
+
+func isBlocking(args []any) bool {
+	return blockingCmds[strings.ToUpper(commandName(args))]
+}
+
+// ctxErr prefers the context's error over the I/O error it caused. A
+// deadline copied from ctx can fire just before ctx itself notices.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if dl, ok := ctx.Deadline(); ok && !time.Now().Before(dl) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+func fail(cmds []*Cmd, err error) error {
+	for _, cmd := range cmds {
+		cmd.err = err
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// fakeServer answers every command with handle and returns its address.
+// A PING without arguments always gets PONG so pool health checks pass.
+func fakeServer(t testing.TB, handle func(args []string) resp.Value) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				w := resp.NewWriter(c)
+				for {
+					raw, err := r.ReadCommand(nil)
+					if err != nil {
+						return
+					}
+					args := make([]string, len(raw))
+					for i, a := range raw {
+						args[i] = string(a)
+					}
+					reply := resp.Value{Typ: "string", Str: "PONG"}
+					if len(args) != 1 || !strings.EqualFold(args[0], "PING") {
+						reply = handle(args)
+					}
+					w.WriteValue(reply)
+					if r.Buffered() == 0 {
+						if err := w.Flush(); err != nil {
+							return
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestPipeline(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		data = map[string]string{}
+	)
+	addr := fakeServer(t, func(args []string) resp.Value {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "SET":
+			data[args[1]] = args[2]
+			return resp.Value{Typ: "string", Str: "OK"}
+		case "GET":
+			v, ok := data[args[1]]
+			if !ok {
+				return resp.Null
+			}
+			return resp.Value{Typ: "bulk", Bulk: v}
+		}
+		return resp.ErrorValue(resp.UnknownCommand(args[0]))
+	})
+	pool := conn.NewConnPoolWithOptions(addr, conn.Options{MaxActive: 1})
+	defer pool.Close()
+
+	p := NewPipeline(pool)
+	set := p.Do("SET", "n", 42)
+	bad := p.Do("NOPE")
+	get := p.Do("GET", "n")
+	missing := p.Do("GET", "missing")
+	if p.Len() != 4 {
+		t.Fatalf("expected 4 queued commands, got %d", p.Len())
+	}
+
+	cmds, err := p.Exec(context.Background())
+	if !resp.HasCode(err, "ERR") {
+		t.Fatalf("expected the first error reply back, got %v", err)
+	}
+	if len(cmds) != 4 || p.Len() != 0 {
+		t.Fatalf("expected 4 replies and an empty queue, got %d and %d", len(cmds), p.Len())
+	}
+	if set.Err() != nil || set.Val().Str != "OK" {
+		t.Fatalf("SET: %v %v", set.Val(), set.Err())
+	}
+	if bad.Err() == nil {
+		t.Fatal("unknown command should carry its error reply")
+	}
+	var n int
+	if err := get.Scan(&n); err != nil || n != 42 {
+		t.Fatalf("GET: %d %v", n, err)
+	}
+	if !missing.Val().IsNull() {
+		t.Fatalf("GET missing: %v", missing.Val())
+	}
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		time.Sleep(time.Millisecond)
+		p.Do("GET", "n")
+		cmd := p.Do("GET", "n")
+		if _, err := p.Exec(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected deadline exceeded, got %v", err)
+		}
+		if !errors.Is(cmd.Err(), context.DeadlineExceeded) {
+			t.Fatalf("queued command should fail too, got %v", cmd.Err())
+		}
+	})
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// ErrClosed is returned when using a PubSub after Close.
+var ErrClosed = errors.New("client: pubsub is closed")
+
+// Message is a message published to a channel the PubSub listens on.
+// Pattern is set when it matched a pattern subscription.
+type Message struct {
+	Channel string
+	Pattern string
+	Payload string
+}
+
+// PubSub holds a dedicated connection in subscribed mode. Messages are
+// delivered on Channel. When the connection drops it is redialed and every
+// channel and pattern is subscribed again.
+type PubSub struct {
+	pool *conn.Pool
+	msgs chan *Message
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	cn       net.Conn
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+// Subscribe opens a PubSub listening on channels.
+func (c *Client) Subscribe(ctx context.Context, channels ...string) (*PubSub, error) {
+	ps, err := c.newPubSub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(channels) > 0 {
+		if err := ps.Subscribe(ctx, channels...); err != nil {
+			ps.Close()
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+// PSubscribe opens a PubSub listening on glob-style patterns.
+func (c *Client) PSubscribe(ctx context.Context, patterns ...string) (*PubSub, error) {
+	ps, err := c.newPubSub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(patterns) > 0 {
+		if err := ps.PSubscribe(ctx, patterns...); err != nil {
+			ps.Close()
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+func (c *Client) newPubSub(ctx context.Context) (*PubSub, error) {
+	cn, err := c.pool.Dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ps := &PubSub{
+		pool:     c.pool,
+		msgs:     make(chan *Message, 100),
+		cn:       cn,
+		channels: map[string]struct{}{},
+		patterns: map[string]struct{}{},
+	}
+	ps.ctx, ps.cancel = context.WithCancel(context.Background())
+	go ps.run(cn)
+	return ps, nil
+}
+
+// Channel returns the channel messages are delivered on. It is closed by
+// Close.
+func (ps *PubSub) Channel() <-chan *Message {
+	return ps.msgs
+}
+
+func (ps *PubSub) Subscribe(ctx context.Context, channels ...string) error {
+	return ps.update(ctx, "SUBSCRIBE", ps.channels, channels, true)
+}
+
+func (ps *PubSub) PSubscribe(ctx context.Context, patterns ...string) error {
+	return ps.update(ctx, "PSUBSCRIBE", ps.patterns, patterns, true)
+}
+
+// Unsubscribe stops listening on channels, or on every channel when none
+// are given.
+func (ps *PubSub) Unsubscribe(ctx context.Context, channels ...string) error {
+	return ps.update(ctx, "UNSUBSCRIBE", ps.channels, channels, false)
+}
+
+// PUnsubscribe stops listening on patterns, or on every pattern when none
+// are given.
+func (ps *PubSub) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	return ps.update(ctx, "PUNSUBSCRIBE", ps.patterns, patterns, false)
+}
+
+// update records the change in set and sends it. A failed write is not
+// reported: the reader notices the broken connection and the reconnect
+// sends the recorded subscriptions again.
+func (ps *PubSub) update(ctx context.Context, name string, set map[string]struct{}, names []string, add bool) error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.ctx.Err() != nil {
+		return ErrClosed
+	}
+	if add && len(names) == 0 {
+		return nil
+	}
+	if add {
+		for _, n := range names {
+			set[n] = struct{}{}
+		}
+	} else if len(names) == 0 {
+		clear(set)
+	} else {
+		for _, n := range names {
+			delete(set, n)
+		}
+	}
+
+	args := make([]any, 0, len(names)+1)
+	args = append(args, name)
+	for _, n := range names {
+		args = append(args, n)
+	}
+	if d, ok := ctx.Deadline(); ok {
+		ps.cn.SetWriteDeadline(d)
+		defer ps.cn.SetWriteDeadline(time.Time{})
+	}
+	w := resp.NewWriter(ps.cn)
+	if writeCommand(w, args) == nil {
+		w.Flush()
+	}
+	return nil
+}
+
+// Close leaves subscribed mode by closing the connection and closes the
+// message channel.
+func (ps *PubSub) Close() error {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.ctx.Err() != nil {
+		return nil
+	}
+	ps.cancel()
+	return ps.cn.Close()
+}
+
+// run reads from cn until it fails, then reconnects, for as long as the
+// PubSub is open.
+func (ps *PubSub) run(cn net.Conn) {
+	defer close(ps.msgs)
+	for {
+		r := resp.NewReader(cn)
+		for {
+			v, err := r.ReadValue()
+			if err != nil {
+				break
+			}
+			m := parseMessage(v)
+			if m == nil {
+				continue
+			}
+			select {
+			case ps.msgs <- m:
+			case <-ps.ctx.Done():
+				return
+			}
+		}
+		cn.Close()
+		if cn = ps.reconnect(); cn == nil {
+			return
+		}
+	}
+}
+
+// reconnect dials until it succeeds or the PubSub is closed, and then
+// restores every subscription on the new connection.
+func (ps *PubSub) reconnect() net.Conn {
+	delay := 50 * time.Millisecond
+	for {
+		if ps.ctx.Err() != nil {
+			return nil
+		}
+		cn, err := ps.pool.Dial(ps.ctx)
+		if err == nil {
+			if cn = ps.resubscribe(cn); cn != nil {
+				return cn
+			}
+			err = ErrClosed
+		}
+
+		wait := delay
+		var ce *conn.CircuitError
+		if errors.As(err, &ce) {
+			wait = max(wait, time.Until(ce.Until))
+		}
+		delay = min(2*delay, 2*time.Second)
+		t := time.NewTimer(wait)
+		select {
+		case <-ps.ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (ps *PubSub) resubscribe(cn net.Conn) net.Conn {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if ps.ctx.Err() != nil {
+		cn.Close()
+		return nil
+	}
+
+	w := resp.NewWriter(cn)
+	for _, sub := range []struct {
+		name string
+		set  map[string]struct{}
+	}{{"SUBSCRIBE", ps.channels}, {"PSUBSCRIBE", ps.patterns}} {
+		if len(sub.set) == 0 {
+			continue
+		}
+		args := []any{sub.name}
+		for _, n := range slices.Sorted(maps.Keys(sub.set)) {
+			args = append(args, n)
+		}
+		writeCommand(w, args)
+	}
+	if err := w.Flush(); err != nil {
+		cn.Close()
+		return nil
+	}
+	ps.cn = cn
+	return cn
+}
+
+// parseMessage turns a "message" or "pmessage" frame into a Message.
+// Subscription confirmations and other frames yield nil.
+func parseMessage(v resp.Value) *Message {
+	if (v.Typ != "array" && v.Typ != "push") || len(v.Array) < 3 {
+		return nil
+	}
+	switch text(v.Array[0]) {
+	case "message":
+		return &Message{Channel: text(v.Array[1]), Payload: text(v.Array[2])}
+	case "pmessage":
+		if len(v.Array) < 4 {
+			return nil
+		}
+		return &Message{Pattern: text(v.Array[1]), Channel: text(v.Array[2]), Payload: text(v.Array[3])}
+	}
+	return nil
+}
+
+func text(v resp.Value) string {
+	if v.Typ == "bulk" {
+		return v.Bulk
+	}
+	return v.Str
+}
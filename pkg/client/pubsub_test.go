@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// pubsubServer is a minimal broker: it tracks SUBSCRIBE/PSUBSCRIBE per
+// connection and delivers publish calls to matching subscribers.
+type pubsubServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	subs  map[net.Conn]map[string]bool // channel or "p:"+pattern
+	joins chan string
+}
+
+func newPubSubServer(t *testing.T) *pubsubServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &pubsubServer{ln: ln, subs: map[net.Conn]map[string]bool{}, joins: make(chan string, 16)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.subs[c] = map[string]bool{}
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	return s
+}
+
+func (s *pubsubServer) serve(c net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+	r := resp.NewReader(c)
+	for {
+		args, err := r.ReadCommand(nil)
+		if err != nil {
+			return
+		}
+		name := strings.ToUpper(string(args[0]))
+		s.mu.Lock()
+		for _, a := range args[1:] {
+			key := string(a)
+			if name == "PSUBSCRIBE" || name == "PUNSUBSCRIBE" {
+				key = "p:" + key
+			}
+			switch name {
+			case "SUBSCRIBE", "PSUBSCRIBE":
+				s.subs[c][key] = true
+			default:
+				delete(s.subs[c], key)
+			}
+			resp.WriteValue(c, resp.Value{Typ: "array", Array: []resp.Value{
+				{Typ: "bulk", Bulk: strings.ToLower(name)}, {Typ: "bulk", Bulk: string(a)}, {Typ: "integer", Num: int64(len(s.subs[c]))},
+			}})
+		}
+		s.mu.Unlock()
+		if name == "SUBSCRIBE" || name == "PSUBSCRIBE" {
+			s.joins <- name
+		}
+	}
+}
+
+func (s *pubsubServer) publish(channel, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c, subs := range s.subs {
+		if subs[channel] {
+			resp.WriteValue(c, resp.Value{Typ: "array", Array: []resp.Value{
+				{Typ: "bulk", Bulk: "message"}, {Typ: "bulk", Bulk: channel}, {Typ: "bulk", Bulk: payload},
+			}})
+		}
+		for key := range subs {
+			if pattern, ok := strings.CutPrefix(key, "p:"); ok {
+				if matched, _ := path.Match(pattern, channel); matched {
+					resp.WriteValue(c, resp.Value{Typ: "array", Array: []resp.Value{
+						{Typ: "bulk", Bulk: "pmessage"}, {Typ: "bulk", Bulk: pattern}, {Typ: "bulk", Bulk: channel}, {Typ: "bulk", Bulk: payload},
+					}})
+				}
+			}
+		}
+	}
+}
+
+// dropAll closes every subscriber connection, as a server restart would.
+func (s *pubsubServer) dropAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.subs {
+		c.Close()
+	}
+}
+
+func (s *pubsubServer) awaitJoin(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.joins:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no subscription arrived")
+	}
+}
+
+func receive(t *testing.T, ch <-chan *Message) *Message {
+	t.Helper()
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message delivered")
+		return nil
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	srv := newPubSubServer(t)
+	c := New(srv.ln.Addr().String(), Options{Pool: conn.Options{MaxActive: 1, MinBackoff: time.Millisecond}})
+	defer c.Close()
+	ctx := context.Background()
+
+	ps, err := c.Subscribe(ctx, "news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.awaitJoin(t)
+	if err := ps.PSubscribe(ctx, "user.*"); err != nil {
+		t.Fatal(err)
+	}
+	srv.awaitJoin(t)
+
+	srv.publish("news", "hello")
+	if m := receive(t, ps.Channel()); m.Channel != "news" || m.Payload != "hello" || m.Pattern != "" {
+		t.Fatalf("unexpected message %+v", m)
+	}
+	srv.publish("user.1", "joined")
+	if m := receive(t, ps.Channel()); m.Channel != "user.1" || m.Pattern != "user.*" || m.Payload != "joined" {
+		t.Fatalf("unexpected pattern message %+v", m)
+	}
+
+	t.Run("resubscribes after reconnect", func(t *testing.T) {
+		srv.dropAll()
+		srv.awaitJoin(t)
+		srv.awaitJoin(t)
+		srv.publish("news", "again")
+		if m := receive(t, ps.Channel()); m.Payload != "again" {
+			t.Fatalf("unexpected message %+v", m)
+		}
+		srv.publish("user.2", "back")
+		if m := receive(t, ps.Channel()); m.Pattern != "user.*" || m.Payload != "back" {
+			t.Fatalf("unexpected pattern message %+v", m)
+		}
+	})
+
+	ps.Close()
+	if _, ok := <-ps.Channel(); ok {
+		t.Fatal("Channel should be closed after Close")
+	}
+	if err := ps.Subscribe(ctx, "late"); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+}
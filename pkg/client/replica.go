@@ -0,0 +1,184 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// ReadPreference picks where read-only commands are sent.
+type ReadPreference int
+
+const (
+	// ReadPrimary sends everything to the primary.
+	ReadPrimary ReadPreference = iota
+	// ReadReplica sends reads to a random replica.
+	ReadReplica
+	// ReadNearest sends reads to whichever node, primary included,
+	// answered PING fastest at the last discovery.
+	ReadNearest
+)
+
+// readOnlyCmds are the commands that may be served by a replica.
+var readOnlyCmds = map[string]bool{
+	"GET": true, "MGET": true, "GETRANGE": true, "STRLEN": true, "EXISTS": true,
+	"TYPE": true, "TTL": true, "PTTL": true, "KEYS": true, "SCAN": true, "RANDOMKEY": true,
+	"DBSIZE": true, "LRANGE": true, "LLEN": true, "LINDEX": true, "RLEN": true, "RRANGE": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HEXISTS": true, "HLEN": true,
+	"HKEYS": true, "HVALS": true, "HSTRLEN": true, "XRANGE": true,
+}
+
+type replica struct {
+	addr string
+	pool *conn.Pool
+	rtt  time.Duration
+}
+
+// replicaSet is the last discovered set of replicas.
+type replicaSet struct {
+	mu         sync.Mutex
+	checked    time.Time
+	nodes      []*replica
+	primaryRTT time.Duration
+}
+
+func (rs *replicaSet) close() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for _, n := range rs.nodes {
+		n.pool.Close()
+	}
+	rs.nodes = nil
+}
+
+// readPool returns the replica pool a command should run on, or nil for
+// the primary.
+func (c *Client) readPool(ctx context.Context, args []any) *conn.Pool {
+	if c.replicas == nil || !readOnlyCmds[strings.ToUpper(commandName(args))] {
+		return nil
+	}
+	rs := c.replicas
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if time.Since(rs.checked) >= c.opts.ReplicaRefresh {
+		c.discover(ctx)
+	}
+	if len(rs.nodes) == 0 {
+		return nil
+	}
+	if c.opts.ReadPreference == ReadNearest {
+		best := rs.nodes[0]
+		for _, n := range rs.nodes[1:] {
+			if n.rtt < best.rtt {
+				best = n
+			}
+		}
+		if rs.primaryRTT <= best.rtt {
+			return nil
+		}
+		return best.pool
+	}
+	return rs.nodes[rand.N(len(rs.nodes))].pool
+}
+
+// discover refreshes the replica set from the primary, keeping the pools of
+// replicas that are still listed. A failed lookup keeps the previous set
+// until the next refresh. The caller holds rs.mu.
+func (c *Client) discover(ctx context.Context) {
+	rs := c.replicas
+	rs.checked = time.Now()
+
+	addrs, ok := parseRole(c.doOn(ctx, c.pool, []any{"ROLE"}).val)
+	if !ok {
+		info := c.doOn(ctx, c.pool, []any{"INFO", "replication"})
+		if info.err != nil {
+			return
+		}
+		addrs = parseReplicationInfo(text(info.val))
+	}
+
+	old := make(map[string]*replica, len(rs.nodes))
+	for _, n := range rs.nodes {
+		old[n.addr] = n
+	}
+	nodes := make([]*replica, 0, len(addrs))
+	for _, addr := range addrs {
+		n, ok := old[addr]
+		if ok {
+			delete(old, addr)
+		} else {
+			n = &replica{addr: addr, pool: conn.NewConnPoolWithOptions(addr, c.opts.Pool)}
+		}
+		nodes = append(nodes, n)
+	}
+	for _, n := range old {
+		n.pool.Close()
+	}
+	rs.nodes = nodes
+
+	if c.opts.ReadPreference == ReadNearest {
+		rs.primaryRTT = c.ping(ctx, c.pool)
+		for _, n := range rs.nodes {
+			n.rtt = c.ping(ctx, n.pool)
+		}
+	}
+}
+
+// ping times a PING round trip; unreachable nodes get the largest duration.
+func (c *Client) ping(ctx context.Context, pool *conn.Pool) time.Duration {
+	start := time.Now()
+	if err := c.doOn(ctx, pool, []any{"PING"}).err; err != nil {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Since(start)
+}
+
+// parseRole reads the replica addresses out of a primary's ROLE reply:
+// ["master", offset, [[ip, port, offset], ...]].
+func parseRole(v resp.Value) ([]string, bool) {
+	if v.Typ != "array" || len(v.Array) < 3 || text(v.Array[0]) != "master" {
+		return nil, false
+	}
+	var addrs []string
+	for _, r := range v.Array[2].Array {
+		if len(r.Array) < 2 {
+			continue
+		}
+		port := text(r.Array[1])
+		if r.Array[1].Typ == "integer" {
+			port = strconv.FormatInt(r.Array[1].Num, 10)
+		}
+		addrs = append(addrs, net.JoinHostPort(text(r.Array[0]), port))
+	}
+	return addrs, true
+}
+
+// parseReplicationInfo reads the online replicas out of INFO replication,
+// whose lines look like "slave0:ip=10.0.0.2,port=6379,state=online,...".
+func parseReplicationInfo(info string) []string {
+	var addrs []string
+	for _, line := range strings.Split(info, "\n") {
+		name, fields, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok || !strings.HasPrefix(name, "slave") {
+			continue
+		}
+		kv := map[string]string{}
+		for _, f := range strings.Split(fields, ",") {
+			if k, v, ok := strings.Cut(f, "="); ok {
+				kv[k] = v
+			}
+		}
+		if kv["ip"] == "" || kv["port"] == "" || (kv["state"] != "" && kv["state"] != "online") {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(kv["ip"], kv["port"]))
+	}
+	return addrs
+}
@@ -0,0 +1,117 @@
+package client
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// roleServer answers GET with name and ROLE with the given replicas.
+func roleServer(t *testing.T, name string, replicas ...string) string {
+	return fakeServer(t, func(args []string) resp.Value {
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			return resp.Value{Typ: "bulk", Bulk: name}
+		case "SET":
+			return resp.Value{Typ: "string", Str: name}
+		case "ROLE":
+			list := []resp.Value{}
+			for _, r := range replicas {
+				host, port, _ := net.SplitHostPort(r)
+				list = append(list, resp.Value{Typ: "array", Array: []resp.Value{
+					{Typ: "bulk", Bulk: host}, {Typ: "bulk", Bulk: port}, {Typ: "bulk", Bulk: "0"},
+				}})
+			}
+			return resp.Value{Typ: "array", Array: []resp.Value{
+				{Typ: "bulk", Bulk: "master"}, {Typ: "integer", Num: 0}, {Typ: "array", Array: list},
+			}}
+		}
+		return resp.ErrorValue(resp.UnknownCommand(args[0]))
+	})
+}
+
+func TestReadPreference(t *testing.T) {
+	ctx := context.Background()
+	pool := conn.Options{MaxActive: 1, DialAttempts: 1}
+
+	t.Run("reads go to the replica", func(t *testing.T) {
+		primary := roleServer(t, "primary", roleServer(t, "replica"))
+		c := New(primary, Options{Pool: pool, ReadPreference: ReadReplica})
+		defer c.Close()
+		if v, err := c.Get(ctx, "k"); err != nil || v != "replica" {
+			t.Fatalf("GET went to %q (%v), want the replica", v, err)
+		}
+		if v := c.Do(ctx, "SET", "k", "v").Val().Str; v != "primary" {
+			t.Fatalf("SET went to %q, want the primary", v)
+		}
+	})
+
+	t.Run("falls back to the primary", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		dead := ln.Addr().String()
+		ln.Close()
+		primary := roleServer(t, "primary", dead)
+		c := New(primary, Options{Pool: pool, ReadPreference: ReadReplica})
+		defer c.Close()
+		if v, err := c.Get(ctx, "k"); err != nil || v != "primary" {
+			t.Fatalf("GET went to %q (%v), want the primary", v, err)
+		}
+	})
+
+	t.Run("primary preference ignores replicas", func(t *testing.T) {
+		primary := roleServer(t, "primary", roleServer(t, "replica"))
+		c := New(primary, Options{Pool: pool})
+		defer c.Close()
+		if v, _ := c.Get(ctx, "k"); v != "primary" {
+			t.Fatalf("GET went to %q, want the primary", v)
+		}
+	})
+
+	t.Run("nearest picks the lowest latency", func(t *testing.T) {
+		primary := roleServer(t, "primary", roleServer(t, "replica"))
+		c := New(primary, Options{Pool: pool, ReadPreference: ReadNearest, ReplicaRefresh: time.Hour})
+		defer c.Close()
+		c.Get(ctx, "k") // discovers
+		c.replicas.mu.Lock()
+		c.replicas.primaryRTT = time.Second
+		c.replicas.nodes[0].rtt = time.Millisecond
+		c.replicas.mu.Unlock()
+		if v, _ := c.Get(ctx, "k"); v != "replica" {
+			t.Fatalf("GET went to %q, want the nearer replica", v)
+		}
+		c.replicas.mu.Lock()
+		c.replicas.primaryRTT = time.Microsecond
+		c.replicas.mu.Unlock()
+		if v, _ := c.Get(ctx, "k"); v != "primary" {
+			t.Fatalf("GET went to %q, want the nearer primary", v)
+		}
+	})
+}
+
+func TestParseReplicationInfo(t *testing.T) {
+	info := "# Replication\r\nrole:master\r\nconnected_slaves:2\r\n" +
+		"slave0:ip=10.0.0.2,port=6380,state=online,offset=1,lag=0\r\n" +
+		"slave1:ip=10.0.0.3,port=6381,state=wait_bgsave,offset=0,lag=0\r\n"
+	if got, want := parseReplicationInfo(info), []string{"10.0.0.2:6380"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	role := resp.Value{Typ: "array", Array: []resp.Value{
+		{Typ: "bulk", Bulk: "master"}, {Typ: "integer", Num: 10},
+		{Typ: "array", Array: []resp.Value{{Typ: "array", Array: []resp.Value{
+			{Typ: "bulk", Bulk: "::1"}, {Typ: "integer", Num: 6380}, {Typ: "bulk", Bulk: strconv.Itoa(10)},
+		}}}},
+	}}
+	if got, ok := parseRole(role); !ok || !reflect.DeepEqual(got, []string{"[::1]:6380"}) {
+		t.Fatalf("parseRole = %q %v", got, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/tracing"
+)
+
+// RetryPolicy controls how commands are retried after transient errors:
+// dropped or reset connections, failed dials and replies such as LOADING
+// or TRYAGAIN that ask the client to come back later.
+type RetryPolicy struct {
+	// MaxRetries is how many times a command is retried after its first
+	// attempt. Zero disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between
+	// attempts. Default to 8ms and 512ms.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = 8 * time.Millisecond
+	}
+	if p.MaxBackoff < p.MinBackoff {
+		p.MaxBackoff = max(512*time.Millisecond, p.MinBackoff)
+	}
+	return p
+}
+
+// backoff returns the delay before the given retry, counting from 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+// RetryError is returned when a command still failed with a transient error
+// after every retry.
+type RetryError struct {
+	Cmd      string
+	Attempts int
+	Err      error // the last error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("client: %s failed after %d attempts: %v", e.Cmd, e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// DoRetry is Do with an explicit idempotency flag. Only idempotent commands
+// are retried, since a dropped connection can hide whether the server ran
+// the command.
+func (c *Client) DoRetry(ctx context.Context, idempotent bool, args ...any) *Cmd {
+	ctx, span := c.opts.Tracer.Start(ctx, strings.ToUpper(commandName(args)), tracing.KindClient,
+		tracing.String("db.system", "redis"),
+		tracing.String("db.operation", strings.ToUpper(commandName(args))))
+	cmd := c.doRetry(ctx, idempotent, args)
+	span.SetError(cmd.err)
+	span.Finish()
+	return cmd
+}
+
+func (c *Client) doRetry(ctx context.Context, idempotent bool, args []any) *Cmd {
+	cmd := c.do(ctx, args)
+	if !idempotent || c.retry.MaxRetries == 0 {
+		return cmd
+	}
+	for attempt := 1; isTransient(cmd.err) && ctx.Err() == nil; attempt++ {
+		if attempt > c.retry.MaxRetries {
+			cmd.err = &RetryError{Cmd: commandName(args), Attempts: attempt, Err: cmd.err}
+			return cmd
+		}
+		t := time.NewTimer(c.retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return cmd
+		case <-t.C:
+		}
+		cmd = c.do(ctx, args)
+	}
+	return cmd
+}
+
+// isTransient reports whether err may go away by trying again.
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch resp.ErrorCode(err) {
+	case "LOADING", "TRYAGAIN", "CLUSTERDOWN", "MASTERDOWN":
+		return true
+	case "":
+	default:
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
+
+// idempotentCmds are commands whose effect does not change when they run
+// twice, so a retry after a lost reply is safe. Relative EXPIRE and
+// PEXPIRE are left out because every retry pushes the deadline further,
+// and SET only counts without NX, XX or GET; see isIdempotent.
+var idempotentCmds = map[string]bool{
+	"PING": true, "ECHO": true, "INFO": true, "TIME": true, "DBSIZE": true,
+	"GET": true, "MGET": true, "GETRANGE": true, "STRLEN": true, "EXISTS": true,
+	"TYPE": true, "TTL": true, "PTTL": true, "KEYS": true, "SCAN": true, "RANDOMKEY": true,
+	"SET": true, "MSET": true, "SETRANGE": true, "DEL": true, "UNLINK": true,
+	"EXPIREAT": true, "PEXPIREAT": true, "PERSIST": true,
+	"LRANGE": true, "LLEN": true, "LINDEX": true, "LSET": true, "RLEN": true, "RRANGE": true,
+	"HGET": true, "HMGET": true, "HGETALL": true, "HEXISTS": true, "HLEN": true,
+	"HKEYS": true, "HVALS": true, "HSTRLEN": true, "HSET": true, "HDEL": true,
+	"XRANGE": true,
+}
+
+func isIdempotent(args []any) bool {
+	name := strings.ToUpper(commandName(args))
+	if name == "SET" && len(args) > 3 {
+		// A retried SET NX or XX would see its own first write and report
+		// that it did nothing, and a retried SET GET would return the
+		// caller's own value as the old one.
+		for _, arg := range args[3:] {
+			switch strings.ToUpper(fmt.Sprint(arg)) {
+			case "NX", "XX", "GET":
+				return false
+			}
+		}
+	}
+	return idempotentCmds[name]
+}
+
+func commandName(args []any) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return fmt.Sprint(args[0])
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/conn"
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// flakyServer drops the connection instead of answering the first drops
+// commands other than PING, then replies OK. It counts every command.
+func flakyServer(t *testing.T, drops int32) (string, *atomic.Int32) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var seen atomic.Int32
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				for {
+					args, err := r.ReadCommand(nil)
+					if err != nil {
+						return
+					}
+					if strings.EqualFold(string(args[0]), "PING") {
+						c.Write([]byte("+PONG\r\n"))
+						continue
+					}
+					if seen.Add(1) <= drops {
+						return
+					}
+					c.Write([]byte("+OK\r\n"))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), &seen
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	policy := RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	t.Run("idempotent command is retried", func(t *testing.T) {
+		addr, seen := flakyServer(t, 2)
+		c := New(addr, Options{Pool: conn.Options{MaxActive: 1}, Retry: policy})
+		defer c.Close()
+		if err := c.Set(ctx, "k", "v", 0); err != nil {
+			t.Fatalf("expected SET to succeed on the third attempt, got %v", err)
+		}
+		if seen.Load() != 3 {
+			t.Fatalf("expected 3 attempts, got %d", seen.Load())
+		}
+	})
+
+	t.Run("non-idempotent command is not retried", func(t *testing.T) {
+		addr, seen := flakyServer(t, 1)
+		c := New(addr, Options{Pool: conn.Options{MaxActive: 1}, Retry: policy})
+		defer c.Close()
+		if _, err := c.Incr(ctx, "n"); err == nil {
+			t.Fatal("expected INCR to fail")
+		}
+		if seen.Load() != 1 {
+			t.Fatalf("expected a single attempt, got %d", seen.Load())
+		}
+		if err := c.DoRetry(ctx, true, "INCR", "n").Err(); err != nil {
+			t.Fatalf("explicitly idempotent INCR should succeed, got %v", err)
+		}
+	})
+
+	t.Run("conditional SET and relative EXPIRE are not retried", func(t *testing.T) {
+		for _, args := range [][]any{
+			{"SET", "lock", "v", "NX", "PX", 30000},
+			{"SET", "k", "v", "xx"},
+			{"SET", "k", "v", "GET"},
+			{"EXPIRE", "k", 10},
+			{"PEXPIRE", "k", 10000},
+		} {
+			addr, seen := flakyServer(t, 1)
+			c := New(addr, Options{Pool: conn.Options{MaxActive: 1}, Retry: policy})
+			if err := c.Do(ctx, args...).Err(); err == nil {
+				t.Errorf("%v: expected the dropped reply to fail the call", args)
+			}
+			if seen.Load() != 1 {
+				t.Errorf("%v: expected a single attempt, got %d", args, seen.Load())
+			}
+			c.Close()
+		}
+		if !isIdempotent([]any{"SET", "k", "v", "EX", 10}) || !isIdempotent([]any{"EXPIREAT", "k", 1}) {
+			t.Fatal("plain SET and absolute EXPIREAT are still safe to retry")
+		}
+	})
+
+	t.Run("exhausted retries", func(t *testing.T) {
+		addr, seen := flakyServer(t, 10)
+		c := New(addr, Options{Pool: conn.Options{MaxActive: 1}, Retry: policy})
+		defer c.Close()
+		_, err := c.Get(ctx, "k")
+		var re *RetryError
+		if !errors.As(err, &re) || re.Attempts != 3 || re.Cmd != "GET" {
+			t.Fatalf("expected a RetryError after 3 attempts, got %v", err)
+		}
+		if !isTransient(re.Err) {
+			t.Fatalf("RetryError should wrap the transient error, got %v", re.Err)
+		}
+		if seen.Load() != 3 {
+			t.Fatalf("expected 3 attempts, got %d", seen.Load())
+		}
+	})
+
+	t.Run("error replies are final", func(t *testing.T) {
+		if isTransient(resp.ErrWrongType) || !isTransient(resp.NewError("LOADING", "loading")) {
+			t.Fatal("only retry-later replies are transient")
+		}
+	})
+}
@@ -3,18 +3,100 @@ package pkg
 type CMD string
 
 const (
-	PING_CMD CMD = "PING"
-
-	SET_CMD CMD = "SET"
-	GET_CMD CMD = "GET"
-	DEL_CMD CMD = "DEL"
-
-	RPUSH_CMD  CMD = "RPUSH"
-	RLEN_CMD   CMD = "RLEN"
-	RRANGE_CMD CMD = "RRANGE"
-	RPOP_CMD   CMD = "RPOP"
-	LPOP_CMD   CMD = "LPOP"
-	LPUSH_CMD  CMD = "LPUSH"
+	PING_CMD   CMD = "PING"
+	TIME_CMD   CMD = "TIME"
+	INFO_CMD   CMD = "INFO"
+	CONFIG_CMD CMD = "CONFIG"
+	CLIENT_CMD CMD = "CLIENT"
+	SELECT_CMD CMD = "SELECT"
+
+	LATENCY_CMD CMD = "LATENCY"
+
+	SET_CMD    CMD = "SET"
+	SETNX_CMD  CMD = "SETNX"
+	SETEX_CMD  CMD = "SETEX"
+	PSETEX_CMD CMD = "PSETEX"
+	GET_CMD    CMD = "GET"
+	GETSET_CMD CMD = "GETSET"
+	GETDEL_CMD CMD = "GETDEL"
+	GETEX_CMD  CMD = "GETEX"
+	DEL_CMD    CMD = "DEL"
+	UNLINK_CMD CMD = "UNLINK"
+
+	EXISTS_CMD CMD = "EXISTS"
+	TOUCH_CMD  CMD = "TOUCH"
+	COPY_CMD   CMD = "COPY"
+	MOVE_CMD   CMD = "MOVE"
+	TYPE_CMD   CMD = "TYPE"
+	OBJECT_CMD CMD = "OBJECT"
+
+	DBSIZE_CMD    CMD = "DBSIZE"
+	RANDOMKEY_CMD CMD = "RANDOMKEY"
+
+	GETRANGE_CMD CMD = "GETRANGE"
+	SETRANGE_CMD CMD = "SETRANGE"
+	MGET_CMD     CMD = "MGET"
+	MSET_CMD     CMD = "MSET"
+	MSETNX_CMD   CMD = "MSETNX"
+
+	EXPIRE_CMD      CMD = "EXPIRE"
+	PEXPIRE_CMD     CMD = "PEXPIRE"
+	EXPIREAT_CMD    CMD = "EXPIREAT"
+	PEXPIREAT_CMD   CMD = "PEXPIREAT"
+	TTL_CMD         CMD = "TTL"
+	PTTL_CMD        CMD = "PTTL"
+	EXPIRETIME_CMD  CMD = "EXPIRETIME"
+	PEXPIRETIME_CMD CMD = "PEXPIRETIME"
+	PERSIST_CMD     CMD = "PERSIST"
+
+	INCR_CMD   CMD = "INCR"
+	DECR_CMD   CMD = "DECR"
+	INCRBY_CMD CMD = "INCRBY"
+	DECRBY_CMD CMD = "DECRBY"
+
+	SCAN_CMD CMD = "SCAN"
+
+	RPUSH_CMD     CMD = "RPUSH"
+	RLEN_CMD      CMD = "RLEN"
+	RRANGE_CMD    CMD = "RRANGE"
+	LRANGE_CMD    CMD = "LRANGE"
+	RPOP_CMD      CMD = "RPOP"
+	LPOP_CMD      CMD = "LPOP"
+	LPUSH_CMD     CMD = "LPUSH"
+	LPUSHX_CMD    CMD = "LPUSHX"
+	RPUSHX_CMD    CMD = "RPUSHX"
+	LINDEX_CMD    CMD = "LINDEX"
+	LSET_CMD      CMD = "LSET"
+	LINSERT_CMD   CMD = "LINSERT"
+	LREM_CMD      CMD = "LREM"
+	LTRIM_CMD     CMD = "LTRIM"
+	BLPOP_CMD     CMD = "BLPOP"
+	BRPOP_CMD     CMD = "BRPOP"
+	LMOVE_CMD     CMD = "LMOVE"
+	RPOPLPUSH_CMD CMD = "RPOPLPUSH"
+	BLMOVE_CMD    CMD = "BLMOVE"
+
+	HSET_CMD    CMD = "HSET"
+	HSETNX_CMD  CMD = "HSETNX"
+	HGET_CMD    CMD = "HGET"
+	HDEL_CMD    CMD = "HDEL"
+	HGETALL_CMD CMD = "HGETALL"
+	HMGET_CMD   CMD = "HMGET"
+	HEXISTS_CMD CMD = "HEXISTS"
+	HLEN_CMD    CMD = "HLEN"
+	HSTRLEN_CMD CMD = "HSTRLEN"
+	HKEYS_CMD   CMD = "HKEYS"
+	HVALS_CMD   CMD = "HVALS"
+
+	HEXPIRE_CMD      CMD = "HEXPIRE"
+	HPEXPIRE_CMD     CMD = "HPEXPIRE"
+	HEXPIREAT_CMD    CMD = "HEXPIREAT"
+	HPEXPIREAT_CMD   CMD = "HPEXPIREAT"
+	HTTL_CMD         CMD = "HTTL"
+	HPTTL_CMD        CMD = "HPTTL"
+	HEXPIRETIME_CMD  CMD = "HEXPIRETIME"
+	HPEXPIRETIME_CMD CMD = "HPEXPIRETIME"
+	HPERSIST_CMD     CMD = "HPERSIST"
 
 	MULTI_CMD   CMD = "MULTI_CMD"
 	EXEC_CMD    CMD = "EXEC_CMD"
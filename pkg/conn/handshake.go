@@ -0,0 +1,94 @@
+package conn
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+// handshakeCmds builds the commands that restore session state on a fresh
+// connection. HELLO 3 carries AUTH and SETNAME itself; otherwise they are
+// sent as separate commands.
+func (o Options) handshakeCmds() [][]string {
+	var cmds [][]string
+	if o.Protocol == resp.RESP3 {
+		hello := []string{"HELLO", "3"}
+		if o.Password != "" {
+			user := o.Username
+			if user == "" {
+				user = "default"
+			}
+			hello = append(hello, "AUTH", user, o.Password)
+		}
+		if o.ClientName != "" {
+			hello = append(hello, "SETNAME", o.ClientName)
+		}
+		cmds = append(cmds, hello)
+	} else {
+		if o.Password != "" {
+			auth := []string{"AUTH", o.Password}
+			if o.Username != "" {
+				auth = []string{"AUTH", o.Username, o.Password}
+			}
+			cmds = append(cmds, auth)
+		}
+		if o.ClientName != "" {
+			cmds = append(cmds, []string{"CLIENT", "SETNAME", o.ClientName})
+		}
+	}
+	if o.DB != 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.Itoa(o.DB)})
+	}
+	return cmds
+}
+
+// handshake pipelines the built-in session commands on c, then runs
+// OnConnect. It is bounded by ctx and by DialTimeout.
+func (p *Pool) handshake(ctx context.Context, c net.Conn) error {
+	cmds := p.opts.handshakeCmds()
+	if len(cmds) == 0 && p.opts.OnConnect == nil {
+		return nil
+	}
+
+	deadline := time.Now().Add(p.opts.DialTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.SetDeadline(deadline); err != nil {
+		return err
+	}
+	defer c.SetDeadline(time.Time{})
+
+	if len(cmds) > 0 {
+		var buf []byte
+		for _, cmd := range cmds {
+			data, err := resp.Marshal(cmd)
+			if err != nil {
+				return err
+			}
+			buf = append(buf, data...)
+		}
+		if _, err := c.Write(buf); err != nil {
+			return err
+		}
+		r := resp.NewReader(c)
+		for _, cmd := range cmds {
+			v, err := r.ReadValue()
+			if err != nil {
+				return err
+			}
+			if err := v.Err(); err != nil {
+				return fmt.Errorf("conn: %s: %w", cmd[0], err)
+			}
+		}
+	}
+
+	if p.opts.OnConnect != nil {
+		return p.opts.OnConnect(ctx, c)
+	}
+	return nil
+}
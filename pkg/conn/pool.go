@@ -1,124 +1,512 @@
 package conn
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"net"
+	"slices"
 	"sync"
-	"sync/atomic"
 	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
+)
+
+var (
+	// ErrPoolClosed is returned by Get once Close has been called.
+	ErrPoolClosed = errors.New("conn: pool is closed")
+	// ErrPoolTimeout is returned by Get when no connection became free in time.
+	ErrPoolTimeout = errors.New("conn: timed out waiting for a free connection")
+	// ErrCircuitOpen matches a *CircuitError with errors.Is.
+	ErrCircuitOpen = errors.New("conn: circuit open")
 )
 
+// CircuitError is returned when the server could not be reached after
+// DialAttempts tries. Until Until passes the pool does not dial again and
+// fails fast with the same error.
+type CircuitError struct {
+	Addr     string
+	Attempts int
+	Until    time.Time
+	Err      error // the last dial error
+}
+
+func (e *CircuitError) Error() string {
+	return fmt.Sprintf("conn: circuit open for %s after %d failed dials: %v", e.Addr, e.Attempts, e.Err)
+}
+
+func (e *CircuitError) Is(target error) bool { return target == ErrCircuitOpen }
+
+func (e *CircuitError) Unwrap() error { return e.Err }
+
+// Options tunes a Pool; zero values pick the defaults.
+type Options struct {
+	// MaxActive caps the connections open at once, idle or checked out.
+	// Get waits when all of them are checked out. Defaults to 4.
+	MaxActive int
+	// MinIdle is how many idle connections are dialed up front and kept
+	// around by the health checker. Capped at MaxActive.
+	MinIdle int
+	// MaxIdleTime closes connections that sat idle for longer, down to
+	// MinIdle. Zero keeps them until they fail a health check.
+	MaxIdleTime time.Duration
+	// WaitTimeout bounds how long Get waits for a free connection on top of
+	// the caller's context. Zero waits for as long as the context allows.
+	WaitTimeout time.Duration
+	// DialTimeout bounds a single dial attempt. Defaults to 3s.
+	DialTimeout time.Duration
+	// Network is passed to the dialer, e.g. "tcp" (the default) or "unix".
+	Network string
+	// Dialer replaces the built-in net.Dialer, e.g. to go through a proxy.
+	// It still runs under the DialTimeout bounded context.
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig, when set, wraps every connection in TLS. An empty
+	// ServerName is filled from the host part of the address.
+	TLSConfig *tls.Config
+	// DialAttempts is how many times a dial is tried before the circuit
+	// opens. Defaults to 3.
+	DialAttempts int
+	// MinBackoff and MaxBackoff bound the jittered exponential delay between
+	// dial attempts. Default to 8ms and 512ms.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// CircuitCooldown is how long the circuit stays open. Defaults to 5s.
+	CircuitCooldown time.Duration
+	// PingTimeout bounds the PING round trip of a health check. Defaults to 1s.
+	PingTimeout time.Duration
+	// Username and Password authenticate every new connection.
+	Username string
+	Password string
+	// DB is SELECTed on every new connection when not 0.
+	DB int
+	// ClientName is set with CLIENT SETNAME on every new connection.
+	ClientName string
+	// Protocol 3 negotiates RESP3 with HELLO 3 on every new connection.
+	Protocol int
+	// OnConnect runs on every new connection after the built-in handshake,
+	// so reconnects can restore any further session state. An error closes
+	// the connection and is returned from Get.
+	OnConnect func(ctx context.Context, c net.Conn) error
+
+	// IdleCheckAfter makes Get PING a connection before handing it out when
+	// it has sat idle for at least this long. Defaults to 30s.
+	IdleCheckAfter time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxActive < 1 {
+		o.MaxActive = 4
+	}
+	o.MinIdle = min(max(o.MinIdle, 0), o.MaxActive)
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = 3 * time.Second
+	}
+	if o.Network == "" {
+		o.Network = "tcp"
+	}
+	if o.DialAttempts < 1 {
+		o.DialAttempts = 3
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = 8 * time.Millisecond
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = max(512*time.Millisecond, o.MinBackoff)
+	}
+	if o.CircuitCooldown <= 0 {
+		o.CircuitCooldown = 5 * time.Second
+	}
+	if o.PingTimeout <= 0 {
+		o.PingTimeout = time.Second
+	}
+	if o.IdleCheckAfter <= 0 {
+		o.IdleCheckAfter = 30 * time.Second
+	}
+	return o
+}
+
+// Pool hands out exclusive connections to a single server. Every connection
+// obtained with Get must be given back with Put, or with Discard when it is
+// broken; while all of them are checked out, Get waits.
 type Pool struct {
 	addr  string
-	size  int
-	next  atomic.Uint32
-	conns []net.Conn
+	opts  Options
+	conns []net.Conn // every open connection, idle or checked out
+	idle  []idleConn
+	slots chan struct{} // one token per checked out connection, or one being health checked or dialed for MinIdle
+	done  chan struct{}
+	open  *CircuitError // set while the circuit is open
 	mu    sync.Mutex
 }
 
+// idleConn is a connection waiting in the pool and when it was put there.
+type idleConn struct {
+	c     net.Conn
+	since time.Time
+}
+
+// NewConnPool keeps size connections open, dialing them all up front.
 func NewConnPool(addr string, size int) *Pool {
-	if size < 1 {
-		size = 4
+	return NewConnPoolWithOptions(addr, Options{MaxActive: size, MinIdle: size})
+}
+
+func NewConnPoolWithOptions(addr string, opts Options) *Pool {
+	opts = opts.withDefaults()
+	p := &Pool{
+		addr:  addr,
+		opts:  opts,
+		slots: make(chan struct{}, opts.MaxActive),
+		done:  make(chan struct{}),
 	}
-	p := &Pool{addr: addr, size: size, conns: make([]net.Conn, size)}
-	for i := 0; i < size; i++ {
-		p.conns[i] = p.dial()
+	for i := 0; i < opts.MinIdle; i++ {
+		if c, err := p.connect(context.Background()); err == nil {
+			p.conns = append(p.conns, c)
+			p.idle = append(p.idle, idleConn{c, time.Now()})
+		}
 	}
 	go p.healthChecker()
 	return p
 }
 
-func (p *Pool) dial() net.Conn {
-	conn, err := net.DialTimeout("tcp", p.addr, 3*time.Second)
+func (p *Pool) dial(ctx context.Context) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.DialTimeout)
+	defer cancel()
+
+	dial := p.opts.Dialer
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	conn, err := dial(ctx, p.opts.Network, p.addr)
 	if err != nil {
-		return nil
+		return nil, fmt.Errorf("conn: dial %s: %w", p.addr, err)
 	}
-	return conn
+	if p.opts.TLSConfig != nil {
+		tc := tls.Client(conn, p.tlsConfig())
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("conn: tls handshake with %s: %w", p.addr, err)
+		}
+		conn = tc
+	}
+	return conn, nil
 }
 
-func (p *Pool) Get() net.Conn {
-	idx := p.next.Add(1) % uint32(p.size)
-	conn := p.conns[idx]
+func (p *Pool) tlsConfig() *tls.Config {
+	cfg := p.opts.TLSConfig
+	if cfg.ServerName != "" || cfg.InsecureSkipVerify {
+		return cfg
+	}
+	cfg = cfg.Clone()
+	if host, _, err := net.SplitHostPort(p.addr); err == nil {
+		cfg.ServerName = host
+	} else {
+		cfg.ServerName = p.addr
+	}
+	return cfg
+}
 
-	if conn != nil && p.isAlive(conn) {
-		return conn
+// Dial opens a connection with the pool's dialer, handshake, backoff and
+// circuit, but outside its limits and bookkeeping. It suits connections
+// that are held for a long time, such as Pub/Sub subscriptions; the
+// caller closes it.
+func (p *Pool) Dial(ctx context.Context) (net.Conn, error) {
+	if p.isClosed() {
+		return nil, ErrPoolClosed
 	}
+	return p.connect(ctx)
+}
 
+// connect dials with jittered exponential backoff. Once DialAttempts tries
+// have failed it opens the circuit, and until CircuitCooldown has passed
+// every call fails fast with the same *CircuitError.
+func (p *Pool) connect(ctx context.Context) (net.Conn, error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-	if old := p.conns[idx]; old != nil {
-		old.Close()
+	open := p.open
+	p.mu.Unlock()
+	if open != nil && time.Now().Before(open.Until) {
+		return nil, open
+	}
+
+	var err error
+	for attempt := 0; attempt < p.opts.DialAttempts; attempt++ {
+		if attempt > 0 {
+			t := time.NewTimer(p.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, ctx.Err()
+			case <-t.C:
+			}
+		}
+		var c net.Conn
+		if c, err = p.dial(ctx); err == nil {
+			p.mu.Lock()
+			p.open = nil
+			p.mu.Unlock()
+			// A rejected handshake is not a reachability problem, so it is
+			// neither retried nor counted towards the circuit.
+			if err := p.handshake(ctx, c); err != nil {
+				c.Close()
+				return nil, err
+			}
+			return c, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	open = &CircuitError{
+		Addr:     p.addr,
+		Attempts: p.opts.DialAttempts,
+		Until:    time.Now().Add(p.opts.CircuitCooldown),
+		Err:      err,
+	}
+	p.mu.Lock()
+	p.open = open
+	p.mu.Unlock()
+	return nil, open
+}
+
+// backoff returns the delay before the given retry: MinBackoff doubled per
+// attempt, capped at MaxBackoff, with the upper half randomised so that
+// clients reconnecting together spread out.
+func (p *Pool) backoff(attempt int) time.Duration {
+	d := p.opts.MinBackoff
+	for i := 1; i < attempt && d < p.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.opts.MaxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+// Get checks out a connection, waiting while all of them are in use until
+// ctx is done or WaitTimeout passes. Connections idle for longer than
+// IdleCheckAfter are PINGed first, and dead ones are replaced by a fresh
+// dial, which also honours ctx.
+func (p *Pool) Get(ctx context.Context) (net.Conn, error) {
+	if p.opts.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.opts.WaitTimeout)
+		defer cancel()
+	}
+
+	if p.isClosed() {
+		return nil, ErrPoolClosed
+	}
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrPoolTimeout, ctx.Err())
+	}
+
+	for {
+		p.mu.Lock()
+		if len(p.idle) == 0 {
+			p.mu.Unlock()
+			break
+		}
+		ic := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.mu.Unlock()
+
+		if time.Since(ic.since) < p.opts.IdleCheckAfter || p.isAlive(ic.c) {
+			return ic.c, nil
+		}
+		p.forget(ic.c)
+	}
+
+	c, err := p.connect(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	p.mu.Lock()
+	p.conns = append(p.conns, c)
+	p.mu.Unlock()
+	return c, nil
+}
+
+// Put returns a healthy connection obtained from Get.
+func (p *Pool) Put(c net.Conn) {
+	if c == nil {
+		return
+	}
+	p.mu.Lock()
+	if p.isClosed() {
+		p.mu.Unlock()
+		c.Close()
+	} else {
+		p.idle = append(p.idle, idleConn{c, time.Now()})
+		p.mu.Unlock()
+	}
+	<-p.slots
+}
+
+func (p *Pool) isClosed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Discard closes a connection obtained from Get instead of returning it,
+// for when it broke or was left in an unknown protocol state.
+func (p *Pool) Discard(c net.Conn) {
+	if c == nil {
+		return
 	}
-	p.conns[idx] = p.dial()
-	return p.conns[idx]
+	p.forget(c)
+	<-p.slots
 }
+
+// forget closes c and drops it from the pool's bookkeeping.
+func (p *Pool) forget(c net.Conn) {
+	c.Close()
+	p.mu.Lock()
+	if i := slices.Index(p.conns, c); i >= 0 {
+		p.conns = slices.Delete(p.conns, i, i+1)
+	}
+	p.mu.Unlock()
+}
+
+var pingCmd = []byte("*1\r\n$4\r\nPING\r\n")
+
+// isAlive does a PING round trip within PingTimeout. A bare write is not
+// enough: it succeeds on half-closed connections and on servers that have
+// stopped answering.
 func (p *Pool) isAlive(c net.Conn) bool {
 	if c == nil {
 		return false
 	}
 
-	if err := c.SetWriteDeadline(time.Now().Add(3 * time.Second)); err != nil {
+	if err := c.SetDeadline(time.Now().Add(p.opts.PingTimeout)); err != nil {
+		return false
+	}
+	defer c.SetDeadline(time.Time{})
+	if _, err := c.Write(pingCmd); err != nil {
+		return false
+	}
+	v, err := resp.NewReader(c).ReadValue()
+	if err != nil {
 		return false
 	}
-	_, err := c.Write(nil)
-	c.SetWriteDeadline(time.Time{})
-	return err == nil
+	return v.Str == "PONG" || v.Bulk == "PONG"
 }
 
 func (p *Pool) healthChecker() {
-	ticker := time.NewTicker(10 * time.Second)
+	interval := 10 * time.Second
+	if p.opts.MaxIdleTime > 0 {
+		interval = min(interval, p.opts.MaxIdleTime)
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		p.mu.Lock()
-		alive := make([]net.Conn, 0, len(p.conns))
-
-		for _, c := range p.conns {
-
-			if c != nil && p.isAlive(c) {
-				alive = append(alive, c)
-			} else {
-				if c != nil {
-					c.Close()
-				}
-			}
-		}
-
-		for len(alive) < p.size {
-			alive = append(alive, p.dial())
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.HealthCheckerOnce()
 		}
-
-		p.conns = alive
-		p.mu.Unlock()
 	}
 }
+
+// HealthCheckerOnce closes dead idle connections and those idle for longer
+// than MaxIdleTime beyond MinIdle, then dials until MinIdle connections are
+// idle again. Checked out connections are left alone.
+//
+// Each connection it takes out of idle, and each one it dials, holds a
+// slot the way a checked out one does. Otherwise Get would find the idle
+// list empty while the pool is already full, and dial past MaxActive.
 func (p *Pool) HealthCheckerOnce() {
 	p.mu.Lock()
-	alive := make([]net.Conn, 0, len(p.conns))
+	n := 0
+	for n < len(p.idle) && p.trySlot() {
+		n++
+	}
+	idle := slices.Clone(p.idle[:n])
+	p.idle = p.idle[n:]
+	p.mu.Unlock()
 
-	for _, c := range p.conns {
-		if c != nil && p.isAlive(c) {
-			alive = append(alive, c)
+	alive := make([]idleConn, 0, len(idle))
+	for _, ic := range idle {
+		if p.isAlive(ic.c) {
+			alive = append(alive, ic)
 		} else {
-			if c != nil {
-				c.Close()
-			}
+			p.forget(ic.c)
 		}
 	}
-
-	for len(alive) < p.size {
-		alive = append(alive, p.dial())
+	// idle is kept oldest first, so the stalest connections go first.
+	if p.opts.MaxIdleTime > 0 {
+		for len(alive) > p.opts.MinIdle && time.Since(alive[0].since) > p.opts.MaxIdleTime {
+			p.forget(alive[0].c)
+			alive = alive[1:]
+		}
 	}
 
-	p.conns = alive
+	p.mu.Lock()
+	p.idle = append(alive, p.idle...)
+	missing := min(p.opts.MinIdle-len(p.idle), p.opts.MaxActive-len(p.conns))
 	p.mu.Unlock()
+	for range n {
+		<-p.slots
+	}
+
+	for i := 0; i < missing && p.trySlot(); i++ {
+		c, err := p.connect(context.Background())
+		if err != nil {
+			<-p.slots
+			return
+		}
+		p.mu.Lock()
+		if len(p.conns) >= p.opts.MaxActive || p.isClosed() {
+			p.mu.Unlock()
+			<-p.slots
+			c.Close()
+			return
+		}
+		p.conns = append(p.conns, c)
+		p.idle = append(p.idle, idleConn{c, time.Now()})
+		p.mu.Unlock()
+		<-p.slots
+	}
+}
+
+// trySlot takes a slot if one is free, without waiting.
+func (p *Pool) trySlot() bool {
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stats reports how many connections are open and how many of them are idle.
+func (p *Pool) Stats() (open, idle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns), len(p.idle)
 }
 
 func (p *Pool) Close() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	if p.isClosed() {
+		return
+	}
+	close(p.done)
 	for _, c := range p.conns {
 		if c != nil {
 			c.Close()
 		}
 	}
+	p.idle = nil
 }
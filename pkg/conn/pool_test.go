@@ -1,24 +1,51 @@
 package conn
 
 import (
+	"context"
+	"errors"
 	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/jafari-mohammad-reza/redis-clone/pkg/resp"
 )
 
-func TestCreatePool(t *testing.T) {
-	go func() {
-		ln, err := net.Listen("tcp", ":3080")
+// servePong accepts connections on addr and answers every command with PONG.
+func servePong(addr string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		panic("failed to listen to " + addr)
+	}
+	pong(ln)
+}
+
+func pong(ln net.Listener) {
+	for {
+		c, err := ln.Accept()
 		if err != nil {
-			panic("failed to listen to 3080")
+			return
 		}
-		for {
-			_, err := ln.Accept()
-			if err != nil {
-				panic("failed to accept conn")
+		go func() {
+			defer c.Close()
+			r := resp.NewReader(c)
+			for {
+				if _, err := r.ReadCommand(nil); err != nil {
+					return
+				}
+				if _, err := c.Write([]byte("+PONG\r\n")); err != nil {
+					return
+				}
 			}
-		}
-	}()
+		}()
+	}
+}
+
+func TestCreatePool(t *testing.T) {
+	go servePong(":3080")
 	time.Sleep(time.Second)
 	pool := NewConnPool(":3080", 6)
 	if pool == nil {
@@ -35,18 +62,7 @@ func TestCreatePool(t *testing.T) {
 }
 
 func Test_isAlive(t *testing.T) {
-	go func() {
-		ln, err := net.Listen("tcp", ":3081")
-		if err != nil {
-			panic("failed to listen to 3081")
-		}
-		for {
-			_, err := ln.Accept()
-			if err != nil {
-				panic("failed to accept conn")
-			}
-		}
-	}()
+	go servePong(":3081")
 	time.Sleep(time.Second)
 	pool := NewConnPool(":3081", 6)
 
@@ -70,4 +86,380 @@ func Test_isAlive(t *testing.T) {
 			t.Fatal("closed conn reported alive")
 		}
 	})
+
+	t.Run("silent peer returns false", func(t *testing.T) {
+		c, peer := net.Pipe()
+		defer c.Close()
+		go func() {
+			buf := make([]byte, 64)
+			for {
+				if _, err := peer.Read(buf); err != nil {
+					return
+				}
+			}
+		}()
+		defer peer.Close()
+		pool.opts.PingTimeout = 50 * time.Millisecond
+		if pool.isAlive(c) {
+			t.Fatal("conn that never answers PING reported alive")
+		}
+	})
+}
+
+func TestGetWaitsForFreeConn(t *testing.T) {
+	go servePong(":3082")
+	time.Sleep(time.Second)
+	pool := NewConnPoolWithOptions(":3082", Options{MaxActive: 2, WaitTimeout: 100 * time.Millisecond})
+	defer pool.Close()
+
+	ctx := context.Background()
+	a, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b {
+		t.Fatal("two checkouts returned the same conn")
+	}
+
+	t.Run("times out when exhausted", func(t *testing.T) {
+		start := time.Now()
+		if _, err := pool.Get(ctx); !errors.Is(err, ErrPoolTimeout) {
+			t.Fatalf("expected ErrPoolTimeout, got %v", err)
+		}
+		if time.Since(start) < 100*time.Millisecond {
+			t.Fatal("Get returned before the wait timeout")
+		}
+	})
+
+	t.Run("honours ctx", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		if _, err := pool.Get(cctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Put wakes a waiter", func(t *testing.T) {
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			pool.Put(a)
+		}()
+		c, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != a {
+			t.Fatal("expected the returned conn to be reused")
+		}
+		pool.Put(c)
+	})
+
+	t.Run("Discard redials", func(t *testing.T) {
+		pool.Discard(b)
+		c, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c == b {
+			t.Fatal("discarded conn handed out again")
+		}
+		pool.Put(c)
+	})
+
+	pool.Close()
+	if _, err := pool.Get(ctx); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestCircuitOpens(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	pool := NewConnPoolWithOptions(addr, Options{
+		MaxActive:       2,
+		DialAttempts:    3,
+		MinBackoff:      time.Millisecond,
+		MaxBackoff:      4 * time.Millisecond,
+		CircuitCooldown: 200 * time.Millisecond,
+	})
+	defer pool.Close()
+	if len(pool.conns) != 0 {
+		t.Fatalf("expected no open conns, got %d", len(pool.conns))
+	}
+
+	_, err = pool.Get(context.Background())
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	var ce *CircuitError
+	if !errors.As(err, &ce) || ce.Attempts != 3 || ce.Addr != addr {
+		t.Fatalf("unexpected circuit error %#v", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("circuit error should wrap the dial error, got %v", err)
+	}
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to stay open during cooldown, got %v", err)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	c, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("expected a dial after cooldown, got %v", err)
+	}
+	pool.Put(c)
+}
+
+func TestBackoff(t *testing.T) {
+	pool := &Pool{opts: Options{MinBackoff: 10 * time.Millisecond, MaxBackoff: 80 * time.Millisecond}}
+	for attempt, want := range []time.Duration{1: 10, 2: 20, 3: 40, 4: 80, 5: 80, 40: 80} {
+		if want == 0 {
+			continue
+		}
+		want *= time.Millisecond
+		for range 20 {
+			if d := pool.backoff(attempt); d < want/2 || d > want {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestDynamicSizing(t *testing.T) {
+	go servePong(":3083")
+	time.Sleep(time.Second)
+	pool := NewConnPoolWithOptions(":3083", Options{MaxActive: 4, MinIdle: 1, MaxIdleTime: 50 * time.Millisecond})
+	defer pool.Close()
+
+	if open, idle := pool.Stats(); open != 1 || idle != 1 {
+		t.Fatalf("expected 1 open idle conn up front, got open=%d idle=%d", open, idle)
+	}
+
+	ctx := context.Background()
+	conns := make([]net.Conn, 4)
+	for i := range conns {
+		c, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = c
+	}
+	if open, _ := pool.Stats(); open != 4 {
+		t.Fatalf("expected the pool to grow to 4, got %d", open)
+	}
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(short); !errors.Is(err, ErrPoolTimeout) {
+		t.Fatalf("expected Get past MaxActive to wait, got %v", err)
+	}
+	for _, c := range conns {
+		pool.Put(c)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	pool.HealthCheckerOnce()
+	if open, idle := pool.Stats(); open != 1 || idle != 1 {
+		t.Fatalf("expected idle conns reaped down to MinIdle, got open=%d idle=%d", open, idle)
+	}
+}
+
+// A health check in progress must not let Get dial past MaxActive.
+func TestHealthCheckKeepsMaxActive(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				for {
+					if _, err := r.ReadCommand(nil); err != nil {
+						return
+					}
+					time.Sleep(100 * time.Millisecond)
+					if _, err := c.Write([]byte("+PONG\r\n")); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	pool := NewConnPoolWithOptions(ln.Addr().String(), Options{MaxActive: 2, MinIdle: 2})
+	defer pool.Close()
+	checked := make(chan struct{})
+	go func() {
+		pool.HealthCheckerOnce()
+		close(checked)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	c, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open, _ := pool.Stats(); open > 2 {
+		t.Fatalf("Get dialed during a health check: %d open, MaxActive 2", open)
+	}
+	pool.Put(c)
+	<-checked
+	if open, idle := pool.Stats(); open != 2 || idle != 2 {
+		t.Fatalf("expected 2 open idle conns after the check, got open=%d idle=%d", open, idle)
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var (
+		mu   sync.Mutex
+		seen []string
+	)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				r := resp.NewReader(c)
+				for {
+					args, err := r.ReadCommand(nil)
+					if err != nil {
+						return
+					}
+					parts := make([]string, len(args))
+					for i, a := range args {
+						parts[i] = string(a)
+					}
+					mu.Lock()
+					seen = append(seen, strings.Join(parts, " "))
+					mu.Unlock()
+					reply := "+OK\r\n"
+					if parts[0] == "AUTH" && parts[len(parts)-1] != "secret" {
+						reply = "-WRONGPASS invalid username-password pair\r\n"
+					}
+					c.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+
+	var hooked int
+	pool := NewConnPoolWithOptions(ln.Addr().String(), Options{
+		MaxActive:  1,
+		Password:   "secret",
+		DB:         2,
+		ClientName: "cli",
+		OnConnect: func(ctx context.Context, c net.Conn) error {
+			hooked++
+			return nil
+		},
+	})
+	defer pool.Close()
+	c, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Put(c)
+	mu.Lock()
+	got := seen
+	mu.Unlock()
+	want := []string{"AUTH secret", "CLIENT SETNAME cli", "SELECT 2"}
+	if !reflect.DeepEqual(got, want) || hooked != 1 {
+		t.Fatalf("handshake sent %q (hook ran %d times), want %q once", got, hooked, want)
+	}
+
+	hello := Options{Protocol: resp.RESP3, Username: "bob", Password: "pw", ClientName: "cli"}.handshakeCmds()
+	if want := [][]string{{"HELLO", "3", "AUTH", "bob", "pw", "SETNAME", "cli"}}; !reflect.DeepEqual(hello, want) {
+		t.Fatalf("HELLO handshake = %q, want %q", hello, want)
+	}
+
+	bad := NewConnPoolWithOptions(ln.Addr().String(), Options{MaxActive: 1, Password: "nope"})
+	defer bad.Close()
+	if _, err := bad.Get(context.Background()); !resp.HasCode(err, "WRONGPASS") {
+		t.Fatalf("expected WRONGPASS, got %v", err)
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("a rejected handshake must not open the circuit")
+	}
+}
+
+func TestCustomDialer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redis.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+	go pong(ln)
+
+	t.Run("unix socket", func(t *testing.T) {
+		pool := NewConnPoolWithOptions(path, Options{MaxActive: 1, Network: "unix"})
+		defer pool.Close()
+		c, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.RemoteAddr().Network() != "unix" {
+			t.Fatalf("expected a unix conn, got %s", c.RemoteAddr().Network())
+		}
+		if !pool.isAlive(c) {
+			t.Fatal("unix conn did not answer PING")
+		}
+		pool.Put(c)
+	})
+
+	t.Run("dial func", func(t *testing.T) {
+		var calls int
+		pool := NewConnPoolWithOptions("ignored:6379", Options{
+			MaxActive: 1,
+			Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				calls++
+				if _, ok := ctx.Deadline(); !ok {
+					t.Error("dialer context has no deadline")
+				}
+				if network != "tcp" || addr != "ignored:6379" {
+					t.Errorf("dialer got %s %s", network, addr)
+				}
+				return net.Dial("unix", path)
+			},
+		})
+		defer pool.Close()
+		c, err := pool.Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.Put(c)
+		if calls != 1 {
+			t.Fatalf("expected one dial, got %d", calls)
+		}
+	})
 }
@@ -0,0 +1,166 @@
+package resp
+
+import (
+	"bufio"
+	"io"
+)
+
+// ReadCommand reads one request, an array of bulk strings, appending its
+// arguments to args[:0] and returning the result. Payloads are stored in a
+// buffer owned by the Reader and the returned slices alias it: they are only
+// valid until the next call on r. Use CopyArgs (or string conversion) to keep
+// anything longer. Simple strings and integers are accepted as elements too,
+// for peers that do not encode everything as bulk strings.
+func (r *Reader) ReadCommand(args [][]byte) ([][]byte, error) {
+	line, err := r.readLineBytes()
+	if err != nil {
+		return args[:0], err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return args[:0], protocolErrorf("expected '*', got %q", line)
+	}
+	count, err := parseLengthBytes(line, r.opts.MaxArrayLen, "multibulk")
+	if err != nil {
+		return args[:0], err
+	}
+
+	// The previous command's arguments are dead now, so a buffer that one
+	// huge argument grew is dropped rather than kept for the connection's
+	// lifetime.
+	if cap(r.buf) > maxKeptBuffer {
+		r.buf = nil
+	}
+	r.buf = r.buf[:0]
+	r.offs = r.offs[:0]
+	for i := 0; i < count; i++ {
+		line, err := r.readLineBytes()
+		if err != nil {
+			return args[:0], err
+		}
+		if len(line) == 0 {
+			return args[:0], protocolErrorf("empty line")
+		}
+		start := len(r.buf)
+		switch line[0] {
+		case '$':
+			length, err := parseLengthBytes(line, r.opts.MaxBulkLen, "bulk")
+			if err != nil {
+				return args[:0], err
+			}
+			if r.buf, err = r.readFull(r.buf, length+2); err != nil {
+				return args[:0], err
+			}
+			if r.buf[start+length] != '\r' || r.buf[start+length+1] != '\n' {
+				return args[:0], protocolErrorf("bulk payload not terminated by CRLF")
+			}
+			r.buf = r.buf[:start+length]
+		case '+', ':':
+			r.buf = append(r.buf, line[1:]...)
+		default:
+			return args[:0], protocolErrorf("expected '$', got %q", line[0])
+		}
+		r.offs = append(r.offs, start, len(r.buf))
+	}
+
+	args = args[:0]
+	for i := 0; i < len(r.offs); i += 2 {
+		args = append(args, r.buf[r.offs[i]:r.offs[i+1]:r.offs[i+1]])
+	}
+	return args, nil
+}
+
+// CopyArgs returns string copies of args that stay valid after the next read.
+func CopyArgs(args [][]byte) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = string(arg)
+	}
+	return out
+}
+
+// bulkChunk is how much of a bulk payload ReadCommand makes room for at a
+// time, and maxKeptBuffer the most argument space it keeps between
+// commands. Growing as the bytes arrive means a client cannot make the
+// server allocate MaxBulkLen just by announcing it.
+const (
+	bulkChunk     = 64 * 1024
+	maxKeptBuffer = 64 * 1024
+)
+
+// readFull appends the next n bytes of input to b, growing it one
+// bulkChunk at a time as data is actually read.
+func (r *Reader) readFull(b []byte, n int) ([]byte, error) {
+	for n > 0 {
+		step := min(n, bulkChunk)
+		start := len(b)
+		b = grow(b, step)
+		if _, err := io.ReadFull(r.br, b[start:]); err != nil {
+			return b, err
+		}
+		n -= step
+	}
+	return b, nil
+}
+
+// grow extends b by n bytes, reallocating only when capacity runs out.
+func grow(b []byte, n int) []byte {
+	if cap(b)-len(b) < n {
+		nb := make([]byte, len(b), 2*cap(b)+n)
+		copy(nb, b)
+		b = nb
+	}
+	return b[:len(b)+n]
+}
+
+// readLineBytes is ReadLine without the string copy. The result aliases the
+// bufio buffer (or r.line for very long lines) until the next read.
+func (r *Reader) readLineBytes() ([]byte, error) {
+	line, err := r.br.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		r.line = append(r.line[:0], line...)
+		for err == bufio.ErrBufferFull {
+			if len(r.line) > r.opts.MaxLineLen+2 {
+				return nil, protocolErrorf("line longer than %d bytes", r.opts.MaxLineLen)
+			}
+			line, err = r.br.ReadSlice('\n')
+			r.line = append(r.line, line...)
+		}
+		line = r.line
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(line) > r.opts.MaxLineLen+2 {
+		return nil, protocolErrorf("line longer than %d bytes", r.opts.MaxLineLen)
+	}
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+	return line, nil
+}
+
+// parseLengthBytes is parseLength without converting the header to a string.
+func parseLengthBytes(header []byte, max int, what string) (int, error) {
+	digits := header[1:]
+	if len(digits) == 0 || len(digits) > 19 {
+		return 0, protocolErrorf("invalid %s length %q", what, digits)
+	}
+	if digits[0] == '-' {
+		return 0, protocolErrorf("negative %s length %s", what, digits[1:])
+	}
+	n := 0
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0, protocolErrorf("invalid %s length %q", what, digits)
+		}
+		// Checking the limit before each step also keeps n from
+		// overflowing into a negative length.
+		d := int(c - '0')
+		if n > (max-d)/10 {
+			return 0, protocolErrorf("%s length %s exceeds limit %d", what, digits, max)
+		}
+		n = n*10 + d
+	}
+	return n, nil
+}
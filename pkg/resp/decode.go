@@ -0,0 +1,384 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrNil is returned when a nil reply is decoded into a type that cannot
+// represent it. Pointers, slices, maps, *Value and *any accept nil silently.
+var ErrNil = errors.New("resp: nil reply")
+
+// Decoder reads RESP replies from a stream and converts them to Go values.
+type Decoder struct {
+	r *Reader
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: NewReader(r)}
+}
+
+// NewDecoderFromReader decodes from an existing Reader, sharing its buffer and limits.
+func NewDecoderFromReader(r *Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// SetPushHandler forwards out-of-band push frames to fn; see Reader.SetPushHandler.
+func (d *Decoder) SetPushHandler(fn func(Value)) {
+	d.r.SetPushHandler(fn)
+}
+
+// Decode reads one reply and stores it in dst, which must be a non-nil
+// pointer. An error reply is returned as a typed error (see ParseError)
+// and leaves dst untouched.
+func (d *Decoder) Decode(dst any) error {
+	v, err := d.r.ReadValue()
+	if err != nil {
+		return err
+	}
+	return Scan(v, dst)
+}
+
+// Scan converts v into dst, which must be a non-nil pointer to one of:
+// Value, any, string, []byte, an integer, float or bool kind, a slice, a map
+// with string keys or a struct. Maps and structs are filled from RESP3 maps
+// or from flat key/value arrays; struct fields are matched by their `resp`
+// tag, falling back to a case-insensitive match on the field name.
+func Scan(v Value, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("resp: Scan needs a non-nil pointer, got %T", dst)
+	}
+	if v.Typ == "error" {
+		return v.Err()
+	}
+	return scanInto(v, rv.Elem())
+}
+
+var valueType = reflect.TypeOf(Value{})
+
+func scanInto(v Value, rv reflect.Value) error {
+	if rv.Type() == valueType {
+		rv.Set(reflect.ValueOf(v))
+		return nil
+	}
+	if v.Typ == "error" {
+		return v.Err()
+	}
+
+	if v.IsNull() {
+		switch rv.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+			rv.Set(reflect.Zero(rv.Type()))
+			return nil
+		}
+		return ErrNil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return scanInto(v, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return fmt.Errorf("resp: cannot decode into %s", rv.Type())
+		}
+		rv.Set(reflect.ValueOf(natural(v)))
+		return nil
+	case reflect.String:
+		s, err := scalarString(v)
+		if err != nil {
+			return err
+		}
+		rv.SetString(s)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := scalarInt(v)
+		if err != nil {
+			return err
+		}
+		if rv.OverflowInt(n) {
+			return fmt.Errorf("resp: %d overflows %s", n, rv.Type())
+		}
+		rv.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := scalarInt(v)
+		if err != nil {
+			return err
+		}
+		if n < 0 || rv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("resp: %d overflows %s", n, rv.Type())
+		}
+		rv.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := scalarFloat(v)
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+		return nil
+	case reflect.Bool:
+		b, err := scalarBool(v)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+		return nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			s, err := scalarString(v)
+			if err != nil {
+				return err
+			}
+			rv.SetBytes([]byte(s))
+			return nil
+		}
+		items, err := elements(v)
+		if err != nil {
+			return err
+		}
+		out := reflect.MakeSlice(rv.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := scanInto(item, out.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(out)
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("resp: cannot decode into %s (map keys must be strings)", rv.Type())
+		}
+		pairs, err := pairsOf(v)
+		if err != nil {
+			return err
+		}
+		out := reflect.MakeMapWithSize(rv.Type(), len(pairs))
+		for _, p := range pairs {
+			key, err := scalarString(p.Key)
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := scanInto(p.Value, elem); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+		}
+		rv.Set(out)
+		return nil
+	case reflect.Struct:
+		pairs, err := pairsOf(v)
+		if err != nil {
+			return err
+		}
+		for _, p := range pairs {
+			key, err := scalarString(p.Key)
+			if err != nil {
+				return err
+			}
+			field, ok := fieldByKey(rv, key)
+			if !ok {
+				continue
+			}
+			if err := scanInto(p.Value, field); err != nil {
+				return fmt.Errorf("resp: field %s: %w", key, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("resp: cannot decode into %s", rv.Type())
+}
+
+// natural maps v to the closest plain Go value for decoding into any.
+func natural(v Value) any {
+	switch v.Typ {
+	case "string", "bignum":
+		return v.Str
+	case "bulk", "verbatim":
+		return v.Bulk
+	case "integer":
+		return v.Num
+	case "double":
+		return v.Double
+	case "boolean":
+		return v.Bool
+	case "null":
+		return nil
+	case "array", "set", "push":
+		if v.Array == nil {
+			return nil
+		}
+		out := make([]any, len(v.Array))
+		for i, item := range v.Array {
+			out[i] = natural(item)
+		}
+		return out
+	case "map":
+		out := make(map[string]any, len(v.Map))
+		for _, p := range v.Map {
+			key, _ := scalarString(p.Key)
+			out[key] = natural(p.Value)
+		}
+		return out
+	}
+	return nil
+}
+
+func scalarString(v Value) (string, error) {
+	switch v.Typ {
+	case "string", "bignum":
+		return v.Str, nil
+	case "bulk", "verbatim":
+		return v.Bulk, nil
+	case "integer":
+		return strconv.FormatInt(v.Num, 10), nil
+	case "double":
+		return formatDouble(v.Double), nil
+	case "boolean":
+		return strconv.FormatBool(v.Bool), nil
+	}
+	return "", fmt.Errorf("resp: cannot convert %s reply to string", v.Typ)
+}
+
+func scalarInt(v Value) (int64, error) {
+	switch v.Typ {
+	case "integer":
+		return v.Num, nil
+	case "boolean":
+		if v.Bool {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	s, err := scalarString(v)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("resp: cannot convert %q to integer", s)
+	}
+	return n, nil
+}
+
+func scalarFloat(v Value) (float64, error) {
+	switch v.Typ {
+	case "double":
+		return v.Double, nil
+	case "integer":
+		return float64(v.Num), nil
+	}
+	s, err := scalarString(v)
+	if err != nil {
+		return 0, err
+	}
+	f, err := parseDouble(s)
+	if err != nil {
+		return 0, fmt.Errorf("resp: cannot convert %q to float", s)
+	}
+	return f, nil
+}
+
+func scalarBool(v Value) (bool, error) {
+	switch v.Typ {
+	case "boolean":
+		return v.Bool, nil
+	case "integer":
+		return v.Num != 0, nil
+	case "string":
+		if v.Str == "OK" {
+			return true, nil
+		}
+	}
+	s, err := scalarString(v)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("resp: cannot convert %q to bool", s)
+	}
+	return b, nil
+}
+
+// elements returns the items of an aggregate; maps are flattened.
+func elements(v Value) ([]Value, error) {
+	switch v.Typ {
+	case "array", "set", "push":
+		return v.Array, nil
+	case "map":
+		out := make([]Value, 0, 2*len(v.Map))
+		for _, p := range v.Map {
+			out = append(out, p.Key, p.Value)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("resp: cannot convert %s reply to a slice", v.Typ)
+}
+
+// pairsOf returns the entries of a RESP3 map or a flat key/value array.
+func pairsOf(v Value) ([]Pair, error) {
+	switch v.Typ {
+	case "map":
+		return v.Map, nil
+	case "array", "set", "push":
+		if len(v.Array)%2 != 0 {
+			return nil, fmt.Errorf("resp: odd number of elements (%d) for key/value pairs", len(v.Array))
+		}
+		pairs := make([]Pair, len(v.Array)/2)
+		for i := range pairs {
+			pairs[i] = Pair{Key: v.Array[2*i], Value: v.Array[2*i+1]}
+		}
+		return pairs, nil
+	}
+	return nil, fmt.Errorf("resp: cannot convert %s reply to key/value pairs", v.Typ)
+}
+
+func fieldByKey(rv reflect.Value, key string) (reflect.Value, bool) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("resp"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == key || (name == "" && strings.EqualFold(sf.Name, key)) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// Encoder writes Go values as RESP; see Marshal for the supported types.
+type Encoder struct {
+	w *Writer
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: NewWriter(w)}
+}
+
+// SetProtocol selects RESP2 (the default) or RESP3 output.
+func (e *Encoder) SetProtocol(proto int) {
+	e.w.SetProtocol(proto)
+}
+
+// Encode writes v and flushes it to the underlying writer.
+func (e *Encoder) Encode(v any) error {
+	if err := e.w.encode(v); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
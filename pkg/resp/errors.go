@@ -0,0 +1,131 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Error is an error reply: an upper-case code such as ERR, WRONGTYPE or
+// NOAUTH followed by a human readable message.
+type Error struct {
+	Code string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Code == "":
+		return e.Msg
+	case e.Msg == "":
+		return e.Code
+	}
+	return e.Code + " " + e.Msg
+}
+
+// Is makes errors.Is match on the code, and on the message too when the
+// target carries one, so errors.Is(err, ErrWrongType) works for any reply.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return t.Code == e.Code && (t.Msg == "" || t.Msg == e.Msg)
+}
+
+func NewError(code, msg string) *Error {
+	return &Error{Code: code, Msg: msg}
+}
+
+func Errorf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Common error replies, worded exactly as Redis words them.
+var (
+	ErrWrongType     = NewError("WRONGTYPE", "Operation against a key holding the wrong kind of value")
+	ErrNoAuth        = NewError("NOAUTH", "Authentication required.")
+	ErrSyntax        = NewError("ERR", "syntax error")
+	ErrNotInteger    = NewError("ERR", "value is not an integer or out of range")
+	ErrNotFloat      = NewError("ERR", "value is not a valid float")
+	ErrNoSuchKey     = NewError("ERR", "no such key")
+	ErrOutOfRange    = NewError("ERR", "index out of range")
+	ErrInvalidDB     = NewError("ERR", "DB index is out of range")
+	ErrInvalidExpire = NewError("ERR", "invalid expire time")
+)
+
+// WrongArgs is the reply for a command called with the wrong arity.
+func WrongArgs(cmd string) *Error {
+	return Errorf("ERR", "wrong number of arguments for '%s' command", strings.ToLower(cmd))
+}
+
+// UnknownCommand is the reply for a command the server does not implement.
+func UnknownCommand(cmd string) *Error {
+	return Errorf("ERR", "unknown command '%s'", cmd)
+}
+
+// RedirectError is a cluster -MOVED or -ASK reply telling the client which
+// node serves a hash slot.
+type RedirectError struct {
+	Kind string // "MOVED" or "ASK"
+	Slot int
+	Addr string
+}
+
+func (e *RedirectError) Error() string {
+	return e.Kind + " " + strconv.Itoa(e.Slot) + " " + e.Addr
+}
+
+// ParseError turns the text of an error reply into a typed error: a
+// *RedirectError for well-formed MOVED/ASK replies and an *Error otherwise.
+func ParseError(s string) error {
+	code, msg, _ := strings.Cut(s, " ")
+	if code != strings.ToUpper(code) || strings.TrimLeft(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ_") != "" {
+		return &Error{Msg: s}
+	}
+	if code == "MOVED" || code == "ASK" {
+		slot, addr, ok := strings.Cut(msg, " ")
+		if n, err := strconv.Atoi(slot); ok && err == nil {
+			return &RedirectError{Kind: code, Slot: n, Addr: addr}
+		}
+	}
+	return &Error{Code: code, Msg: msg}
+}
+
+// HasCode reports whether err is an error reply with the given code.
+func HasCode(err error, code string) bool {
+	return ErrorCode(err) == code
+}
+
+// ErrorCode returns the code of an error reply anywhere in err's chain,
+// or "" for other errors.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var re *RedirectError
+	if errors.As(err, &re) {
+		return re.Kind
+	}
+	return ""
+}
+
+// ErrorValue wraps err as an error reply; errors that are not already
+// typed replies get the generic ERR code.
+func ErrorValue(err error) Value {
+	switch err.(type) {
+	case *Error, *RedirectError:
+		return Value{Typ: "error", Str: err.Error()}
+	}
+	return Value{Typ: "error", Str: "ERR " + err.Error()}
+}
+
+// Err returns the typed error carried by an error reply, or nil.
+func (v Value) Err() error {
+	if v.Typ != "error" {
+		return nil
+	}
+	return ParseError(v.Str)
+}
@@ -0,0 +1,122 @@
+package resp
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnbalancedQuotes is returned by SplitArgs for an unterminated quote or
+// a closing quote that is not followed by a space.
+var ErrUnbalancedQuotes = errors.New("unbalanced quotes")
+
+// SplitArgs splits an inline command line into arguments using the same
+// rules as redis-cli and the Redis inline protocol: arguments are separated
+// by whitespace; "double quoted" arguments understand \n, \r, \t, \b, \a,
+// \\, \" and \xHH escapes; 'single quoted' arguments only understand \'.
+// A trailing CRLF is ignored.
+func SplitArgs(line string) ([]string, error) {
+	line = strings.TrimRight(line, "\r\n")
+	args := make([]string, 0, 4)
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i >= len(line) {
+			return args, nil
+		}
+
+		var cur strings.Builder
+		inDouble, inSingle, done := false, false, false
+		for !done {
+			if i >= len(line) {
+				if inDouble || inSingle {
+					return nil, ErrUnbalancedQuotes
+				}
+				break
+			}
+			c := line[i]
+			switch {
+			case inDouble:
+				switch {
+				case c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+					cur.WriteByte(hexValue(line[i+2])<<4 | hexValue(line[i+3]))
+					i += 3
+				case c == '\\' && i+1 < len(line):
+					i++
+					cur.WriteByte(unescape(line[i]))
+				case c == '"':
+					// the closing quote must be followed by a space or nothing
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					cur.WriteByte(c)
+				}
+			case inSingle:
+				switch {
+				case c == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					cur.WriteByte('\'')
+				case c == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					cur.WriteByte(c)
+				}
+			default:
+				switch {
+				case isSpace(c):
+					done = true
+				case c == '"':
+					inDouble = true
+				case c == '\'':
+					inSingle = true
+				default:
+					cur.WriteByte(c)
+				}
+			}
+			i++
+		}
+		args = append(args, cur.String())
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	default:
+		return c
+	}
+}
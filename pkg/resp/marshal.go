@@ -0,0 +1,209 @@
+package resp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Marshal encodes v as RESP2. Supported inputs are nil, string (a simple
+// string), error, every integer and float kind, bool, []byte, Value and
+// *Value, []string (bulk strings, as commands are sent), []any, any other
+// slice or array, maps with string keys and structs. Maps and structs become
+// flat key/value arrays; use MarshalProto with RESP3 to get real maps.
+//
+// Struct fields are named by a `resp:"name"` tag, falling back to the field
+// name; `resp:"-"` skips a field and `resp:"name,omitempty"` drops zero
+// values. Strings nested in maps and structs are written as bulk strings.
+func Marshal(v any) ([]byte, error) {
+	return MarshalProto(v, RESP2)
+}
+
+// MarshalProto is Marshal for the given protocol version.
+func MarshalProto(v any, proto int) ([]byte, error) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetProtocol(proto)
+	if err := w.encode(v); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encode writes an arbitrary Go value; see Marshal for the mapping.
+func (w *Writer) encode(v any) error {
+	switch v := v.(type) {
+	case nil:
+		return w.WriteNull()
+	case string:
+		return w.WriteSimpleString(v)
+	case error:
+		return w.WriteError(v.Error())
+	case []byte:
+		return w.WriteBulkBytes(v)
+	case Value:
+		return w.WriteValue(v)
+	case *Value:
+		if v == nil {
+			return w.WriteNull()
+		}
+		return w.WriteValue(*v)
+	case bool:
+		if w.proto >= RESP3 {
+			return w.WriteBool(v)
+		}
+		if v {
+			return w.WriteInt(1)
+		}
+		return w.WriteInt(0)
+	case []string:
+		if err := w.WriteArrayHeader(len(v)); err != nil {
+			return err
+		}
+		for _, s := range v {
+			if err := w.WriteBulkString(s); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		if err := w.WriteArrayHeader(len(v)); err != nil {
+			return err
+		}
+		for _, item := range v {
+			if err := w.encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return w.encodeReflect(reflect.ValueOf(v))
+}
+
+func (w *Writer) encodeReflect(rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return w.WriteInt(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if rv.Uint() > math.MaxInt64 {
+			return fmt.Errorf("unsupported value: %d overflows a RESP integer", rv.Uint())
+		}
+		return w.WriteInt(int64(rv.Uint()))
+	case reflect.Float32, reflect.Float64:
+		if w.proto >= RESP3 {
+			return w.WriteDouble(rv.Float())
+		}
+		return w.WriteBulkString(formatDouble(rv.Float()))
+	case reflect.String:
+		return w.WriteBulkString(rv.String())
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		return w.encodeNested(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return w.WriteBulkBytes(rv.Bytes())
+		}
+		if err := w.WriteArrayHeader(rv.Len()); err != nil {
+			return err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := w.encodeNested(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type: %s (map keys must be strings)", rv.Type())
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		if err := w.writePairsHeader(len(keys)); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := w.WriteBulkString(k.String()); err != nil {
+				return err
+			}
+			if err := w.encodeNested(rv.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Struct:
+		fields := structFields(rv)
+		if err := w.writePairsHeader(len(fields)); err != nil {
+			return err
+		}
+		for _, f := range fields {
+			if err := w.WriteBulkString(f.name); err != nil {
+				return err
+			}
+			if err := w.encodeNested(f.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %s", rv.Type())
+	}
+}
+
+// encodeNested encodes an element of a container, where strings are always
+// bulk strings rather than status replies.
+func (w *Writer) encodeNested(rv reflect.Value) error {
+	if rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return w.WriteNull()
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.String {
+		return w.WriteBulkString(rv.String())
+	}
+	return w.encode(rv.Interface())
+}
+
+func (w *Writer) writePairsHeader(n int) error {
+	if w.proto >= RESP3 {
+		return w.WriteMapHeader(n)
+	}
+	return w.WriteArrayHeader(2 * n)
+}
+
+type structField struct {
+	name  string
+	value reflect.Value
+}
+
+func structFields(rv reflect.Value) []structField {
+	t := rv.Type()
+	fields := make([]structField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get("resp"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fv := rv.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		fields = append(fields, structField{name: name, value: fv})
+	}
+	return fields
+}
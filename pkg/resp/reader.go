@@ -0,0 +1,343 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrProtocol matches every *ProtocolError with errors.Is.
+var ErrProtocol = errors.New("protocol error")
+
+// ProtocolError reports malformed or over-limit input. The stream cannot be
+// resynchronised afterwards, so servers reply with it and close the connection.
+type ProtocolError struct {
+	Reason string
+}
+
+func (e *ProtocolError) Error() string { return "Protocol error: " + e.Reason }
+
+func (e *ProtocolError) Is(target error) bool { return target == ErrProtocol }
+
+func protocolErrorf(format string, args ...any) error {
+	return &ProtocolError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// ReaderOptions bounds what a Reader accepts; zero fields use the defaults
+// from DefaultReaderOptions.
+type ReaderOptions struct {
+	MaxBulkLen  int // longest bulk string payload
+	MaxArrayLen int // most elements in one aggregate (pairs for maps)
+	MaxDepth    int // deepest nesting of aggregates
+	MaxLineLen  int // longest header or simple line, excluding CRLF
+}
+
+// DefaultReaderOptions mirrors the limits of a stock Redis server.
+var DefaultReaderOptions = ReaderOptions{
+	MaxBulkLen:  512 * 1024 * 1024,
+	MaxArrayLen: 1024 * 1024,
+	MaxDepth:    64,
+	MaxLineLen:  64 * 1024,
+}
+
+func (o ReaderOptions) withDefaults() ReaderOptions {
+	if o.MaxBulkLen <= 0 {
+		o.MaxBulkLen = DefaultReaderOptions.MaxBulkLen
+	}
+	if o.MaxArrayLen <= 0 {
+		o.MaxArrayLen = DefaultReaderOptions.MaxArrayLen
+	}
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = DefaultReaderOptions.MaxDepth
+	}
+	if o.MaxLineLen <= 0 {
+		o.MaxLineLen = DefaultReaderOptions.MaxLineLen
+	}
+	return o
+}
+
+// Reader decodes RESP values from a stream while enforcing ReaderOptions.
+type Reader struct {
+	br   *bufio.Reader
+	opts ReaderOptions
+
+	// scratch space reused by ReadCommand
+	buf  []byte
+	offs []int
+	line []byte
+
+	onPush func(Value)
+}
+
+// SetPushHandler routes out-of-band RESP3 push frames (pub/sub messages,
+// client tracking invalidations) to fn instead of returning them from
+// ReadValue, which then keeps reading until it finds the actual reply.
+// fn runs on the reading goroutine. A nil fn restores the default of
+// returning push frames like any other value.
+func (r *Reader) SetPushHandler(fn func(Value)) {
+	r.onPush = fn
+}
+
+func NewReader(r io.Reader) *Reader {
+	return NewReaderWithOptions(r, ReaderOptions{})
+}
+
+func NewReaderWithOptions(r io.Reader, opts ReaderOptions) *Reader {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Reader{br: br, opts: opts.withDefaults()}
+}
+
+// Buffered returns the number of bytes already read from the stream but not yet decoded.
+func (r *Reader) Buffered() int {
+	return r.br.Buffered()
+}
+
+// Peek returns the next byte without consuming it.
+func (r *Reader) Peek() (byte, error) {
+	b, err := r.br.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// UnmarshalOne reads exactly ONE complete RESP value from r
+func UnmarshalOne(r *bufio.Reader) (Value, error) {
+	return (&Reader{br: r, opts: DefaultReaderOptions}).ReadValue()
+}
+
+func isPrefix(b byte) bool {
+	switch b {
+	case '+', '-', ':', '$', '*', '_', '#', ',', '(', '=', '!', '%', '~', '>', '|':
+		return true
+	}
+	return false
+}
+
+// ReadValue reads exactly one complete RESP value. Input that does not start
+// with a RESP type byte is returned as an error Value holding the line, which
+// is how plain-text replies from non-RESP peers surface.
+func (r *Reader) ReadValue() (Value, error) {
+	for {
+		v, err := r.readValue(0)
+		if err != nil || v.Typ != "push" || r.onPush == nil {
+			return v, err
+		}
+		r.onPush(v)
+	}
+}
+
+func (r *Reader) readValue(depth int) (Value, error) {
+	b, err := r.Peek()
+	if err != nil {
+		return Value{}, err
+	}
+
+	// If it's not a valid RESP prefix, read the whole line as error/plaintext
+	if !isPrefix(b) {
+		line, err := r.ReadLine()
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "error", Str: "Server sent: " + line}, nil
+	}
+	line, err := r.ReadLine()
+	if err != nil {
+		return Value{}, err
+	}
+	if len(line) == 0 {
+		return Value{}, protocolErrorf("empty line")
+	}
+
+	switch line[0] {
+	case '+': // Simple String
+		return Value{Typ: "string", Str: line[1:]}, nil
+	case '-': // Error
+		return Value{Typ: "error", Str: line[1:]}, nil
+	case ':': // Integer
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return Value{}, protocolErrorf("invalid integer %q", line[1:])
+		}
+		return Value{Typ: "integer", Num: n}, nil
+	case '$': // Bulk String
+		if line == "$-1" {
+			return Value{Typ: "null"}, nil
+		}
+		payload, err := r.readBlob(line)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "bulk", Bulk: payload}, nil
+	case '*': // Array
+		if line == "*-1" {
+			return Value{Typ: "null"}, nil
+		}
+		arr, err := r.readAggregate(line, 1, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "array", Array: arr}, nil
+	case '_': // RESP3 Null
+		return Value{Typ: "null"}, nil
+	case '#': // RESP3 Boolean
+		switch line[1:] {
+		case "t":
+			return Value{Typ: "boolean", Bool: true}, nil
+		case "f":
+			return Value{Typ: "boolean", Bool: false}, nil
+		}
+		return Value{}, protocolErrorf("invalid boolean %q", line[1:])
+	case ',': // RESP3 Double
+		f, err := parseDouble(line[1:])
+		if err != nil {
+			return Value{}, protocolErrorf("invalid double %q", line[1:])
+		}
+		return Value{Typ: "double", Double: f}, nil
+	case '(': // RESP3 Big Number
+		return Value{Typ: "bignum", Str: line[1:]}, nil
+	case '!': // RESP3 Blob Error
+		payload, err := r.readBlob(line)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "error", Str: payload}, nil
+	case '=': // RESP3 Verbatim String
+		payload, err := r.readBlob(line)
+		if err != nil {
+			return Value{}, err
+		}
+		if len(payload) < 4 || payload[3] != ':' {
+			return Value{}, protocolErrorf("invalid verbatim string %q", payload)
+		}
+		return Value{Typ: "verbatim", Format: payload[:3], Bulk: payload[4:]}, nil
+	case '%': // RESP3 Map
+		pairs, err := r.readPairs(line, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "map", Map: pairs}, nil
+	case '~': // RESP3 Set
+		arr, err := r.readAggregate(line, 1, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "set", Array: arr}, nil
+	case '>': // RESP3 Push
+		arr, err := r.readAggregate(line, 1, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		return Value{Typ: "push", Array: arr}, nil
+	case '|': // RESP3 Attribute, describing the value that follows it
+		pairs, err := r.readPairs(line, depth)
+		if err != nil {
+			return Value{}, err
+		}
+		// The described value counts as one level deeper, or a chain of
+		// attributes could recurse past MaxDepth.
+		v, err := r.readValue(depth + 1)
+		if err != nil {
+			return Value{}, err
+		}
+		v.Attrs = append(pairs, v.Attrs...)
+		return v, nil
+	default:
+		return Value{}, protocolErrorf("unexpected prefix %q", line[0])
+	}
+}
+
+// parseLength parses the length announced by a bulk or aggregate header.
+func parseLength(header string, max int, what string) (int, error) {
+	n, err := strconv.Atoi(header[1:])
+	if err != nil {
+		return 0, protocolErrorf("invalid %s length %q", what, header[1:])
+	}
+	if n < 0 {
+		return 0, protocolErrorf("negative %s length %d", what, n)
+	}
+	if n > max {
+		return 0, protocolErrorf("%s length %d exceeds limit %d", what, n, max)
+	}
+	return n, nil
+}
+
+// readBlob reads the length-prefixed payload announced by header.
+func (r *Reader) readBlob(header string) (string, error) {
+	length, err := parseLength(header, r.opts.MaxBulkLen, "bulk")
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, length+2) // +2 for \r\n
+	if _, err := io.ReadFull(r.br, buf); err != nil {
+		return "", err
+	}
+	if buf[length] != '\r' || buf[length+1] != '\n' {
+		return "", protocolErrorf("bulk payload not terminated by CRLF")
+	}
+	return string(buf[:length]), nil
+}
+
+// readAggregate reads count*per elements, where count comes from header;
+// maps announce pairs, so they pass per=2.
+func (r *Reader) readAggregate(header string, per, depth int) ([]Value, error) {
+	if depth+1 > r.opts.MaxDepth {
+		return nil, protocolErrorf("nesting deeper than %d", r.opts.MaxDepth)
+	}
+	count, err := parseLength(header, r.opts.MaxArrayLen, "multibulk")
+	if err != nil {
+		return nil, err
+	}
+	arr := make([]Value, count*per)
+	for i := range arr {
+		val, err := r.readValue(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = val
+	}
+	return arr, nil
+}
+
+// readPairs reads the key/value body of a map or attribute frame.
+func (r *Reader) readPairs(header string, depth int) ([]Pair, error) {
+	flat, err := r.readAggregate(header, 2, depth)
+	if err != nil {
+		return nil, err
+	}
+	pairs := make([]Pair, len(flat)/2)
+	for i := range pairs {
+		pairs[i] = Pair{Key: flat[2*i], Value: flat[2*i+1]}
+	}
+	return pairs, nil
+}
+
+// ReadLine reads one CRLF (or bare LF) terminated line, without the
+// terminator, refusing lines longer than MaxLineLen.
+func (r *Reader) ReadLine() (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.br.ReadSlice('\n')
+		if len(line)+len(chunk) > r.opts.MaxLineLen+2 {
+			return "", protocolErrorf("line longer than %d bytes", r.opts.MaxLineLen)
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		break
+	}
+	line = line[:len(line)-1] // remove trailing \n
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1] // remove \r if present
+	}
+	return string(line), nil
+}
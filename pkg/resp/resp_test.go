@@ -5,7 +5,10 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"io"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -87,13 +90,13 @@ func TestWriteValue(t *testing.T) {
 		{Value{Typ: "error", Str: "ERR"}, "-ERR\r\n"},
 		{Value{Typ: "integer", Num: 123}, ":123\r\n"},
 		{Value{Typ: "null"}, "$-1\r\n"},
-		{Value{Typ: "bulk", Bulk: ""}, "$-1\r\n"},
+		{Value{Typ: "bulk", Bulk: ""}, "$0\r\n\r\n"},
 		{Value{Typ: "bulk", Bulk: "hello"}, "$5\r\nhello\r\n"},
-		{Value{Typ: "array", Array: []Value{{Typ: "string", Str: "PING"}}}, "+PING\r\n"},
+		{Value{Typ: "array", Array: []Value{{Typ: "string", Str: "PING"}}}, "*1\r\n+PING\r\n"},
 		{Value{Typ: "array", Array: []Value{
 			{Typ: "bulk", Bulk: "GET"},
 			{Typ: "bulk", Bulk: "key"},
-		}}, "$3\r\nGET\r\n$3\r\nkey\r\n"},
+		}}, "*2\r\n$3\r\nGET\r\n$3\r\nkey\r\n"},
 		{Value{Typ: "array", Array: nil}, "*-1\r\n"},
 	}
 
@@ -107,3 +110,532 @@ func TestWriteValue(t *testing.T) {
 		}
 	}
 }
+
+func TestUnmarshalOne_RESP3(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Value
+	}{
+		{"null", "_\r\n", Value{Typ: "null"}},
+		{"true", "#t\r\n", Value{Typ: "boolean", Bool: true}},
+		{"false", "#f\r\n", Value{Typ: "boolean", Bool: false}},
+		{"double", ",3.25\r\n", Value{Typ: "double", Double: 3.25}},
+		{"inf", ",-inf\r\n", Value{Typ: "double", Double: math.Inf(-1)}},
+		{"bignum", "(3492890328409238509324850943850943825024385\r\n", Value{Typ: "bignum", Str: "3492890328409238509324850943850943825024385"}},
+		{"blob error", "!21\r\nSYNTAX invalid syntax\r\n", Value{Typ: "error", Str: "SYNTAX invalid syntax"}},
+		{"verbatim", "=15\r\ntxt:Some string\r\n", Value{Typ: "verbatim", Format: "txt", Bulk: "Some string"}},
+		{"map", "%2\r\n+first\r\n:1\r\n+second\r\n:2\r\n", Value{Typ: "map", Map: []Pair{
+			{Key: Value{Typ: "string", Str: "first"}, Value: Value{Typ: "integer", Num: 1}},
+			{Key: Value{Typ: "string", Str: "second"}, Value: Value{Typ: "integer", Num: 2}},
+		}}},
+		{"set", "~2\r\n+a\r\n+b\r\n", Value{Typ: "set", Array: []Value{
+			{Typ: "string", Str: "a"},
+			{Typ: "string", Str: "b"},
+		}}},
+		{"push", ">2\r\n+message\r\n$2\r\nhi\r\n", Value{Typ: "push", Array: []Value{
+			{Typ: "string", Str: "message"},
+			{Typ: "bulk", Bulk: "hi"},
+		}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader([]byte(tt.input)))
+			got, err := UnmarshalOne(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteValueProto(&buf, got, RESP3); err != nil {
+				t.Fatal(err)
+			}
+			out := tt.input
+			if tt.name == "blob error" {
+				// single-line errors are always written in the simple form
+				out = "-SYNTAX invalid syntax\r\n"
+			}
+			if buf.String() != out {
+				t.Errorf("round trip: got %q, want %q", buf.String(), out)
+			}
+		})
+	}
+}
+
+func TestWriteValueProto_RESP2Downgrade(t *testing.T) {
+	v := Value{Typ: "map", Map: []Pair{
+		{Key: Value{Typ: "bulk", Bulk: "ok"}, Value: Value{Typ: "boolean", Bool: true}},
+		{Key: Value{Typ: "bulk", Bulk: "score"}, Value: Value{Typ: "double", Double: 1.5}},
+		{Key: Value{Typ: "bulk", Bulk: "tags"}, Value: Value{Typ: "set", Array: []Value{{Typ: "bulk", Bulk: "x"}}}},
+		{Key: Value{Typ: "bulk", Bulk: "none"}, Value: Value{Typ: "null"}},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteValueProto(&buf, v, RESP2); err != nil {
+		t.Fatal(err)
+	}
+	want := "*8\r\n$2\r\nok\r\n:1\r\n$5\r\nscore\r\n$3\r\n1.5\r\n$4\r\ntags\r\n*1\r\n$1\r\nx\r\n$4\r\nnone\r\n$-1\r\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+
+	w.WriteArrayHeader(3)
+	w.WriteBulkBytes([]byte("hello"))
+	w.WriteInt(-42)
+	w.WriteError("ERR boom")
+	if buf.Len() != 0 {
+		t.Fatal("nothing should reach the underlying writer before Flush")
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "*3\r\n$5\r\nhello\r\n:-42\r\n-ERR boom\r\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	w.SetProtocol(RESP3)
+	w.WriteNull()
+	w.WriteMapHeader(1)
+	w.WriteSimpleString("ok")
+	w.WriteBool(true)
+	w.Flush()
+	if want := "_\r\n%1\r\n+ok\r\n#t\r\n"; buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func BenchmarkWriterReply(b *testing.B) {
+	w := NewWriter(io.Discard)
+	payload := []byte("some moderately sized value")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w.WriteArrayHeader(2)
+		w.WriteBulkBytes(payload)
+		w.WriteInt(int64(i))
+		w.Flush()
+	}
+}
+
+func TestMarshal_Structured(t *testing.T) {
+	type info struct {
+		Name    string `resp:"name"`
+		Port    int    `resp:"port"`
+		Secret  string `resp:"-"`
+		Comment string `resp:"comment,omitempty"`
+		Replica bool
+	}
+
+	tests := []struct {
+		name  string
+		in    any
+		proto int
+		want  string
+	}{
+		{"int8", int8(-7), RESP2, ":-7\r\n"},
+		{"uint16", uint16(7), RESP2, ":7\r\n"},
+		{"string slice", []string{"SET", "k", "v v"}, RESP2, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$3\r\nv v\r\n"},
+		{"value", Value{Typ: "integer", Num: 3}, RESP2, ":3\r\n"},
+		{"nil value pointer", (*Value)(nil), RESP2, "$-1\r\n"},
+		{"bool resp2", true, RESP2, ":1\r\n"},
+		{"bool resp3", true, RESP3, "#t\r\n"},
+		{"float resp2", 2.5, RESP2, "$3\r\n2.5\r\n"},
+		{"float resp3", 2.5, RESP3, ",2.5\r\n"},
+		{"map resp2", map[string]any{"b": 2, "a": "x"}, RESP2, "*4\r\n$1\r\na\r\n$1\r\nx\r\n$1\r\nb\r\n:2\r\n"},
+		{"map resp3", map[string]string{"a": "x"}, RESP3, "%1\r\n$1\r\na\r\n$1\r\nx\r\n"},
+		{"struct", info{Name: "n1", Port: 8090, Secret: "s"}, RESP2,
+			"*6\r\n$4\r\nname\r\n$2\r\nn1\r\n$4\r\nport\r\n:8090\r\n$7\r\nReplica\r\n:0\r\n"},
+		{"nested", []map[string]int{{"x": 1}}, RESP3, "*1\r\n%1\r\n$1\r\nx\r\n:1\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MarshalProto(tt.in, tt.proto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Marshal(map[int]string{1: "x"}); err == nil {
+		t.Error("expected error for non-string map keys")
+	}
+	if _, err := Marshal(make(chan int)); err == nil {
+		t.Error("expected error for unsupported type")
+	}
+}
+
+func TestEmptyBulkRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	WriteValue(&buf, Value{Typ: "bulk", Bulk: ""})
+	WriteValue(&buf, Null)
+
+	r := bufio.NewReader(&buf)
+	empty, err := UnmarshalOne(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.IsNull() || empty.Typ != "bulk" || empty.Bulk != "" {
+		t.Fatalf("empty bulk decoded as %+v", empty)
+	}
+	null, err := UnmarshalOne(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !null.IsNull() {
+		t.Fatalf("null decoded as %+v", null)
+	}
+}
+
+func TestReader_Limits(t *testing.T) {
+	opts := ReaderOptions{MaxBulkLen: 4, MaxArrayLen: 2, MaxDepth: 2, MaxLineLen: 8}
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"bulk too large", "$5\r\nhello\r\n"},
+		{"array too large", "*3\r\n:1\r\n:2\r\n:3\r\n"},
+		{"too deep", "*1\r\n*1\r\n*1\r\n:1\r\n"},
+		{"line too long", "+123456789\r\n"},
+		{"bad bulk length", "$abc\r\n"},
+		{"negative array length", "*-2\r\n"},
+		{"bad integer", ":12x\r\n"},
+		{"bad boolean", "#x\r\n"},
+		{"missing crlf after bulk", "$2\r\nhixx"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReaderWithOptions(bytes.NewReader([]byte(tt.input)), opts)
+			_, err := r.ReadValue()
+			var perr *ProtocolError
+			if !errors.As(err, &perr) || !errors.Is(err, ErrProtocol) {
+				t.Fatalf("expected a protocol error, got %v", err)
+			}
+		})
+	}
+
+	r := NewReaderWithOptions(bytes.NewReader([]byte("*2\r\n$4\r\nabcd\r\n*1\r\n:1\r\n")), opts)
+	if _, err := r.ReadValue(); err != nil {
+		t.Fatalf("input at the limits should parse: %v", err)
+	}
+}
+
+func TestReader_ReadCommand(t *testing.T) {
+	input := "*3\r\n$3\r\nSET\r\n$3\r\nkey\r\n$0\r\n\r\n" +
+		"*2\r\n+GET\r\n+key\r\n" +
+		"*1\r\n:5\r\n"
+	r := NewReader(bytes.NewReader([]byte(input)))
+
+	var args [][]byte
+	var err error
+	want := [][]string{{"SET", "key", ""}, {"GET", "key"}, {"5"}}
+	var kept []string
+	for i, w := range want {
+		args, err = r.ReadCommand(args)
+		if err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+		got := CopyArgs(args)
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("command %d: got %q, want %q", i, got, w)
+		}
+		if i == 0 {
+			kept = got
+		}
+	}
+	if kept[1] != "key" {
+		t.Fatal("copied args must survive later reads")
+	}
+
+	if _, err := NewReader(bytes.NewReader([]byte("$3\r\nfoo\r\n"))).ReadCommand(nil); !errors.Is(err, ErrProtocol) {
+		t.Fatalf("expected protocol error for non-array request, got %v", err)
+	}
+
+	// Lengths past MaxInt64 must be rejected, not wrap to a negative size.
+	for _, input := range []string{
+		"*9223372036854775808\r\n",
+		"*1\r\n$9223372036854775808\r\nabc\r\n",
+		"*1\r\n$9999999999999999999\r\nabc\r\n",
+	} {
+		if _, err := NewReader(bytes.NewReader([]byte(input))).ReadCommand(nil); !errors.Is(err, ErrProtocol) {
+			t.Fatalf("%q: expected protocol error, got %v", input, err)
+		}
+	}
+}
+
+func TestReader_ReadCommandBuffer(t *testing.T) {
+	// A header announcing 512MB with nothing behind it must not make the
+	// reader allocate the announced size up front.
+	r := NewReader(strings.NewReader("*1\r\n$536870912\r\nabc"))
+	if _, err := r.ReadCommand(nil); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a short read, got %v", err)
+	}
+	if cap(r.buf) > 4*bulkChunk {
+		t.Fatalf("buffer grew to %d bytes for 3 bytes of payload", cap(r.buf))
+	}
+
+	// A big argument is read in full, and its buffer is let go once the
+	// next command comes in.
+	big := strings.Repeat("x", 1<<20)
+	r = NewReader(strings.NewReader("*1\r\n$1048576\r\n" + big + "\r\n*1\r\n$4\r\nPING\r\n"))
+	args, err := r.ReadCommand(nil)
+	if err != nil || len(args) != 1 || string(args[0]) != big {
+		t.Fatalf("big argument: %d args, %v", len(args), err)
+	}
+	if args, err = r.ReadCommand(args); err != nil || string(args[0]) != "PING" {
+		t.Fatalf("second command: %q, %v", args, err)
+	}
+	if cap(r.buf) > maxKeptBuffer {
+		t.Fatalf("kept a %d byte buffer after a small command", cap(r.buf))
+	}
+}
+
+func BenchmarkReadCommand(b *testing.B) {
+	payload := []byte("*3\r\n$3\r\nSET\r\n$10\r\nsome:key:1\r\n$16\r\nsome-value-bytes\r\n")
+	input := bytes.Repeat(payload, 1024)
+	rd := bytes.NewReader(input)
+	r := NewReader(rd)
+	var args [][]byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if rd.Len() == 0 && r.Buffered() == 0 {
+			rd.Reset(input)
+		}
+		var err error
+		if args, err = r.ReadCommand(args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	type server struct {
+		Name  string `resp:"name"`
+		Port  int
+		Roles []string `resp:"roles"`
+		Extra *string  `resp:"extra"`
+	}
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	enc.Encode([]byte("hello"))
+	enc.Encode(42)
+	enc.Encode([]string{"a", "b"})
+	enc.Encode(map[string]string{"f1": "v1", "f2": "v2"})
+	enc.Encode(Value{Typ: "array", Array: []Value{
+		{Typ: "bulk", Bulk: "name"}, {Typ: "bulk", Bulk: "primary"},
+		{Typ: "bulk", Bulk: "port"}, {Typ: "bulk", Bulk: "8090"},
+		{Typ: "bulk", Bulk: "roles"}, {Typ: "array", Array: []Value{{Typ: "bulk", Bulk: "master"}}},
+		{Typ: "bulk", Bulk: "extra"}, {Typ: "null"},
+	}})
+	enc.Encode(nil)
+	enc.Encode(nil)
+	enc.Encode(errors.New("WRONGTYPE bad"))
+
+	dec := NewDecoder(&buf)
+	var s string
+	var n int64
+	var list []string
+	var m map[string]string
+	var srv server
+	var ptr *string
+	var missing string
+	if err := dec.Decode(&s); err != nil || s != "hello" {
+		t.Fatalf("string: %q %v", s, err)
+	}
+	if err := dec.Decode(&n); err != nil || n != 42 {
+		t.Fatalf("int64: %d %v", n, err)
+	}
+	if err := dec.Decode(&list); err != nil || !reflect.DeepEqual(list, []string{"a", "b"}) {
+		t.Fatalf("[]string: %v %v", list, err)
+	}
+	if err := dec.Decode(&m); err != nil || !reflect.DeepEqual(m, map[string]string{"f1": "v1", "f2": "v2"}) {
+		t.Fatalf("map: %v %v", m, err)
+	}
+	if err := dec.Decode(&srv); err != nil {
+		t.Fatal(err)
+	}
+	if srv.Name != "primary" || srv.Port != 8090 || !reflect.DeepEqual(srv.Roles, []string{"master"}) || srv.Extra != nil {
+		t.Fatalf("struct: %+v", srv)
+	}
+	if err := dec.Decode(&ptr); err != nil || ptr != nil {
+		t.Fatalf("nil into pointer: %v %v", ptr, err)
+	}
+	if err := dec.Decode(&missing); err != ErrNil {
+		t.Fatalf("nil into string should be ErrNil, got %v", err)
+	}
+	if err := dec.Decode(&s); err == nil || err.Error() != "WRONGTYPE bad" {
+		t.Fatalf("expected the error reply, got %v", err)
+	}
+}
+
+func TestReader_AttributesAndPush(t *testing.T) {
+	input := "|1\r\n+key-popularity\r\n%1\r\n$1\r\na\r\n,0.5\r\n" +
+		"*1\r\n:2\r\n" +
+		">3\r\n$10\r\ninvalidate\r\n*1\r\n$3\r\nfoo\r\n_\r\n" +
+		"+OK\r\n"
+
+	var pushes []Value
+	r := NewReader(bytes.NewReader([]byte(input)))
+	r.SetPushHandler(func(v Value) { pushes = append(pushes, v) })
+
+	withAttrs, err := r.ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withAttrs.Typ != "array" || len(withAttrs.Attrs) != 1 || withAttrs.Attrs[0].Key.Str != "key-popularity" {
+		t.Fatalf("attributes not attached: %+v", withAttrs)
+	}
+
+	ok, err := r.ReadValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok.Str != "OK" {
+		t.Fatalf("push frame should be skipped, got %+v", ok)
+	}
+	if len(pushes) != 1 || pushes[0].Array[0].Bulk != "invalidate" {
+		t.Fatalf("push handler not called: %+v", pushes)
+	}
+
+	var buf bytes.Buffer
+	WriteValueProto(&buf, withAttrs, RESP3)
+	if want := input[:strings.Index(input, ">")]; buf.String() != want {
+		t.Fatalf("attribute round trip: got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	WriteValueProto(&buf, withAttrs, RESP2)
+	if buf.String() != "*1\r\n:2\r\n" {
+		t.Fatalf("RESP2 must drop attributes, got %q", buf.String())
+	}
+
+	chained := strings.Repeat("|0\r\n", 3) + "+OK\r\n"
+	if v, err := NewReader(strings.NewReader(chained)).ReadValue(); err != nil || v.Str != "OK" {
+		t.Fatalf("short attribute chain: %+v, %v", v, err)
+	}
+	chained = strings.Repeat("|0\r\n", 1000) + "+OK\r\n"
+	r = NewReaderWithOptions(strings.NewReader(chained), ReaderOptions{MaxDepth: 8})
+	if _, err := r.ReadValue(); !errors.Is(err, ErrProtocol) {
+		t.Fatalf("attribute chain past MaxDepth: expected a protocol error, got %v", err)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", []string{}},
+		{"   \r\n", []string{}},
+		{"PING", []string{"PING"}},
+		{"SET  key   value\r\n", []string{"SET", "key", "value"}},
+		{`SET key "hello world"`, []string{"SET", "key", "hello world"}},
+		{`SET key "a\"b\n\x41\\"`, []string{"SET", "key", "a\"b\nA\\"}},
+		{`SET key 'it\'s "raw" \n'`, []string{"SET", "key", `it's "raw" \n`}},
+		{`SET key ""`, []string{"SET", "key", ""}},
+		{`GET ab"c"`, []string{"GET", "abc"}},
+	}
+	for _, tt := range tests {
+		got, err := SplitArgs(tt.line)
+		if err != nil {
+			t.Errorf("SplitArgs(%q) error: %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitArgs(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{`SET "key`, `SET 'key`, `SET "key"x`, `SET 'key'x`} {
+		if _, err := SplitArgs(bad); err != ErrUnbalancedQuotes {
+			t.Errorf("SplitArgs(%q) should fail with unbalanced quotes, got %v", bad, err)
+		}
+	}
+}
+
+func TestStreamingBulk(t *testing.T) {
+	const size = 3 << 20
+	payload := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+
+	pr, pw := io.Pipe()
+	go func() {
+		w := NewWriter(pw)
+		err := w.WriteBulkFrom(bytes.NewReader(payload), int64(len(payload)))
+		if err == nil {
+			err = w.WriteNull()
+		}
+		if err == nil {
+			err = w.Flush()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	// a small MaxBulkLen proves the payload never goes through the buffered path
+	r := NewReaderWithOptions(pr, ReaderOptions{MaxBulkLen: 1024})
+	var out bytes.Buffer
+	n, err := r.ReadBulkTo(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(payload)) || !bytes.Equal(out.Bytes(), payload) {
+		t.Fatalf("streamed %d bytes, payload mismatch", n)
+	}
+	if _, err := r.ReadBulkTo(&out); err != ErrNil {
+		t.Fatalf("expected ErrNil for a nil bulk, got %v", err)
+	}
+
+	w := NewWriter(io.Discard)
+	if err := w.WriteBulkFrom(bytes.NewReader([]byte("short")), 10); err == nil {
+		t.Fatal("expected an error when the source is shorter than announced")
+	}
+}
+
+func TestParseError(t *testing.T) {
+	err := ParseError("WRONGTYPE Operation against a key holding the wrong kind of value")
+	if !errors.Is(err, ErrWrongType) || !HasCode(err, "WRONGTYPE") {
+		t.Fatalf("expected WRONGTYPE, got %#v", err)
+	}
+	if errors.Is(ParseError("ERR unknown command 'FOO'"), ErrSyntax) {
+		t.Fatal("different ERR messages must not match")
+	}
+	if !errors.Is(ParseError("NOAUTH Authentication required."), &Error{Code: "NOAUTH"}) {
+		t.Fatal("code-only target should match any message")
+	}
+
+	var redirect *RedirectError
+	if !errors.As(ParseError("MOVED 3999 127.0.0.1:6381"), &redirect) {
+		t.Fatal("expected a redirect")
+	}
+	if redirect.Kind != "MOVED" || redirect.Slot != 3999 || redirect.Addr != "127.0.0.1:6381" {
+		t.Fatalf("bad redirect: %+v", redirect)
+	}
+	if !HasCode(ParseError("ASK 1 10.0.0.2:7000"), "ASK") {
+		t.Fatal("expected ASK redirect")
+	}
+
+	plain := ParseError("something went wrong")
+	if ErrorCode(plain) != "" || plain.Error() != "something went wrong" {
+		t.Fatalf("lower-case text has no code: %#v", plain)
+	}
+
+	if v := ErrorValue(errors.New("boom")); v.Str != "ERR boom" {
+		t.Fatalf("untyped errors get ERR, got %q", v.Str)
+	}
+	if v := ErrorValue(WrongArgs("GET")); v.Str != "ERR wrong number of arguments for 'get' command" {
+		t.Fatalf("got %q", v.Str)
+	}
+}
@@ -0,0 +1,54 @@
+package resp
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteBulkFrom writes a bulk string of exactly n bytes copied from src,
+// without holding the payload in memory. If src runs dry early the frame is
+// left incomplete and the connection must be discarded.
+func (w *Writer) WriteBulkFrom(src io.Reader, n int64) error {
+	if err := w.writeHeader('$', n); err != nil {
+		return err
+	}
+	copied, err := io.CopyN(w.bw, src, n)
+	if err != nil {
+		return fmt.Errorf("resp: bulk stream ended after %d of %d bytes: %w", copied, n, err)
+	}
+	_, err = w.bw.WriteString("\r\n")
+	return err
+}
+
+// ReadBulkTo reads the next value, which must be a bulk string, copying its
+// payload into dst as it arrives and returning its length. A nil bulk
+// returns ErrNil. MaxBulkLen is not enforced because nothing is buffered.
+func (r *Reader) ReadBulkTo(dst io.Writer) (int64, error) {
+	line, err := r.ReadLine()
+	if err != nil {
+		return 0, err
+	}
+	if line == "$-1" {
+		return 0, ErrNil
+	}
+	if len(line) == 0 || line[0] != '$' {
+		return 0, protocolErrorf("expected '$', got %q", line)
+	}
+	length, err := parseLength(line, int(^uint(0)>>1), "bulk")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.CopyN(dst, r.br, int64(length))
+	if err != nil {
+		return n, err
+	}
+
+	var crlf [2]byte
+	if _, err := io.ReadFull(r.br, crlf[:]); err != nil {
+		return n, err
+	}
+	if crlf != [2]byte{'\r', '\n'} {
+		return n, protocolErrorf("bulk payload not terminated by CRLF")
+	}
+	return n, nil
+}
@@ -0,0 +1,255 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Writer encodes RESP frames straight into a buffered writer, so replies are
+// built without intermediate byte slices. Nothing reaches the underlying
+// writer until Flush is called or the buffer fills up.
+type Writer struct {
+	bw      *bufio.Writer
+	proto   int
+	scratch []byte
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{bw: bufio.NewWriter(w), proto: RESP2, scratch: make([]byte, 0, 24)}
+}
+
+// Reset discards any unflushed data and points the Writer at w.
+func (w *Writer) Reset(dst io.Writer) {
+	w.bw.Reset(dst)
+	w.proto = RESP2
+}
+
+// SetProtocol selects the protocol used by WriteValue and WriteNull.
+func (w *Writer) SetProtocol(proto int) {
+	w.proto = proto
+}
+
+func (w *Writer) Protocol() int {
+	return w.proto
+}
+
+func (w *Writer) Flush() error {
+	return w.bw.Flush()
+}
+
+// Buffered returns the number of bytes waiting to be flushed.
+func (w *Writer) Buffered() int {
+	return w.bw.Buffered()
+}
+
+func (w *Writer) writeHeader(prefix byte, n int64) error {
+	w.scratch = append(w.scratch[:0], prefix)
+	w.scratch = strconv.AppendInt(w.scratch, n, 10)
+	w.scratch = append(w.scratch, '\r', '\n')
+	_, err := w.bw.Write(w.scratch)
+	return err
+}
+
+func (w *Writer) writeLine(prefix byte, s string) error {
+	w.bw.WriteByte(prefix)
+	w.bw.WriteString(s)
+	_, err := w.bw.WriteString("\r\n")
+	return err
+}
+
+func (w *Writer) WriteArrayHeader(n int) error { return w.writeHeader('*', int64(n)) }
+func (w *Writer) WriteMapHeader(n int) error   { return w.writeHeader('%', int64(n)) }
+func (w *Writer) WriteSetHeader(n int) error   { return w.writeHeader('~', int64(n)) }
+func (w *Writer) WritePushHeader(n int) error  { return w.writeHeader('>', int64(n)) }
+
+func (w *Writer) WriteSimpleString(s string) error { return w.writeLine('+', s) }
+func (w *Writer) WriteInt(n int64) error           { return w.writeHeader(':', n) }
+
+// WriteError writes msg as an error reply. Multi-line messages become blob
+// errors under RESP3 and are flattened onto one line under RESP2.
+func (w *Writer) WriteError(msg string) error {
+	switch {
+	case !strings.ContainsAny(msg, "\r\n"):
+		return w.writeLine('-', msg)
+	case w.proto >= RESP3:
+		w.writeHeader('!', int64(len(msg)))
+		w.bw.WriteString(msg)
+		_, err := w.bw.WriteString("\r\n")
+		return err
+	default:
+		return w.writeLine('-', strings.NewReplacer("\r", " ", "\n", " ").Replace(msg))
+	}
+}
+
+func (w *Writer) WriteBulkBytes(b []byte) error {
+	w.writeHeader('$', int64(len(b)))
+	w.bw.Write(b)
+	_, err := w.bw.WriteString("\r\n")
+	return err
+}
+
+func (w *Writer) WriteBulkString(s string) error {
+	w.writeHeader('$', int64(len(s)))
+	w.bw.WriteString(s)
+	_, err := w.bw.WriteString("\r\n")
+	return err
+}
+
+// WriteNull writes the null reply for the current protocol.
+func (w *Writer) WriteNull() error {
+	if w.proto >= RESP3 {
+		_, err := w.bw.WriteString("_\r\n")
+		return err
+	}
+	_, err := w.bw.WriteString("$-1\r\n")
+	return err
+}
+
+// WriteNullArray writes the RESP2 null array, or plain null under RESP3.
+func (w *Writer) WriteNullArray() error {
+	if w.proto >= RESP3 {
+		return w.WriteNull()
+	}
+	_, err := w.bw.WriteString("*-1\r\n")
+	return err
+}
+
+func (w *Writer) WriteDouble(f float64) error { return w.writeLine(',', formatDouble(f)) }
+
+func (w *Writer) WriteBool(b bool) error {
+	if b {
+		return w.writeLine('#', "t")
+	}
+	return w.writeLine('#', "f")
+}
+
+func (w *Writer) WriteBigNumber(digits string) error { return w.writeLine('(', digits) }
+
+func (w *Writer) WriteVerbatim(format, text string) error {
+	if format == "" {
+		format = "txt"
+	}
+	w.writeHeader('=', int64(len(text)+4))
+	w.bw.WriteString(format)
+	w.bw.WriteByte(':')
+	w.bw.WriteString(text)
+	_, err := w.bw.WriteString("\r\n")
+	return err
+}
+
+// WriteValue encodes v for the Writer's protocol, downgrading RESP3-only
+// types for RESP2 peers.
+func (w *Writer) WriteValue(v Value) error {
+	if w.proto < RESP3 {
+		v = v.RESP2()
+	}
+	return w.writeValue(v)
+}
+
+// WriteAttributeHeader starts an attribute frame of n pairs; the value it
+// describes must be written right after the pairs.
+func (w *Writer) WriteAttributeHeader(n int) error { return w.writeHeader('|', int64(n)) }
+
+func (w *Writer) writeValue(v Value) error {
+	if len(v.Attrs) > 0 && w.proto >= RESP3 {
+		if err := w.WriteAttributeHeader(len(v.Attrs)); err != nil {
+			return err
+		}
+		for _, p := range v.Attrs {
+			if err := w.writeValue(p.Key); err != nil {
+				return err
+			}
+			if err := w.writeValue(p.Value); err != nil {
+				return err
+			}
+		}
+	}
+	switch v.Typ {
+	case "string":
+		return w.WriteSimpleString(v.Str)
+	case "error":
+		return w.WriteError(v.Str)
+	case "integer":
+		return w.WriteInt(v.Num)
+	case "bulk":
+		return w.WriteBulkString(v.Bulk)
+	case "null":
+		return w.WriteNull()
+	case "double":
+		return w.WriteDouble(v.Double)
+	case "boolean":
+		return w.WriteBool(v.Bool)
+	case "bignum":
+		return w.WriteBigNumber(v.Str)
+	case "verbatim":
+		return w.WriteVerbatim(v.Format, v.Bulk)
+	case "array", "set", "push":
+		if v.Array == nil && v.Typ == "array" {
+			return w.WriteNullArray()
+		}
+		var err error
+		switch v.Typ {
+		case "set":
+			err = w.WriteSetHeader(len(v.Array))
+		case "push":
+			err = w.WritePushHeader(len(v.Array))
+		default:
+			err = w.WriteArrayHeader(len(v.Array))
+		}
+		if err != nil {
+			return err
+		}
+		for _, item := range v.Array {
+			if err := w.writeValue(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "map":
+		if err := w.WriteMapHeader(len(v.Map)); err != nil {
+			return err
+		}
+		for _, p := range v.Map {
+			if err := w.writeValue(p.Key); err != nil {
+				return err
+			}
+			if err := w.writeValue(p.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return errors.New("unknown type")
+	}
+}
+
+var writerPool = sync.Pool{
+	New: func() any { return NewWriter(nil) },
+}
+
+// WriteValue writes a Value directly to a writer (useful for servers)
+func WriteValue(w io.Writer, v Value) error {
+	return WriteValueProto(w, v, RESP2)
+}
+
+// WriteValueProto writes v for a client speaking the given protocol
+// version, downgrading RESP3-only types when proto is RESP2. Long-lived
+// connections should keep their own Writer instead.
+func WriteValueProto(w io.Writer, v Value, proto int) error {
+	rw := writerPool.Get().(*Writer)
+	defer writerPool.Put(rw)
+
+	rw.Reset(w)
+	rw.SetProtocol(proto)
+	if err := rw.WriteValue(v); err != nil {
+		rw.Reset(nil)
+		return err
+	}
+	err := rw.Flush()
+	rw.Reset(nil)
+	return err
+}
@@ -0,0 +1,193 @@
+// Package tracing records OpenTelemetry style spans for commands and
+// exports them over OTLP/HTTP, without depending on the OpenTelemetry SDK.
+// A nil *Tracer is valid and records nothing, so callers can trace
+// unconditionally.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace, the tree of spans for one request.
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether id is set; the all-zero ID is invalid.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether id is set; the all-zero ID is invalid.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx carrying sc, so spans
+// started from it become children of sc.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx, or the
+// zero SpanContext.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
+
+// Traceparent formats sc as a W3C traceparent header value.
+func Traceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ErrTraceparent is returned by ParseTraceparent for malformed values.
+var ErrTraceparent = errors.New("tracing: malformed traceparent")
+
+// ParseTraceparent reads a W3C traceparent header value, the form used to
+// carry a trace across HTTP calls and message queues.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		(parts[0] == "00" && len(parts) != 4) {
+		return sc, ErrTraceparent
+	}
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !sc.IsValid() {
+		return SpanContext{}, ErrTraceparent
+	}
+	var flags [1]byte
+	if !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, ErrTraceparent
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// Kind is a span's role, numbered as in OTLP.
+type Kind int8
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// Attribute is one key-value pair on a span. Value is a string, bool,
+// int64 or float64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+func String(key, value string) Attribute    { return Attribute{key, value} }
+func Int(key string, value int) Attribute   { return Attribute{key, int64(value)} }
+func Bool(key string, value bool) Attribute { return Attribute{key, value} }
+
+// Span is one timed operation. Methods on a nil *Span do nothing.
+type Span struct {
+	Name       string
+	Kind       Kind
+	Context    SpanContext
+	Parent     SpanID
+	Start      time.Time
+	End        time.Time
+	Attributes []Attribute
+	// Error is the failure message; empty means the operation succeeded.
+	Error string
+
+	tracer *Tracer
+	once   sync.Once
+}
+
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s != nil {
+		s.Attributes = append(s.Attributes, attrs...)
+	}
+}
+
+// SetError marks the span failed; a nil err leaves it alone.
+func (s *Span) SetError(err error) {
+	if s != nil && err != nil {
+		s.Error = err.Error()
+	}
+}
+
+// Finish stamps the end time and hands a sampled span to the exporter.
+// Calls after the first do nothing.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.once.Do(func() {
+		s.End = time.Now()
+		if s.Context.Sampled {
+			s.tracer.exporter.ExportSpan(s)
+		}
+	})
+}
+
+// Exporter receives finished spans. ExportSpan is called on the
+// goroutine that finished the span and must not block on the network.
+type Exporter interface {
+	ExportSpan(s *Span)
+}
+
+// Tracer starts spans and sends them to an Exporter.
+type Tracer struct {
+	exporter Exporter
+}
+
+func NewTracer(exp Exporter) *Tracer {
+	return &Tracer{exporter: exp}
+}
+
+// Start begins a span as a child of the span context in ctx, or as the
+// root of a new sampled trace. The returned context carries the new span
+// for further children. On a nil Tracer it returns ctx and a nil Span.
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind, attrs ...Attribute) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{Name: name, Kind: kind, Start: time.Now(), Attributes: attrs, tracer: t}
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		s.Context.TraceID = parent.TraceID
+		s.Context.Sampled = parent.Sampled
+		s.Parent = parent.SpanID
+	} else {
+		rand.Read(s.Context.TraceID[:])
+		s.Context.Sampled = true
+	}
+	rand.Read(s.Context.SpanID[:])
+	return ContextWithSpanContext(ctx, s.Context), s
+}
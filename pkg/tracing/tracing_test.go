@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) ExportSpan(s *Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, s)
+}
+
+func TestTraceparent(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(tp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Sampled || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatalf("parsed %+v", sc)
+	}
+	if got := Traceparent(sc); got != tp {
+		t.Fatalf("got %q, want %q", got, tp)
+	}
+
+	for _, bad := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err := ParseTraceparent(bad); !errors.Is(err, ErrTraceparent) {
+			t.Errorf("%q: expected ErrTraceparent, got %v", bad, err)
+		}
+	}
+}
+
+func TestTracer_Start(t *testing.T) {
+	rec := &recorder{}
+	tr := NewTracer(rec)
+
+	ctx, root := tr.Start(context.Background(), "root", KindInternal)
+	_, child := tr.Start(ctx, "child", KindClient, String("db.system", "redis"))
+	child.SetError(errors.New("ERR boom"))
+	child.Finish()
+	child.Finish()
+	root.Finish()
+
+	if len(rec.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(rec.spans))
+	}
+	if child.Context.TraceID != root.Context.TraceID || child.Parent != root.Context.SpanID {
+		t.Fatal("child is not linked to its parent")
+	}
+	if root.Parent.IsValid() || child.Error != "ERR boom" || child.End.Before(child.Start) {
+		t.Fatalf("unexpected spans %+v %+v", root, child)
+	}
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, unsampled := tr.Start(ContextWithSpanContext(context.Background(), parent), "x", KindServer)
+	unsampled.Finish()
+	if len(rec.spans) != 2 {
+		t.Fatal("an unsampled span was exported")
+	}
+
+	var nilTracer *Tracer
+	ctx2, s := nilTracer.Start(context.Background(), "noop", KindInternal)
+	s.SetAttributes(Int("n", 1))
+	s.SetError(errors.New("x"))
+	s.Finish()
+	if s != nil || SpanContextFromContext(ctx2).IsValid() {
+		t.Fatal("a nil tracer should record nothing")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		reqs []otlpRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	exp, err := NewOTLPExporter(OTLPOptions{
+		Endpoint:    srv.URL,
+		ServiceName: "test",
+		BatchSize:   2,
+		Interval:    time.Hour,
+		OnError:     func(err error) { t.Error(err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTracer(exp)
+	for range 3 {
+		_, s := tr.Start(context.Background(), "GET", KindServer, Int("db.redis.key_count", 1))
+		s.SetError(errors.New("ERR nope"))
+		s.Finish()
+	}
+	if err := exp.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var spans []otlpSpan
+	for _, req := range reqs {
+		rs := req.ResourceSpans[0]
+		if v := rs.Resource.Attributes[0].Value.StringValue; v == nil || *v != "test" {
+			t.Fatalf("service.name not set: %+v", rs.Resource)
+		}
+		spans = append(spans, rs.ScopeSpans[0].Spans...)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("collector got %d spans, want 3", len(spans))
+	}
+	s := spans[0]
+	if s.Name != "GET" || s.Kind != KindServer || len(s.TraceID) != 32 || len(s.SpanID) != 16 ||
+		s.Status.Code != 2 || *s.Attributes[0].Value.IntValue != "1" {
+		t.Fatalf("unexpected span %+v", s)
+	}
+
+	if _, err := NewOTLPExporter(OTLPOptions{Endpoint: "localhost:4318"}); err == nil {
+		t.Fatal("expected an error for an endpoint without a scheme")
+	}
+}